@@ -0,0 +1,100 @@
+package main
+
+import (
+	"log"
+	"net"
+	"time"
+
+	"loan-origination-system/internal/api/auth"
+	"loan-origination-system/internal/codec"
+	"loan-origination-system/internal/config"
+
+	"github.com/gin-gonic/gin"
+	"go.temporal.io/sdk/converter"
+)
+
+// Serves the Temporal remote codec endpoints so the Web UI and CLI can show
+// payloads encrypted by the worker and API server. Point the UI's codec
+// endpoint, or the CLI's --codec-endpoint, at this server.
+func main() {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal("Unable to load configuration:", err)
+	}
+	if !cfg.Temporal.Encryption.Enabled() {
+		log.Fatal("Payload encryption is not configured; set TEMPORAL_ENCRYPTION_KEY_ID and TEMPORAL_ENCRYPTION_KEYS")
+	}
+	if cfg.CodecServer.RequireAuth && cfg.Auth.JWTSecret == "" {
+		log.Fatal("Codec server auth is required but no JWT secret is set; set the API server's AUTH_JWT_SECRET or CODEC_SERVER_REQUIRE_AUTH=false")
+	}
+	// Without auth anyone who reaches the server can decode borrower data,
+	// so it is only served on this machine
+	if !cfg.CodecServer.RequireAuth && !loopback(cfg.CodecServer.ListenAddress) {
+		log.Fatal("Codec server auth is off, so it must listen on a loopback address; set CODEC_SERVER_REQUIRE_AUTH=true to listen on ", cfg.CodecServer.ListenAddress)
+	}
+
+	encryption, err := codec.NewFromConfig(cfg.Temporal.Encryption)
+	if err != nil {
+		log.Fatal("Unable to create encryption codec:", err)
+	}
+
+	router := gin.Default()
+
+	// Only the configured Web UI origins may call the codec from a browser
+	allowedOrigins := map[string]bool{}
+	for _, origin := range cfg.CodecServer.AllowedOrigins {
+		allowedOrigins[origin] = true
+	}
+	router.Use(func(c *gin.Context) {
+		if origin := c.GetHeader("Origin"); allowedOrigins[origin] {
+			c.Header("Access-Control-Allow-Origin", origin)
+			c.Header("Access-Control-Allow-Credentials", "true")
+			c.Header("Access-Control-Allow-Methods", "POST, OPTIONS")
+			c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Namespace")
+			c.Header("Vary", "Origin")
+		}
+
+		if c.Request.Method == "OPTIONS" {
+			c.AbortWithStatus(204)
+			return
+		}
+
+		c.Next()
+	})
+
+	// Decoding shows borrower data, so with auth on it is limited to staff
+	// holding a token issued by the API server
+	var handlers []gin.HandlerFunc
+	if cfg.CodecServer.RequireAuth {
+		// Only token verification is used, so no user directory is needed
+		authenticator, err := auth.NewAuthenticator([]byte(cfg.Auth.JWTSecret), time.Hour, nil)
+		if err != nil {
+			log.Fatal("Unable to create authenticator:", err)
+		}
+		handlers = append(handlers, authenticator.Middleware(), auth.RequireRoles(auth.StaffRoles...))
+	}
+
+	// The UI posts to {endpoint}/encode and {endpoint}/decode, optionally
+	// under a namespace path
+	handlers = append(handlers, gin.WrapH(converter.NewPayloadCodecHTTPHandler(encryption)))
+	router.POST("/*path", handlers...)
+
+	log.Println("Codec server starting on", cfg.CodecServer.ListenAddress)
+	if err := router.Run(cfg.CodecServer.ListenAddress); err != nil {
+		log.Fatal("Failed to start codec server:", err)
+	}
+}
+
+// loopback reports whether address only accepts connections from this
+// machine.
+func loopback(address string) bool {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"os"
+
+	"loan-origination-system/internal/creditbureau"
+)
+
+// Serves a stand-in for a credit bureau's REST API, backed by the simulator.
+// Run the worker with CREDIT_BUREAU_PROVIDER=http and CREDIT_BUREAU_URL
+// pointing here to exercise the HTTP provider end to end.
+func main() {
+	listenAddress := os.Getenv("CREDIT_BUREAU_STUB_LISTEN_ADDRESS")
+	if listenAddress == "" {
+		listenAddress = ":8083"
+	}
+	apiKey := os.Getenv("CREDIT_BUREAU_API_KEY")
+
+	handler := creditbureau.NewHandler(creditbureau.Simulator{Name: "Stub Bureau"}, apiKey)
+
+	log.Printf("Credit bureau stub listening on %s", listenAddress)
+	if err := http.ListenAndServe(listenAddress, handler); err != nil {
+		log.Fatal("Unable to start credit bureau stub:", err)
+	}
+}
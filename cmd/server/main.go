@@ -1,24 +1,97 @@
 package main
 
 import (
+	"context"
 	"log"
+	"time"
 
 	"loan-origination-system/internal/api"
+	"loan-origination-system/internal/api/auth"
+	"loan-origination-system/internal/config"
+	"loan-origination-system/internal/events"
+	"loan-origination-system/internal/ledger"
+	"loan-origination-system/internal/policy"
+	"loan-origination-system/internal/projection"
+	"loan-origination-system/internal/storage"
 	"loan-origination-system/pkg/temporal"
 
 	"github.com/gin-gonic/gin"
 )
 
 func main() {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal("Failed to load configuration:", err)
+	}
+
 	// Initialize Temporal client
-	temporalClient, err := temporal.NewClient()
+	temporalClient, err := temporal.NewClient(cfg.Temporal)
 	if err != nil {
 		log.Fatal("Failed to create Temporal client:", err)
 	}
 	defer temporalClient.Close()
 
+	// Decode payloads read from workflow history the same way the client does
+	dataConverter, err := temporal.NewDataConverter(cfg.Temporal)
+	if err != nil {
+		log.Fatal("Failed to create data converter:", err)
+	}
+
+	// Open the loan read model written by the worker
+	store, err := projection.Open(projection.DefaultDatabasePath)
+	if err != nil {
+		log.Fatal("Failed to open projection store:", err)
+	}
+	defer store.Close()
+
+	// Open the funding ledger the worker posts disbursements to
+	book, err := ledger.Open(ledger.DefaultDatabasePath)
+	if err != nil {
+		log.Fatal("Failed to open ledger:", err)
+	}
+	defer book.Close()
+
+	// Open the configured document storage
+	documents, err := storage.New(context.Background(), cfg.Storage)
+	if err != nil {
+		log.Fatal("Failed to open document storage:", err)
+	}
+
+	if cfg.Temporal.SearchKey == "" {
+		log.Println("TEMPORAL_SEARCH_KEY not set; new loans cannot be searched by borrower email")
+	}
+
+	// Load the underwriting policy new loans are started under
+	policies, err := policy.Load(cfg.PolicyFile)
+	if err != nil {
+		log.Fatal("Failed to load underwriting policy:", err)
+	}
+	log.Println("Using underwriting policy version", policies.Version)
+
+	// Push projected loan changes to Server-Sent Events streams
+	broker := events.NewBroker(store, time.Second)
+	go func() {
+		if err := broker.Run(context.Background()); err != nil {
+			log.Fatal("Failed to follow loan changes:", err)
+		}
+	}()
+
+	// Issue and verify bearer tokens for the demo persona users
+	if cfg.Auth.JWTSecret == "" {
+		log.Println("AUTH_JWT_SECRET not set, using a random secret; tokens will not survive a restart")
+	}
+	authenticator, err := auth.NewAuthenticator([]byte(cfg.Auth.JWTSecret), 12*time.Hour, auth.DemoUsers{Password: cfg.Auth.DemoPassword})
+	if err != nil {
+		log.Fatal("Failed to create authenticator:", err)
+	}
+
 	// Setup Gin router
 	router := gin.Default()
+	// Signatures record the client address, so forwarding headers are not
+	// trusted to set it
+	if err := router.SetTrustedProxies(nil); err != nil {
+		log.Fatal("Failed to configure trusted proxies:", err)
+	}
 
 	// Enable CORS for development
 	router.Use(func(c *gin.Context) {
@@ -35,10 +108,10 @@ func main() {
 	})
 
 	// Setup routes
-	api.SetupRoutes(router, temporalClient)
+	api.SetupRoutes(router, cfg, temporalClient, dataConverter, store, documents, policies, book, broker, authenticator)
 
-	log.Println("Server starting on :8082")
-	if err := router.Run(":8082"); err != nil {
+	log.Println("Server starting on", cfg.Server.ListenAddress)
+	if err := router.Run(cfg.Server.ListenAddress); err != nil {
 		log.Fatal("Failed to start server:", err)
 	}
 }
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+
+	"loan-origination-system/internal/config"
+	"loan-origination-system/internal/workflows"
+	"loan-origination-system/pkg/temporal"
+)
+
+// Registers the custom search attributes used by the loan origination
+// workflow. Run once against a fresh dev server before starting the worker.
+// With -borrower-email it prints the query that finds a borrower's loans
+// instead.
+func main() {
+	borrowerEmail := flag.String("borrower-email", "", "print the search query for this borrower's loans and exit")
+	flag.Parse()
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal("Unable to load configuration:", err)
+	}
+
+	if *borrowerEmail != "" {
+		if cfg.Temporal.SearchKey == "" {
+			log.Fatal("TEMPORAL_SEARCH_KEY is not set, so borrower emails are not indexed")
+		}
+		fmt.Printf("%s = %q\n", workflows.SearchAttributeBorrowerEmail, workflows.HashBorrowerEmail(cfg.Temporal.SearchKey, *borrowerEmail))
+		return
+	}
+
+	c, err := temporal.NewClient(cfg.Temporal)
+	if err != nil {
+		log.Fatal("Unable to create Temporal client:", err)
+	}
+	defer c.Close()
+
+	added, err := temporal.RegisterSearchAttributes(context.Background(), c, cfg.Temporal.Namespace, workflows.SearchAttributeTypes)
+	if err != nil {
+		log.Fatal("Unable to register search attributes:", err)
+	}
+
+	if len(added) == 0 {
+		log.Println("Search attributes already registered")
+		return
+	}
+	log.Println("Registered search attributes:", added)
+}
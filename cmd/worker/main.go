@@ -1,31 +1,84 @@
 package main
 
 import (
+	"context"
 	"log"
 
 	"loan-origination-system/internal/activities"
+	"loan-origination-system/internal/config"
+	"loan-origination-system/internal/creditbureau"
+	"loan-origination-system/internal/ledger"
+	"loan-origination-system/internal/notify"
+	"loan-origination-system/internal/projection"
+	"loan-origination-system/internal/storage"
 	"loan-origination-system/internal/workflows"
 	"loan-origination-system/pkg/temporal"
 )
 
 func main() {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal("Unable to load configuration:", err)
+	}
+
 	// Create Temporal client
-	c, err := temporal.NewClient()
+	c, err := temporal.NewClient(cfg.Temporal)
 	if err != nil {
 		log.Fatal("Unable to create Temporal client:", err)
 	}
 	defer c.Close()
 
+	// Open the loan read model updated by the projection activity
+	store, err := projection.Open(projection.DefaultDatabasePath)
+	if err != nil {
+		log.Fatal("Unable to open projection store:", err)
+	}
+	defer store.Close()
+
+	// Open the ledger funded loans are disbursed through
+	book, err := ledger.Open(ledger.DefaultDatabasePath)
+	if err != nil {
+		log.Fatal("Unable to open ledger:", err)
+	}
+	defer book.Close()
+
+	// Connect to the configured credit bureau
+	bureau, err := creditbureau.New(cfg.CreditBureau)
+	if err != nil {
+		log.Fatal("Unable to create credit bureau client:", err)
+	}
+
+	// Generated notices are stored with the uploaded documents
+	documents, err := storage.New(context.Background(), cfg.Storage)
+	if err != nil {
+		log.Fatal("Unable to open document storage:", err)
+	}
+
+	// Borrowers are notified by email and SMS
+	mailer, sms, err := notify.New(cfg.Notifications)
+	if err != nil {
+		log.Fatal("Unable to create notification channels:", err)
+	}
+
 	// Create worker
-	w := temporal.NewWorker(c)
+	w := temporal.NewWorker(c, cfg.Temporal.TaskQueue)
 
 	// Register workflows
 	w.RegisterWorkflow(workflows.LoanOriginationWorkflow)
 
 	// Register activities
-	w.RegisterActivity(activities.GenerateLoanAgreement)
-	w.RegisterActivity(activities.ProcessFunding)
-	w.RegisterActivity(activities.CreditScoreCheck)
+	w.RegisterActivity(&activities.AgreementActivities{Documents: documents})
+	w.RegisterActivity(&activities.FundingActivities{Ledger: book})
+	w.RegisterActivity(activities.SendFundingFailureNotification)
+	w.RegisterActivity(activities.VoidLoanAgreement)
+	w.RegisterActivity(activities.ReleaseRateLock)
+	w.RegisterActivity(&activities.CreditActivities{Bureau: bureau})
+	w.RegisterActivity(activities.ValueVehicle)
+	w.RegisterActivity(activities.CheckLiens)
+	w.RegisterActivity(activities.EvaluateLoan)
+	w.RegisterActivity(&activities.NoticeActivities{Documents: documents})
+	w.RegisterActivity(&activities.NotificationActivities{Mailer: mailer, SMS: sms})
+	w.RegisterActivity(&projection.Activities{Store: store})
 
 	log.Println("Starting Temporal worker...")
 	err = w.Run(nil)
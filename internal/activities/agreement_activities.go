@@ -0,0 +1,216 @@
+package activities
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	htmltemplate "html/template"
+	"path"
+	"text/template"
+	"time"
+
+	"loan-origination-system/internal/pdf"
+	"loan-origination-system/internal/storage"
+
+	"go.temporal.io/sdk/activity"
+)
+
+type GenerateLoanAgreementInput struct {
+	LoanApplicationID string `json:"loan_application_id"`
+	// Version numbers the agreements of a loan. Each change of terms draws
+	// up a new version, stored next to the earlier ones.
+	Version         int       `json:"version"`
+	BorrowerName    string    `json:"borrower_name"`
+	BorrowerEmail   string    `json:"borrower_email"`
+	Product         string    `json:"product"`
+	LoanPurpose     string    `json:"loan_purpose"`
+	PropertyAddress string    `json:"property_address,omitempty"`
+	Date            time.Time `json:"date"`
+	// Terms the agreement is drawn up for. A counter-offer the borrower
+	// accepts changes them, and the agreement is generated again.
+	LoanAmount     float64 `json:"loan_amount"`
+	InterestRate   float64 `json:"interest_rate"`
+	TermMonths     int     `json:"term_months"`
+	MonthlyPayment float64 `json:"monthly_payment"`
+}
+
+// StoredFile is a generated file in the document storage.
+type StoredFile struct {
+	FilePath    string `json:"file_path"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	SHA256      string `json:"sha256"`
+}
+
+type GenerateLoanAgreementResult struct {
+	HTML StoredFile `json:"html"`
+	PDF  StoredFile `json:"pdf"`
+}
+
+// AgreementActivities draws up loan agreements and stores them with the
+// loan's documents.
+type AgreementActivities struct {
+	Documents storage.BlobStore
+}
+
+// GenerateLoanAgreement renders the agreement for the given terms as HTML
+// and PDF and stores both. Rendering is deterministic, so a retried attempt
+// stores the same files under the same keys.
+func (a *AgreementActivities) GenerateLoanAgreement(ctx context.Context, input GenerateLoanAgreementInput) (*GenerateLoanAgreementResult, error) {
+	data := newAgreementData(input)
+
+	var html, text bytes.Buffer
+	if err := agreementHTMLTemplate.Execute(&html, data); err != nil {
+		return nil, err
+	}
+	if err := agreementTextTemplate.Execute(&text, data); err != nil {
+		return nil, err
+	}
+	document := pdf.FromText(data.Title, text.String())
+
+	base := path.Join("loans", input.LoanApplicationID, "agreements", fmt.Sprintf("v%d", input.Version))
+	htmlFile, err := storeFile(ctx, a.Documents, base+".html", html.Bytes(), "text/html; charset=utf-8")
+	if err != nil {
+		return nil, err
+	}
+	pdfFile, err := storeFile(ctx, a.Documents, base+".pdf", document, "application/pdf")
+	if err != nil {
+		return nil, err
+	}
+
+	activity.GetLogger(ctx).Info("Loan agreement generated", "loanApplicationID", input.LoanApplicationID,
+		"version", input.Version, "sha256", pdfFile.SHA256)
+	return &GenerateLoanAgreementResult{HTML: htmlFile, PDF: pdfFile}, nil
+}
+
+// storeFile stores generated contents under key.
+func storeFile(ctx context.Context, documents storage.BlobStore, key string, contents []byte, contentType string) (StoredFile, error) {
+	object, err := storage.PutObject(ctx, documents, key, bytes.NewReader(contents), int64(len(contents)), contentType)
+	if err != nil {
+		return StoredFile{}, err
+	}
+	return StoredFile{
+		FilePath:    object.Key,
+		ContentType: object.ContentType,
+		Size:        object.Size,
+		SHA256:      object.SHA256,
+	}, nil
+}
+
+// agreementData is the input with the figures the templates print.
+type agreementData struct {
+	GenerateLoanAgreementInput
+	Title           string
+	Dated           string
+	Amount          string
+	Rate            string
+	Payment         string
+	TotalOfPayments string
+	FinanceCharge   string
+}
+
+func newAgreementData(input GenerateLoanAgreementInput) agreementData {
+	total := input.MonthlyPayment * float64(input.TermMonths)
+	return agreementData{
+		GenerateLoanAgreementInput: input,
+		Title:                      fmt.Sprintf("Loan Agreement %s (version %d)", input.LoanApplicationID, input.Version),
+		Dated:                      input.Date.Format("January 2, 2006"),
+		Amount:                     money(input.LoanAmount),
+		Rate:                       fmt.Sprintf("%.3f%%", input.InterestRate*100),
+		Payment:                    money(input.MonthlyPayment),
+		TotalOfPayments:            money(total),
+		FinanceCharge:              money(total - input.LoanAmount),
+	}
+}
+
+func money(amount float64) string {
+	return fmt.Sprintf("$%.2f", amount)
+}
+
+// The HTML and text templates carry the same wording; the text one is laid
+// out as the PDF.
+var agreementHTMLTemplate = htmltemplate.Must(htmltemplate.New("agreement.html").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: Georgia, serif; max-width: 720px; margin: 2em auto; line-height: 1.5; }
+h1 { font-size: 1.4em; }
+h2 { font-size: 1.1em; margin-top: 1.5em; }
+table { border-collapse: collapse; }
+td { padding: 2px 12px 2px 0; }
+</style>
+</head>
+<body>
+<h1>Loan Agreement</h1>
+<p>Agreement {{.LoanApplicationID}}, version {{.Version}}, dated {{.Dated}}</p>
+
+<h2>Parties</h2>
+<p>Borrower: {{.BorrowerName}} ({{.BorrowerEmail}})<br>Lender: the lender named in the loan application</p>
+
+<h2>Loan</h2>
+<table>
+<tr><td>Product</td><td>{{.Product}}</td></tr>
+<tr><td>Purpose</td><td>{{.LoanPurpose}}</td></tr>
+{{- if .PropertyAddress}}
+<tr><td>Property</td><td>{{.PropertyAddress}}</td></tr>
+{{- end}}
+<tr><td>Amount financed</td><td>{{.Amount}}</td></tr>
+<tr><td>Annual interest rate</td><td>{{.Rate}}</td></tr>
+<tr><td>Term</td><td>{{.TermMonths}} months</td></tr>
+<tr><td>Monthly payment</td><td>{{.Payment}}</td></tr>
+<tr><td>Total of payments</td><td>{{.TotalOfPayments}}</td></tr>
+<tr><td>Finance charge</td><td>{{.FinanceCharge}}</td></tr>
+</table>
+
+<h2>Promise to pay</h2>
+<p>The borrower promises to repay the amount financed with interest at the annual rate above, in {{.TermMonths}} equal monthly payments of {{.Payment}}, the first due one month after funding.</p>
+
+<h2>Prepayment</h2>
+<p>The borrower may prepay all or part of the loan at any time without penalty. Prepayments are applied to principal.</p>
+
+<h2>Default</h2>
+<p>If a payment is more than 30 days late, the lender may declare the unpaid balance due after giving the notice required by law.</p>
+
+<h2>Signatures</h2>
+<p>Borrower: ______________________________ Date: __________</p>
+<p>Lender: ______________________________ Date: __________</p>
+</body>
+</html>
+`))
+
+var agreementTextTemplate = template.Must(template.New("agreement.txt").Parse(`# Loan Agreement
+Agreement {{.LoanApplicationID}}, version {{.Version}}, dated {{.Dated}}
+
+# Parties
+Borrower: {{.BorrowerName}} ({{.BorrowerEmail}})
+Lender: the lender named in the loan application
+
+# Loan
+Product: {{.Product}}
+Purpose: {{.LoanPurpose}}
+{{- if .PropertyAddress}}
+Property: {{.PropertyAddress}}
+{{- end}}
+Amount financed: {{.Amount}}
+Annual interest rate: {{.Rate}}
+Term: {{.TermMonths}} months
+Monthly payment: {{.Payment}}
+Total of payments: {{.TotalOfPayments}}
+Finance charge: {{.FinanceCharge}}
+
+# Promise to pay
+The borrower promises to repay the amount financed with interest at the annual rate above, in {{.TermMonths}} equal monthly payments of {{.Payment}}, the first due one month after funding.
+
+# Prepayment
+The borrower may prepay all or part of the loan at any time without penalty. Prepayments are applied to principal.
+
+# Default
+If a payment is more than 30 days late, the lender may declare the unpaid balance due after giving the notice required by law.
+
+# Signatures
+Borrower: ______________________________ Date: __________
+
+Lender: ______________________________ Date: __________
+`))
@@ -0,0 +1,79 @@
+package activities
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"loan-origination-system/internal/storage"
+
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/testsuite"
+)
+
+func TestGenerateLoanAgreement(t *testing.T) {
+	store, err := storage.NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestActivityEnvironment()
+	env.RegisterActivity(&AgreementActivities{Documents: store})
+
+	input := GenerateLoanAgreementInput{
+		LoanApplicationID: "loan-1",
+		Version:           2,
+		BorrowerName:      "Jane <Doe>",
+		BorrowerEmail:     "jane@example.com",
+		Product:           "mortgage",
+		LoanPurpose:       "home purchase",
+		PropertyAddress:   "1 Main St",
+		Date:              time.Date(2024, 6, 3, 15, 0, 0, 0, time.UTC),
+		LoanAmount:        200000,
+		InterestRate:      0.075,
+		TermMonths:        240,
+		MonthlyPayment:    1611.19,
+	}
+
+	var agreements *AgreementActivities
+	value, err := env.ExecuteActivity(agreements.GenerateLoanAgreement, input)
+	require.NoError(t, err)
+	var result GenerateLoanAgreementResult
+	require.NoError(t, value.Get(&result))
+
+	require.Equal(t, "loans/loan-1/agreements/v2.html", result.HTML.FilePath)
+	require.Equal(t, "text/html; charset=utf-8", result.HTML.ContentType)
+	require.Equal(t, "loans/loan-1/agreements/v2.pdf", result.PDF.FilePath)
+	require.Equal(t, "application/pdf", result.PDF.ContentType)
+	require.Len(t, result.PDF.SHA256, 64)
+
+	html := string(readObject(t, store, result.HTML))
+	require.Contains(t, html, "version 2, dated June 3, 2024")
+	require.Contains(t, html, "Jane &lt;Doe&gt;")
+	require.Contains(t, html, "<td>Property</td><td>1 Main St</td>")
+	require.Contains(t, html, "<td>Amount financed</td><td>$200000.00</td>")
+	require.Contains(t, html, "<td>Annual interest rate</td><td>7.500%</td>")
+	require.Contains(t, html, "<td>Total of payments</td><td>$386685.60</td>")
+
+	pdf := string(readObject(t, store, result.PDF))
+	require.Contains(t, pdf, "%PDF-1.4")
+	require.Contains(t, pdf, "(Borrower: Jane <Doe> \\(jane@example.com\\)) Tj")
+	require.Contains(t, pdf, "(Monthly payment: $1611.19) Tj")
+
+	// Generating the same version again stores the same files
+	value, err = env.ExecuteActivity(agreements.GenerateLoanAgreement, input)
+	require.NoError(t, err)
+	var again GenerateLoanAgreementResult
+	require.NoError(t, value.Get(&again))
+	require.Equal(t, result, again)
+}
+
+func readObject(t *testing.T, store storage.BlobStore, file StoredFile) []byte {
+	reader, err := store.Get(context.Background(), file.FilePath)
+	require.NoError(t, err)
+	defer reader.Close()
+	contents, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	require.Equal(t, file.Size, int64(len(contents)))
+	return contents
+}
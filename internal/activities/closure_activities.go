@@ -0,0 +1,36 @@
+package activities
+
+import (
+	"context"
+	"time"
+
+	"go.temporal.io/sdk/activity"
+)
+
+// Cleanup activities run when an application is withdrawn or cancelled
+// before funding. Each is safe to retry.
+
+type VoidLoanAgreementInput struct {
+	LoanApplicationID string `json:"loan_application_id"`
+	Reason            string `json:"reason"`
+}
+
+type ReleaseRateLockInput struct {
+	LoanApplicationID string `json:"loan_application_id"`
+}
+
+func VoidLoanAgreement(ctx context.Context, input VoidLoanAgreementInput) error {
+	// In a real system, this would mark the agreement void in the document
+	// system so it can no longer be signed
+	activity.GetLogger(ctx).Info("Voided loan agreement", "loanApplicationID", input.LoanApplicationID, "reason", input.Reason)
+	time.Sleep(500 * time.Millisecond) // Simulate processing time
+	return nil
+}
+
+func ReleaseRateLock(ctx context.Context, input ReleaseRateLockInput) error {
+	// In a real system, this would release the rate lock with the pricing
+	// engine; releasing a lock that is already gone succeeds
+	activity.GetLogger(ctx).Info("Released rate lock", "loanApplicationID", input.LoanApplicationID)
+	time.Sleep(500 * time.Millisecond) // Simulate processing time
+	return nil
+}
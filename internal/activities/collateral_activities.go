@@ -0,0 +1,121 @@
+package activities
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"strings"
+	"time"
+
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/temporal"
+)
+
+// CollateralRejectedErrorType marks collateral that cannot be valued or
+// searched whatever the retry, such as an invalid VIN.
+const CollateralRejectedErrorType = "CollateralRejected"
+
+type VehicleValuationInput struct {
+	LoanApplicationID string `json:"loan_application_id"`
+	VIN               string `json:"vin"`
+	Year              int    `json:"year"`
+	Make              string `json:"make"`
+	Model             string `json:"model"`
+	Mileage           int    `json:"mileage"`
+}
+
+type VehicleValuationResult struct {
+	Value     float64   `json:"value"`
+	Source    string    `json:"source"`
+	Reference string    `json:"reference"`
+	ValuedAt  time.Time `json:"valued_at"`
+}
+
+type LienCheckInput struct {
+	LoanApplicationID string  `json:"loan_application_id"`
+	BorrowerName      string  `json:"borrower_name"`
+	PropertyAddress   string  `json:"property_address"`
+	PropertyValue     float64 `json:"property_value"`
+}
+
+// Lien is a recorded claim against the property.
+type Lien struct {
+	Holder   string  `json:"holder"`
+	Position int     `json:"position"`
+	Balance  float64 `json:"balance"`
+}
+
+type LienCheckResult struct {
+	Liens     []Lien    `json:"liens"`
+	Reference string    `json:"reference"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// ValueVehicle prices a vehicle from a simulated valuation guide: a base
+// price for the make and model, depreciated by age and mileage.
+func ValueVehicle(ctx context.Context, input VehicleValuationInput) (*VehicleValuationResult, error) {
+	now := time.Now().UTC()
+
+	if len(input.VIN) != 17 {
+		err := fmt.Errorf("invalid VIN %q: must be 17 characters", input.VIN)
+		return nil, temporal.NewNonRetryableApplicationError(err.Error(), CollateralRejectedErrorType, err)
+	}
+	age := now.Year() - input.Year
+	if input.Year == 0 || age < -1 || age > 30 {
+		err := fmt.Errorf("vehicle year %d is outside the valuation guide", input.Year)
+		return nil, temporal.NewNonRetryableApplicationError(err.Error(), CollateralRejectedErrorType, err)
+	}
+	if age < 0 {
+		age = 0
+	}
+
+	seed := hashString(strings.ToLower(input.Make + "|" + input.Model))
+	base := float64(18000 + seed%42*1000)
+	value := base * math.Pow(0.85, float64(age)) * math.Max(0.4, 1-float64(input.Mileage)/250000)
+
+	result := &VehicleValuationResult{
+		Value:     math.Round(value/50) * 50,
+		Source:    "Simulated Valuation Guide",
+		Reference: fmt.Sprintf("VAL-%016x", hashString(input.VIN+input.LoanApplicationID)),
+		ValuedAt:  now,
+	}
+	activity.GetLogger(ctx).Info("Vehicle valued", "vin", input.VIN, "value", result.Value)
+	return result, nil
+}
+
+// CheckLiens searches simulated title records for liens on the property.
+// Most properties carry a first mortgage; the balance is derived from the
+// address so repeated checks agree.
+func CheckLiens(ctx context.Context, input LienCheckInput) (*LienCheckResult, error) {
+	if input.PropertyAddress == "" {
+		err := errors.New("property address is required for a lien check")
+		return nil, temporal.NewNonRetryableApplicationError(err.Error(), CollateralRejectedErrorType, err)
+	}
+
+	seed := hashString(strings.ToLower(strings.TrimSpace(input.PropertyAddress)))
+	result := &LienCheckResult{
+		Liens:     []Lien{},
+		Reference: fmt.Sprintf("TITLE-%016x", seed^hashString(input.LoanApplicationID)),
+		CheckedAt: time.Now().UTC(),
+	}
+	if seed%5 != 0 {
+		// Between 30% and 70% of the property value is still owed
+		owed := 0.3 + float64(seed>>8%41)/100
+		result.Liens = append(result.Liens, Lien{
+			Holder:   "First National Bank",
+			Position: 1,
+			Balance:  math.Round(input.PropertyValue * owed),
+		})
+	}
+
+	activity.GetLogger(ctx).Info("Lien check completed", "liens", len(result.Liens), "reference", result.Reference)
+	return result, nil
+}
+
+func hashString(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	return h.Sum64()
+}
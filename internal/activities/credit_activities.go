@@ -0,0 +1,60 @@
+package activities
+
+import (
+	"context"
+
+	"loan-origination-system/internal/creditbureau"
+
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/temporal"
+)
+
+// CreditBureauRejectedErrorType marks credit checks the bureau rejected in a
+// way retrying cannot fix, such as an unknown borrower.
+const CreditBureauRejectedErrorType = "CreditBureauRejected"
+
+type CreditScoreCheckInput struct {
+	LoanApplicationID string                `json:"loan_application_id"`
+	BorrowerName      string                `json:"borrower_name"`
+	BorrowerEmail     string                `json:"borrower_email"`
+	PullType          creditbureau.PullType `json:"pull_type"`
+}
+
+type CreditScoreCheckResult struct {
+	CreditScore int                 `json:"credit_score"`
+	Status      string              `json:"status"`
+	Report      creditbureau.Report `json:"report"`
+}
+
+// CreditActivities pulls credit reports from the configured bureau.
+type CreditActivities struct {
+	Bureau creditbureau.CreditBureau
+}
+
+func (a *CreditActivities) CreditScoreCheck(ctx context.Context, input CreditScoreCheckInput) (*CreditScoreCheckResult, error) {
+	logger := activity.GetLogger(ctx)
+
+	report, err := a.Bureau.PullReport(ctx, creditbureau.Request{
+		LoanApplicationID: input.LoanApplicationID,
+		BorrowerName:      input.BorrowerName,
+		BorrowerEmail:     input.BorrowerEmail,
+		PullType:          input.PullType,
+	})
+	if err == nil {
+		err = report.Validate()
+	}
+	if err != nil {
+		logger.Warn("Credit report pull failed", "attempt", activity.GetInfo(ctx).Attempt, "error", err)
+		if creditbureau.IsPermanent(err) {
+			return nil, temporal.NewNonRetryableApplicationError(err.Error(), CreditBureauRejectedErrorType, err)
+		}
+		return nil, err
+	}
+
+	logger.Info("Credit report pulled", "bureau", report.Bureau, "reference", report.Reference, "score", report.Score)
+	return &CreditScoreCheckResult{
+		CreditScore: report.Score,
+		Status:      "completed",
+		Report:      *report,
+	}, nil
+}
@@ -0,0 +1,26 @@
+package activities
+
+import (
+	"context"
+
+	"loan-origination-system/internal/decisioning"
+
+	"go.temporal.io/sdk/activity"
+)
+
+type EvaluateLoanInput struct {
+	LoanApplicationID string                  `json:"loan_application_id"`
+	Application       decisioning.Application `json:"application"`
+	// Policy is the decisioning policy of the loan's product, from the
+	// policy snapshotted when the workflow started
+	Policy decisioning.Policy `json:"policy"`
+}
+
+// EvaluateLoan runs automated decisioning for a loan.
+func EvaluateLoan(ctx context.Context, input EvaluateLoanInput) (*decisioning.Result, error) {
+	result := input.Policy.Evaluate(input.Application)
+
+	activity.GetLogger(ctx).Info("Loan evaluated", "loanApplicationID", input.LoanApplicationID,
+		"recommendation", result.Recommendation, "ltv", result.LTV, "creditTier", result.CreditTier)
+	return &result, nil
+}
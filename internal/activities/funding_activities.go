@@ -0,0 +1,108 @@
+package activities
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"loan-origination-system/internal/ledger"
+
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/temporal"
+)
+
+// LedgerRejectedErrorType marks disbursements the ledger refused, such as a
+// loan already disbursed for another amount. Retrying cannot fix them.
+const LedgerRejectedErrorType = "LedgerRejected"
+
+type ProcessFundingInput struct {
+	LoanApplicationID string `json:"loan_application_id"`
+	// Attempt counts the loan's funding attempts, starting at 1
+	Attempt  int       `json:"attempt"`
+	Amount   float64   `json:"amount"`
+	FundedAt time.Time `json:"funded_at"`
+}
+
+type ProcessFundingResult struct {
+	LedgerEntryID string    `json:"ledger_entry_id"`
+	PostedAt      time.Time `json:"posted_at"`
+}
+
+type ReverseFundingInput struct {
+	LoanApplicationID string    `json:"loan_application_id"`
+	Attempt           int       `json:"attempt"`
+	ReversedAt        time.Time `json:"reversed_at"`
+}
+
+type ReverseFundingResult struct {
+	// LedgerEntryID is empty when the attempt had posted nothing to reverse
+	LedgerEntryID string `json:"ledger_entry_id,omitempty"`
+}
+
+type SendFundingFailureNotificationInput struct {
+	LoanApplicationID string  `json:"loan_application_id"`
+	FundManagerID     string  `json:"fund_manager_id"`
+	Attempt           int     `json:"attempt"`
+	Amount            float64 `json:"amount"`
+	Error             string  `json:"error"`
+}
+
+// FundingActivities disburses funded loans through the ledger.
+type FundingActivities struct {
+	Ledger *ledger.Ledger
+}
+
+// ProcessFunding posts the loan's disbursement to the ledger. The entry is
+// keyed by the loan and the funding attempt, so a retried activity returns
+// the entry already posted.
+func (a *FundingActivities) ProcessFunding(ctx context.Context, input ProcessFundingInput) (*ProcessFundingResult, error) {
+	entry, err := a.Ledger.Post(ctx, ledger.Disbursement(input.LoanApplicationID, input.Attempt, ledger.Cents(input.Amount), input.FundedAt))
+	if err != nil {
+		return nil, ledgerError(err)
+	}
+
+	activity.GetLogger(ctx).Info("Loan disbursed", "loanApplicationID", input.LoanApplicationID,
+		"entryID", entry.ID, "amount", input.Amount)
+	return &ProcessFundingResult{LedgerEntryID: entry.ID, PostedAt: entry.PostedAt}, nil
+}
+
+// ReverseFunding compensates a failed funding attempt by reversing its
+// disbursement, if one was posted. Reversing it again returns the reversal
+// already posted.
+func (a *FundingActivities) ReverseFunding(ctx context.Context, input ReverseFundingInput) (*ReverseFundingResult, error) {
+	logger := activity.GetLogger(ctx)
+
+	disbursement, err := a.Ledger.Entry(ctx, ledger.DisbursementID(input.LoanApplicationID, input.Attempt))
+	if errors.Is(err, ledger.ErrNotFound) {
+		logger.Info("No disbursement to reverse", "loanApplicationID", input.LoanApplicationID, "attempt", input.Attempt)
+		return &ReverseFundingResult{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	reversal, err := a.Ledger.Post(ctx, ledger.Reversal(disbursement, input.ReversedAt))
+	if err != nil {
+		return nil, ledgerError(err)
+	}
+
+	logger.Info("Disbursement reversed", "loanApplicationID", input.LoanApplicationID, "entryID", reversal.ID)
+	return &ReverseFundingResult{LedgerEntryID: reversal.ID}, nil
+}
+
+// ledgerError marks the errors of entries the ledger refused as not
+// retryable.
+func ledgerError(err error) error {
+	if errors.Is(err, ledger.ErrConflict) || errors.Is(err, ledger.ErrUnbalanced) || errors.Is(err, ledger.ErrUnknownAccount) {
+		return temporal.NewNonRetryableApplicationError(err.Error(), LedgerRejectedErrorType, err)
+	}
+	return err
+}
+
+func SendFundingFailureNotification(ctx context.Context, input SendFundingFailureNotificationInput) error {
+	// In a real system, this would email the fund manager who released the
+	// loan
+	activity.GetLogger(ctx).Info("Sent funding failure notification", "loanApplicationID", input.LoanApplicationID,
+		"fundManagerID", input.FundManagerID, "attempt", input.Attempt, "error", input.Error)
+	return nil
+}
@@ -0,0 +1,88 @@
+package activities
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"loan-origination-system/internal/ledger"
+
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/testsuite"
+)
+
+func TestProcessFunding(t *testing.T) {
+	book, err := ledger.Open(filepath.Join(t.TempDir(), "ledger.db"))
+	require.NoError(t, err)
+	defer book.Close()
+
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestActivityEnvironment()
+	env.RegisterActivity(&FundingActivities{Ledger: book})
+
+	input := ProcessFundingInput{
+		LoanApplicationID: "loan-1",
+		Attempt:           1,
+		Amount:            250000,
+		FundedAt:          time.Date(2024, 6, 3, 15, 0, 0, 0, time.UTC),
+	}
+
+	var funding *FundingActivities
+	for attempt := 0; attempt < 2; attempt++ {
+		value, err := env.ExecuteActivity(funding.ProcessFunding, input)
+		require.NoError(t, err)
+		var result ProcessFundingResult
+		require.NoError(t, value.Get(&result))
+		require.Equal(t, "disbursement-loan-1-1", result.LedgerEntryID)
+		require.Equal(t, input.FundedAt, result.PostedAt)
+	}
+
+	entries, err := book.Entries(context.Background(), "loan-1")
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+
+	input.Amount = 200000
+	_, err = env.ExecuteActivity(funding.ProcessFunding, input)
+	var appErr *temporal.ApplicationError
+	require.ErrorAs(t, err, &appErr)
+	require.Equal(t, LedgerRejectedErrorType, appErr.Type())
+	require.True(t, appErr.NonRetryable())
+}
+
+func TestReverseFunding(t *testing.T) {
+	book, err := ledger.Open(filepath.Join(t.TempDir(), "ledger.db"))
+	require.NoError(t, err)
+	defer book.Close()
+
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestActivityEnvironment()
+	env.RegisterActivity(&FundingActivities{Ledger: book})
+
+	fundedAt := time.Date(2024, 6, 3, 15, 0, 0, 0, time.UTC)
+	input := ReverseFundingInput{LoanApplicationID: "loan-1", Attempt: 1, ReversedAt: fundedAt.Add(time.Minute)}
+
+	var funding *FundingActivities
+	value, err := env.ExecuteActivity(funding.ReverseFunding, input)
+	require.NoError(t, err)
+	var result ReverseFundingResult
+	require.NoError(t, value.Get(&result))
+	require.Empty(t, result.LedgerEntryID)
+
+	_, err = env.ExecuteActivity(funding.ProcessFunding, ProcessFundingInput{
+		LoanApplicationID: "loan-1", Attempt: 1, Amount: 250000, FundedAt: fundedAt,
+	})
+	require.NoError(t, err)
+
+	value, err = env.ExecuteActivity(funding.ReverseFunding, input)
+	require.NoError(t, err)
+	require.NoError(t, value.Get(&result))
+	require.Equal(t, "reversal-disbursement-loan-1-1", result.LedgerEntryID)
+
+	balances, err := book.Balances(context.Background())
+	require.NoError(t, err)
+	for _, balance := range balances {
+		require.Zero(t, balance.Balance, balance.Account)
+	}
+}
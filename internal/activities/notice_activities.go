@@ -0,0 +1,182 @@
+package activities
+
+import (
+	"bytes"
+	"context"
+	htmltemplate "html/template"
+	"path"
+	"text/template"
+	"time"
+
+	"loan-origination-system/internal/pdf"
+	"loan-origination-system/internal/storage"
+
+	"go.temporal.io/sdk/activity"
+)
+
+// NoticeReason is a principal reason for declining the loan.
+type NoticeReason struct {
+	Code        string `json:"code"`
+	Description string `json:"description"`
+}
+
+// CreditScoreDisclosure is the credit score the decision relied on, which
+// the borrower must be told about.
+type CreditScoreDisclosure struct {
+	Score           int       `json:"score"`
+	ScoreModel      string    `json:"score_model"`
+	Bureau          string    `json:"bureau"`
+	ReportReference string    `json:"report_reference"`
+	ScoreDate       time.Time `json:"score_date"`
+}
+
+type GenerateAdverseActionNoticeInput struct {
+	LoanApplicationID string `json:"loan_application_id"`
+	// NoticeID names the notice's files and is its document ID. The workflow
+	// derives it from the decision, so a retried attempt overwrites the same
+	// files and the notice of another decision is kept.
+	NoticeID      string         `json:"notice_id"`
+	BorrowerName  string         `json:"borrower_name"`
+	BorrowerEmail string         `json:"borrower_email"`
+	Product       string         `json:"product"`
+	LoanAmount    float64        `json:"loan_amount"`
+	DecisionDate  time.Time      `json:"decision_date"`
+	Reasons       []NoticeReason `json:"reasons"`
+	// CreditScore is nil when no credit report was used
+	CreditScore *CreditScoreDisclosure `json:"credit_score"`
+}
+
+type GenerateAdverseActionNoticeResult struct {
+	DocumentID string     `json:"document_id"`
+	HTML       StoredFile `json:"html"`
+	PDF        StoredFile `json:"pdf"`
+}
+
+// NoticeActivities renders notices to the borrower and stores them with the
+// loan's documents.
+type NoticeActivities struct {
+	Documents storage.BlobStore
+}
+
+// GenerateAdverseActionNotice renders the notice sent when a loan is
+// declined, with its principal reasons, the credit score disclosure and the
+// borrower's rights, as HTML and PDF and stores both. The PDF is the copy
+// mailed or handed to the borrower.
+func (a *NoticeActivities) GenerateAdverseActionNotice(ctx context.Context, input GenerateAdverseActionNoticeInput) (*GenerateAdverseActionNoticeResult, error) {
+	var html, text bytes.Buffer
+	if err := adverseActionNoticeHTMLTemplate.Execute(&html, input); err != nil {
+		return nil, err
+	}
+	if err := adverseActionNoticeTextTemplate.Execute(&text, input); err != nil {
+		return nil, err
+	}
+	document := pdf.FromText("Notice of Action Taken "+input.LoanApplicationID, text.String())
+
+	base := path.Join("loans", input.LoanApplicationID, "documents", input.NoticeID)
+	htmlFile, err := storeFile(ctx, a.Documents, base+".html", html.Bytes(), "text/html; charset=utf-8")
+	if err != nil {
+		return nil, err
+	}
+	pdfFile, err := storeFile(ctx, a.Documents, base+".pdf", document, "application/pdf")
+	if err != nil {
+		return nil, err
+	}
+
+	activity.GetLogger(ctx).Info("Adverse action notice generated", "loanApplicationID", input.LoanApplicationID,
+		"noticeID", input.NoticeID, "reasons", len(input.Reasons))
+	return &GenerateAdverseActionNoticeResult{DocumentID: input.NoticeID, HTML: htmlFile, PDF: pdfFile}, nil
+}
+
+// The HTML and text templates carry the same wording; the text one is laid
+// out as the PDF.
+var adverseActionNoticeHTMLTemplate = htmltemplate.Must(htmltemplate.New("adverse-action-notice.html").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Notice of Action Taken</title>
+<style>
+body { font-family: Georgia, serif; max-width: 720px; margin: 2em auto; line-height: 1.5; }
+h1 { font-size: 1.4em; }
+h2 { font-size: 1.1em; margin-top: 1.5em; }
+</style>
+</head>
+<body>
+<h1>Notice of Action Taken</h1>
+<p>Date: {{.DecisionDate.Format "January 2, 2006"}}</p>
+<p>{{.BorrowerName}}<br>{{.BorrowerEmail}}</p>
+<p>Re: {{.Product}} loan application {{.LoanApplicationID}} for ${{printf "%.2f" .LoanAmount}}</p>
+
+<p>Thank you for your application. We regret that we are unable to approve your request.</p>
+
+<h2>Principal reasons for our decision</h2>
+<ol>
+{{- range .Reasons}}
+<li>{{.Description}}</li>
+{{- end}}
+</ol>
+
+<h2>Your credit score</h2>
+{{- with .CreditScore}}
+<p>Our decision was based in whole or in part on information obtained in a report from the consumer reporting agency listed below. You have a right under the Fair Credit Reporting Act to know the information contained in your credit file at the consumer reporting agency. The reporting agency played no part in our decision and is unable to supply specific reasons why we have denied credit to you.</p>
+<p>{{.Bureau}}<br>Report reference: {{.ReportReference}}</p>
+<p>We also obtained your credit score from this consumer reporting agency and used it in making our credit decision.</p>
+<ul>
+<li>Your credit score: {{.Score}}</li>
+<li>Scoring model: {{.ScoreModel}}</li>
+<li>Scores range from a low of 300 to a high of 850</li>
+<li>Date: {{.ScoreDate.Format "January 2, 2006"}}</li>
+</ul>
+{{- else}}
+<p>Our decision was not based on information from a consumer reporting agency.</p>
+{{- end}}
+
+<h2>Your rights</h2>
+{{- if .CreditScore}}
+<p>You have a right to obtain a free copy of your credit report from the consumer reporting agency named above if you request it within 60 days of receiving this notice. You also have a right to dispute with the consumer reporting agency the accuracy or completeness of any information in the report it furnished.</p>
+{{- end}}
+<p>The federal Equal Credit Opportunity Act prohibits creditors from discriminating against credit applicants on the basis of race, color, religion, national origin, sex, marital status, or age (provided the applicant has the capacity to enter into a binding contract); because all or part of the applicant's income derives from any public assistance program; or because the applicant has in good faith exercised any right under the Consumer Credit Protection Act. The federal agency that administers compliance with this law concerning this creditor is the Consumer Financial Protection Bureau, 1700 G Street NW, Washington, DC 20552.</p>
+</body>
+</html>
+`))
+
+var adverseActionNoticeTextTemplate = template.Must(template.New("adverse-action-notice.txt").Funcs(template.FuncMap{
+	"inc": func(i int) int { return i + 1 },
+}).Parse(`# Notice of Action Taken
+Date: {{.DecisionDate.Format "January 2, 2006"}}
+
+{{.BorrowerName}}
+{{.BorrowerEmail}}
+
+Re: {{.Product}} loan application {{.LoanApplicationID}} for ${{printf "%.2f" .LoanAmount}}
+
+Thank you for your application. We regret that we are unable to approve your request.
+
+# Principal reasons for our decision
+{{- range $i, $reason := .Reasons}}
+{{inc $i}}. {{$reason.Description}}
+{{- end}}
+
+# Your credit score
+{{- with .CreditScore}}
+Our decision was based in whole or in part on information obtained in a report from the consumer reporting agency listed below. You have a right under the Fair Credit Reporting Act to know the information contained in your credit file at the consumer reporting agency. The reporting agency played no part in our decision and is unable to supply specific reasons why we have denied credit to you.
+
+{{.Bureau}}
+Report reference: {{.ReportReference}}
+
+We also obtained your credit score from this consumer reporting agency and used it in making our credit decision.
+
+- Your credit score: {{.Score}}
+- Scoring model: {{.ScoreModel}}
+- Scores range from a low of 300 to a high of 850
+- Date: {{.ScoreDate.Format "January 2, 2006"}}
+{{- else}}
+Our decision was not based on information from a consumer reporting agency.
+{{- end}}
+
+# Your rights
+{{- if .CreditScore}}
+You have a right to obtain a free copy of your credit report from the consumer reporting agency named above if you request it within 60 days of receiving this notice. You also have a right to dispute with the consumer reporting agency the accuracy or completeness of any information in the report it furnished.
+{{- end}}
+
+The federal Equal Credit Opportunity Act prohibits creditors from discriminating against credit applicants on the basis of race, color, religion, national origin, sex, marital status, or age (provided the applicant has the capacity to enter into a binding contract); because all or part of the applicant's income derives from any public assistance program; or because the applicant has in good faith exercised any right under the Consumer Credit Protection Act. The federal agency that administers compliance with this law concerning this creditor is the Consumer Financial Protection Bureau, 1700 G Street NW, Washington, DC 20552.
+`))
@@ -0,0 +1,73 @@
+package activities
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"loan-origination-system/internal/storage"
+
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/testsuite"
+)
+
+func TestGenerateAdverseActionNotice(t *testing.T) {
+	store, err := storage.NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestActivityEnvironment()
+	env.RegisterActivity(&NoticeActivities{Documents: store})
+
+	decided := time.Date(2024, 6, 3, 15, 0, 0, 0, time.UTC)
+	input := GenerateAdverseActionNoticeInput{
+		LoanApplicationID: "loan-1",
+		NoticeID:          "adverse-action-notice-decision-loan-1",
+		BorrowerName:      "Jane <Doe>",
+		BorrowerEmail:     "jane@example.com",
+		Product:           "mortgage",
+		LoanAmount:        250000,
+		DecisionDate:      decided,
+		Reasons:           []NoticeReason{{Code: "DTI_ABOVE_MAXIMUM", Description: "DTI 55.0% exceeds the maximum of 50.0%"}},
+		CreditScore:       &CreditScoreDisclosure{Score: 610, ScoreModel: "FICO 8", Bureau: "Test Bureau", ReportReference: "report-1", ScoreDate: decided},
+	}
+
+	var notices *NoticeActivities
+	value, err := env.ExecuteActivity(notices.GenerateAdverseActionNotice, input)
+	require.NoError(t, err)
+	var result GenerateAdverseActionNoticeResult
+	require.NoError(t, value.Get(&result))
+
+	require.Equal(t, "adverse-action-notice-decision-loan-1", result.DocumentID)
+	require.Equal(t, "loans/loan-1/documents/adverse-action-notice-decision-loan-1.html", result.HTML.FilePath)
+	require.Equal(t, "text/html; charset=utf-8", result.HTML.ContentType)
+	require.Equal(t, "loans/loan-1/documents/adverse-action-notice-decision-loan-1.pdf", result.PDF.FilePath)
+	require.Equal(t, "application/pdf", result.PDF.ContentType)
+	require.Len(t, result.PDF.SHA256, 64)
+
+	stored := func(file StoredFile) string {
+		reader, err := store.Get(context.Background(), file.FilePath)
+		require.NoError(t, err)
+		defer reader.Close()
+		contents, err := io.ReadAll(reader)
+		require.NoError(t, err)
+		require.Equal(t, int64(len(contents)), file.Size)
+		return string(contents)
+	}
+
+	document := stored(result.PDF)
+	require.True(t, strings.HasPrefix(document, "%PDF-"))
+	require.Contains(t, document, "1. DTI 55.0% exceeds the maximum of 50.0%")
+	require.Contains(t, document, "Your credit score: 610")
+
+	notice := stored(result.HTML)
+	require.Contains(t, notice, "June 3, 2024")
+	require.Contains(t, notice, "Jane &lt;Doe&gt;")
+	require.Contains(t, notice, "$250000.00")
+	require.Contains(t, notice, "<li>DTI 55.0% exceeds the maximum of 50.0%</li>")
+	require.Contains(t, notice, "Your credit score: 610")
+	require.Contains(t, notice, "free copy of your credit report")
+	require.Contains(t, notice, "Equal Credit Opportunity Act")
+}
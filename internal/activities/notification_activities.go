@@ -0,0 +1,62 @@
+package activities
+
+import (
+	"context"
+	"errors"
+
+	"loan-origination-system/internal/notify"
+
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/temporal"
+)
+
+// NotificationRejectedErrorType marks notifications that cannot be rendered
+// or addressed. Retrying cannot fix them.
+const NotificationRejectedErrorType = "NotificationRejected"
+
+type SendNotificationInput struct {
+	LoanApplicationID string         `json:"loan_application_id"`
+	Event             notify.Event   `json:"event"`
+	Channel           notify.Channel `json:"channel"`
+	// Recipient is the borrower's email address or phone number
+	Recipient string      `json:"recipient"`
+	Data      notify.Data `json:"data"`
+}
+
+type SendNotificationResult struct {
+	Subject   string `json:"subject,omitempty"`
+	MessageID string `json:"message_id"`
+}
+
+// NotificationActivities tells borrowers about their loan by email and SMS.
+type NotificationActivities struct {
+	Mailer notify.Mailer
+	SMS    notify.SMSProvider
+}
+
+// SendNotification renders the templated message for the event and sends it
+// on the input's channel.
+func (a *NotificationActivities) SendNotification(ctx context.Context, input SendNotificationInput) (*SendNotificationResult, error) {
+	msg, err := notify.Render(input.Event, input.Channel, input.Recipient, input.Data)
+	if err != nil {
+		return nil, temporal.NewNonRetryableApplicationError(err.Error(), NotificationRejectedErrorType, err)
+	}
+
+	var messageID string
+	switch input.Channel {
+	case notify.ChannelEmail:
+		messageID, err = a.Mailer.SendEmail(ctx, msg)
+	case notify.ChannelSMS:
+		messageID, err = a.SMS.SendSMS(ctx, msg)
+	}
+	if errors.Is(err, notify.ErrInvalidRecipient) {
+		return nil, temporal.NewNonRetryableApplicationError(err.Error(), NotificationRejectedErrorType, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	activity.GetLogger(ctx).Info("Notification sent", "loanApplicationID", input.LoanApplicationID,
+		"event", input.Event, "channel", input.Channel, "messageID", messageID)
+	return &SendNotificationResult{Subject: msg.Subject, MessageID: messageID}, nil
+}
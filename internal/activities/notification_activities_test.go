@@ -0,0 +1,85 @@
+package activities
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"loan-origination-system/internal/notify"
+
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/testsuite"
+)
+
+// recordingSender records the messages sent on both channels.
+type recordingSender struct {
+	sent []notify.Message
+	err  error
+}
+
+func (r *recordingSender) SendEmail(ctx context.Context, msg notify.Message) (string, error) {
+	return r.send(msg)
+}
+
+func (r *recordingSender) SendSMS(ctx context.Context, msg notify.Message) (string, error) {
+	return r.send(msg)
+}
+
+func (r *recordingSender) send(msg notify.Message) (string, error) {
+	if r.err != nil {
+		return "", r.err
+	}
+	r.sent = append(r.sent, msg)
+	return string(msg.Channel) + "-1", nil
+}
+
+func TestSendNotification(t *testing.T) {
+	sender := &recordingSender{}
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestActivityEnvironment()
+	env.RegisterActivity(&NotificationActivities{Mailer: sender, SMS: sender})
+
+	data := notify.Data{LoanApplicationID: "loan-1", BorrowerName: "Jane Doe", LoanAmount: 250000}
+
+	var notifications *NotificationActivities
+	value, err := env.ExecuteActivity(notifications.SendNotification, SendNotificationInput{
+		LoanApplicationID: "loan-1",
+		Event:             notify.EventFunded,
+		Channel:           notify.ChannelEmail,
+		Recipient:         "jane@example.com",
+		Data:              data,
+	})
+	require.NoError(t, err)
+	var result SendNotificationResult
+	require.NoError(t, value.Get(&result))
+	require.Equal(t, "Your loan has been funded", result.Subject)
+	require.Equal(t, "email-1", result.MessageID)
+
+	_, err = env.ExecuteActivity(notifications.SendNotification, SendNotificationInput{
+		LoanApplicationID: "loan-1",
+		Event:             notify.EventFunded,
+		Channel:           notify.ChannelSMS,
+		Recipient:         "555-0100",
+		Data:              data,
+	})
+	require.NoError(t, err)
+	require.Len(t, sender.sent, 2)
+	require.Equal(t, "555-0100", sender.sent[1].To)
+	require.Equal(t, "Your loan of $250000.00 has been funded.", sender.sent[1].Body)
+
+	sender.err = errors.New("connection refused")
+	_, err = env.ExecuteActivity(notifications.SendNotification, SendNotificationInput{
+		Event: notify.EventFunded, Channel: notify.ChannelEmail, Recipient: "jane@example.com", Data: data,
+	})
+	var appErr *temporal.ApplicationError
+	require.ErrorAs(t, err, &appErr)
+	require.False(t, appErr.NonRetryable())
+
+	_, err = env.ExecuteActivity(notifications.SendNotification, SendNotificationInput{
+		Event: "loan_exploded", Channel: notify.ChannelEmail, Recipient: "jane@example.com", Data: data,
+	})
+	require.ErrorAs(t, err, &appErr)
+	require.Equal(t, NotificationRejectedErrorType, appErr.Type())
+	require.True(t, appErr.NonRetryable())
+}
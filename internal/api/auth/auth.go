@@ -0,0 +1,186 @@
+package auth
+
+import (
+	"crypto/rand"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Role is one of the personas allowed to act on loans.
+type Role string
+
+const (
+	RoleLoanOfficer   Role = "loan-officer"
+	RoleCustomer      Role = "customer"
+	RoleLoanProcessor Role = "loan-processor"
+	RoleAppraiser     Role = "appraiser"
+	RoleUnderwriter   Role = "underwriter"
+	RoleFundManager   Role = "fund-manager"
+	// RoleAdmin operates the system, such as retrying failed funding
+	RoleAdmin Role = "admin"
+)
+
+// AllRoles lists every persona role.
+var AllRoles = []Role{
+	RoleLoanOfficer,
+	RoleCustomer,
+	RoleLoanProcessor,
+	RoleAppraiser,
+	RoleUnderwriter,
+	RoleFundManager,
+	RoleAdmin,
+}
+
+// StaffRoles lists every role except the customer.
+var StaffRoles = []Role{
+	RoleLoanOfficer,
+	RoleLoanProcessor,
+	RoleAppraiser,
+	RoleUnderwriter,
+	RoleFundManager,
+	RoleAdmin,
+}
+
+const principalKey = "auth.principal"
+
+var ErrInvalidCredentials = errors.New("invalid username or password")
+
+// Principal is the authenticated caller of an API request.
+type Principal struct {
+	Subject string `json:"sub"`
+	Roles   []Role `json:"roles"`
+}
+
+// OwnLoansOnly reports whether the principal may only reach the loans it
+// applied for, which holds for customers without a staff role.
+func (p Principal) OwnLoansOnly() bool {
+	return p.HasRole(RoleCustomer) && !p.HasRole(StaffRoles...)
+}
+
+// HasRole reports whether the principal holds any of the given roles.
+func (p Principal) HasRole(roles ...Role) bool {
+	for _, held := range p.Roles {
+		for _, role := range roles {
+			if held == role {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+type claims struct {
+	Roles []Role `json:"roles"`
+	jwt.RegisteredClaims
+}
+
+// Authenticator issues and verifies HS256 JWT bearer tokens for the users in
+// its directory.
+type Authenticator struct {
+	secret []byte
+	ttl    time.Duration
+	users  UserDirectory
+}
+
+// NewAuthenticator returns an Authenticator signing tokens with secret. An
+// empty secret is replaced by a random one, which invalidates tokens on
+// restart.
+func NewAuthenticator(secret []byte, ttl time.Duration, users UserDirectory) (*Authenticator, error) {
+	if len(secret) == 0 {
+		secret = make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, err
+		}
+	}
+	return &Authenticator{secret: secret, ttl: ttl, users: users}, nil
+}
+
+// Login checks the credentials against the user directory and issues a token.
+func (a *Authenticator) Login(username, password string) (string, Principal, error) {
+	principal, ok := a.users.Authenticate(username, password)
+	if !ok {
+		return "", Principal{}, ErrInvalidCredentials
+	}
+	token, err := a.IssueToken(principal)
+	return token, principal, err
+}
+
+// IssueToken signs a token for the principal.
+func (a *Authenticator) IssueToken(principal Principal) (string, error) {
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims{
+		Roles: principal.Roles,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   principal.Subject,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(a.ttl)),
+		},
+	})
+	return token.SignedString(a.secret)
+}
+
+// ParseToken verifies a token and returns its principal.
+func (a *Authenticator) ParseToken(tokenString string) (Principal, error) {
+	var c claims
+	_, err := jwt.ParseWithClaims(tokenString, &c, func(t *jwt.Token) (interface{}, error) {
+		return a.secret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil {
+		return Principal{}, err
+	}
+	return Principal{Subject: c.Subject, Roles: c.Roles}, nil
+}
+
+// Middleware authenticates the bearer token of every request and stores the
+// principal in the context. Requests without a valid token get 401.
+func (a *Authenticator) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tokenString, ok := bearerToken(c)
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+			return
+		}
+
+		principal, err := a.ParseToken(tokenString)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+			return
+		}
+
+		c.Set(principalKey, principal)
+		c.Next()
+	}
+}
+
+// RequireRoles allows the request only if the authenticated principal holds
+// one of the roles. It must run after Middleware.
+func RequireRoles(roles ...Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !PrincipalFrom(c).HasRole(roles...) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Not permitted for your role"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// PrincipalFrom returns the principal authenticated for the request.
+func PrincipalFrom(c *gin.Context) Principal {
+	principal, _ := c.Get(principalKey)
+	p, _ := principal.(Principal)
+	return p
+}
+
+func bearerToken(c *gin.Context) (string, bool) {
+	header := c.GetHeader("Authorization")
+	token, ok := strings.CutPrefix(header, "Bearer ")
+	if !ok || token == "" {
+		return "", false
+	}
+	return token, true
+}
@@ -0,0 +1,93 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRouter(t *testing.T, authenticator *Authenticator) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/appraisal", authenticator.Middleware(), RequireRoles(RoleAppraiser), func(c *gin.Context) {
+		c.String(http.StatusOK, PrincipalFrom(c).Subject)
+	})
+	return router
+}
+
+func serve(router *gin.Engine, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/appraisal", nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestMiddleware_EnforcesRoles(t *testing.T) {
+	authenticator, err := NewAuthenticator(nil, time.Hour, DemoUsers{Password: "demo"})
+	require.NoError(t, err)
+	router := newTestRouter(t, authenticator)
+
+	require.Equal(t, http.StatusUnauthorized, serve(router, "").Code)
+	require.Equal(t, http.StatusUnauthorized, serve(router, "not-a-token").Code)
+
+	underwriterToken, _, err := authenticator.Login("underwriter", "demo")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusForbidden, serve(router, underwriterToken).Code)
+
+	appraiserToken, _, err := authenticator.Login("appraiser", "demo")
+	require.NoError(t, err)
+	rec := serve(router, appraiserToken)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "appraiser-001", rec.Body.String())
+}
+
+func TestMiddleware_RejectsForeignAndExpiredTokens(t *testing.T) {
+	authenticator, err := NewAuthenticator([]byte("secret"), time.Hour, DemoUsers{Password: "demo"})
+	require.NoError(t, err)
+	router := newTestRouter(t, authenticator)
+
+	other, err := NewAuthenticator([]byte("other-secret"), time.Hour, DemoUsers{Password: "demo"})
+	require.NoError(t, err)
+	foreign, err := other.IssueToken(Principal{Subject: "appraiser", Roles: []Role{RoleAppraiser}})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusUnauthorized, serve(router, foreign).Code)
+
+	expiring, err := NewAuthenticator([]byte("secret"), -time.Minute, DemoUsers{Password: "demo"})
+	require.NoError(t, err)
+	expired, err := expiring.IssueToken(Principal{Subject: "appraiser", Roles: []Role{RoleAppraiser}})
+	require.NoError(t, err)
+	require.Equal(t, http.StatusUnauthorized, serve(router, expired).Code)
+}
+
+func TestDemoUsers_Authenticate(t *testing.T) {
+	users := DemoUsers{Password: "demo"}
+
+	principal, ok := users.Authenticate("fund-manager", "demo")
+	require.True(t, ok)
+	require.Equal(t, Principal{Subject: "fund-manager-001", Roles: []Role{RoleFundManager}}, principal)
+
+	_, ok = users.Authenticate("fund-manager", "wrong")
+	require.False(t, ok)
+	_, ok = users.Authenticate("root", "demo")
+	require.False(t, ok)
+
+	principal, ok = users.Authenticate("admin", "demo")
+	require.True(t, ok)
+	require.Equal(t, []Role{RoleAdmin}, principal.Roles)
+	require.False(t, principal.OwnLoansOnly())
+
+	first, ok := users.Authenticate("customer", "demo")
+	require.True(t, ok)
+	second, ok := users.Authenticate("customer-2", "demo")
+	require.True(t, ok)
+	require.NotEqual(t, first.Subject, second.Subject)
+	require.True(t, first.OwnLoansOnly())
+}
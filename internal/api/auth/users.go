@@ -0,0 +1,47 @@
+package auth
+
+import (
+	"crypto/subtle"
+)
+
+// UserDirectory authenticates users by username and password.
+type UserDirectory interface {
+	Authenticate(username, password string) (Principal, bool)
+}
+
+// DemoUsers is a directory of demo users, signed in with their persona's
+// name and a single shared password. Each user has its own subject, so the
+// loans and actions it is recorded against name the user rather than the
+// role. It stands in for a real identity provider.
+type DemoUsers struct {
+	Password string
+}
+
+// demoUsers lists the DemoUsers. The second customer shows that borrowers
+// only see their own loans.
+var demoUsers = []struct {
+	username string
+	subject  string
+	role     Role
+}{
+	{"loan-officer", "loan-officer-001", RoleLoanOfficer},
+	{"customer", "customer-001", RoleCustomer},
+	{"customer-2", "customer-002", RoleCustomer},
+	{"loan-processor", "loan-processor-001", RoleLoanProcessor},
+	{"appraiser", "appraiser-001", RoleAppraiser},
+	{"underwriter", "underwriter-001", RoleUnderwriter},
+	{"fund-manager", "fund-manager-001", RoleFundManager},
+	{"admin", "admin-001", RoleAdmin},
+}
+
+func (d DemoUsers) Authenticate(username, password string) (Principal, bool) {
+	if subtle.ConstantTimeCompare([]byte(password), []byte(d.Password)) != 1 {
+		return Principal{}, false
+	}
+	for _, user := range demoUsers {
+		if username == user.username {
+			return Principal{Subject: user.subject, Roles: []Role{user.role}}, true
+		}
+	}
+	return Principal{}, false
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"loan-origination-system/internal/audit"
+
+	"github.com/gin-gonic/gin"
+	enumspb "go.temporal.io/api/enums/v1"
+	historypb "go.temporal.io/api/history/v1"
+	"go.temporal.io/api/serviceerror"
+)
+
+// GetAuditTrail returns the loan's audit timeline read from its workflow
+// history, as JSON or as CSV with ?format=csv
+func (h *LoanHandler) GetAuditTrail(c *gin.Context) {
+	loanID := c.Param("id")
+	workflowID := "loan-origination-" + loanID
+
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "csv" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be json or csv"})
+		return
+	}
+
+	var events []*historypb.HistoryEvent
+	iter := h.temporalClient.GetWorkflowHistory(c.Request.Context(), workflowID, "", false, enumspb.HISTORY_EVENT_FILTER_TYPE_ALL_EVENT)
+	for iter.HasNext() {
+		event, err := iter.Next()
+		if err != nil {
+			var notFound *serviceerror.NotFound
+			if errors.As(err, &notFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Loan application not found"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read workflow history"})
+			return
+		}
+		events = append(events, event)
+	}
+
+	timeline := audit.BuildTimeline(events, h.dataConverter)
+
+	if format == "csv" {
+		c.Header("Content-Type", "text/csv")
+		c.Header("Content-Disposition", `attachment; filename="loan-`+loanID+`-audit.csv"`)
+		c.Status(http.StatusOK)
+		if err := audit.WriteCSV(c.Writer, timeline); err != nil {
+			c.Error(err)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"loan_id":     loanID,
+		"workflow_id": workflowID,
+		"events":      timeline,
+	})
+}
@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"loan-origination-system/internal/api/auth"
+	"loan-origination-system/internal/events"
+	"loan-origination-system/internal/projection"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// heartbeatInterval keeps idle streams open through proxies.
+	heartbeatInterval = 15 * time.Second
+	replayBatchSize   = 100
+)
+
+type EventHandler struct {
+	broker *events.Broker
+	store  *projection.Store
+}
+
+func NewEventHandler(broker *events.Broker, store *projection.Store) *EventHandler {
+	return &EventHandler{
+		broker: broker,
+		store:  store,
+	}
+}
+
+// StreamLoanEvents streams changes to a single loan as Server-Sent Events,
+// starting with its current state
+func (h *EventHandler) StreamLoanEvents(c *gin.Context) {
+	h.stream(c, c.Param("id"), "")
+}
+
+// StreamEvents streams changes to every loan as Server-Sent Events. Customers
+// only receive changes to the loans they applied for.
+func (h *EventHandler) StreamEvents(c *gin.Context) {
+	owner := ""
+	if principal := auth.PrincipalFrom(c); principal.OwnLoansOnly() {
+		owner = principal.Subject
+	}
+	h.stream(c, "", owner)
+}
+
+// stream writes each projected change as a "loan" event whose ID is the change
+// sequence, so reconnecting clients resume from Last-Event-ID. With an owner,
+// changes to loans created by anyone else are skipped.
+func (h *EventHandler) stream(c *gin.Context, loanID, owner string) {
+	// Subscribe before replaying so no change falls between the two
+	sub := h.broker.Subscribe(loanID)
+	defer h.broker.Unsubscribe(sub)
+
+	replayFrom := int64(-1)
+	if lastEventID := c.GetHeader("Last-Event-ID"); lastEventID != "" {
+		sequence, err := strconv.ParseInt(lastEventID, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Last-Event-ID"})
+			return
+		}
+		replayFrom = sequence
+	} else if loanID != "" {
+		replayFrom = 0
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	var sent int64
+	for replayFrom >= 0 {
+		changes, err := h.store.ChangesSince(c.Request.Context(), replayFrom, loanID, replayBatchSize)
+		if err != nil {
+			return
+		}
+		for _, change := range changes {
+			sent = change.Sequence
+			if !ownedBy(change, owner) {
+				continue
+			}
+			if err := writeLoanEvent(c.Writer, change); err != nil {
+				return
+			}
+		}
+		if len(changes) < replayBatchSize {
+			break
+		}
+		replayFrom = sent
+	}
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case change, ok := <-sub.Events():
+			if !ok {
+				return
+			}
+			if change.Sequence <= sent {
+				continue
+			}
+			sent = change.Sequence
+			if !ownedBy(change, owner) {
+				continue
+			}
+			if err := writeLoanEvent(c.Writer, change); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		case <-heartbeat.C:
+			if _, err := io.WriteString(c.Writer, ": keepalive\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		}
+	}
+}
+
+func ownedBy(change projection.LoanChange, owner string) bool {
+	return owner == "" || change.State.LoanApplication.CreatedBy == owner
+}
+
+func writeLoanEvent(w io.Writer, change projection.LoanChange) error {
+	data, err := json.Marshal(change.State)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: loan\ndata: %s\n\n", change.Sequence, data)
+	return err
+}
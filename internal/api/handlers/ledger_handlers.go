@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"net/http"
+
+	"loan-origination-system/internal/ledger"
+
+	"github.com/gin-gonic/gin"
+)
+
+type LedgerHandler struct {
+	ledger *ledger.Ledger
+}
+
+func NewLedgerHandler(ledger *ledger.Ledger) *LedgerHandler {
+	return &LedgerHandler{ledger: ledger}
+}
+
+// GetLedger returns the journal entries, only those of one loan when
+// loan_id is set, and the balance of every account. Amounts are in cents.
+func (h *LedgerHandler) GetLedger(c *gin.Context) {
+	entries, err := h.ledger.Entries(c.Request.Context(), c.Query("loan_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load ledger"})
+		return
+	}
+	balances, err := h.ledger.Balances(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load ledger"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"entries":  entries,
+		"balances": balances,
+	})
+}
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"loan-origination-system/internal/api/auth"
+	"loan-origination-system/internal/projection"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireLoanOwner lets customers reach only the loans they applied for,
+// those created under their subject. Staff pass through. It runs after the
+// role checks on routes with a loan :id, and answers 404 for other loans so
+// customers cannot probe which loan IDs exist.
+func (h *LoanHandler) RequireLoanOwner(c *gin.Context) {
+	principal := auth.PrincipalFrom(c)
+	if !principal.OwnLoansOnly() {
+		c.Next()
+		return
+	}
+
+	owner, err := h.loanOwner(c.Request.Context(), c.Param("id"))
+	if err != nil || owner != principal.Subject {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "Loan application not found"})
+		return
+	}
+	c.Next()
+}
+
+// loanOwner returns the subject the loan was created by, querying the
+// workflow for loans not projected yet.
+func (h *LoanHandler) loanOwner(ctx context.Context, loanID string) (string, error) {
+	loanData, err := h.store.GetLoan(ctx, loanID)
+	if err == nil {
+		return loanData.LoanApplication.CreatedBy, nil
+	}
+	if !errors.Is(err, projection.ErrNotFound) {
+		return "", err
+	}
+
+	loanData, err = h.queryLoan(ctx, loanID)
+	if err != nil {
+		return "", err
+	}
+	return loanData.LoanApplication.CreatedBy, nil
+}
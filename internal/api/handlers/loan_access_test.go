@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"loan-origination-system/internal/api/auth"
+	"loan-origination-system/internal/projection"
+	"loan-origination-system/internal/workflows"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequireLoanOwner_CustomersOnlyReachTheirLoans(t *testing.T) {
+	store, err := projection.Open(filepath.Join(t.TempDir(), "loans.db"))
+	require.NoError(t, err)
+	for _, loan := range []struct{ id, createdBy string }{
+		{"loan-1", "customer-001"},
+		{"loan-2", "customer-002"},
+	} {
+		state := workflows.LoanOriginationState{
+			LoanApplication: workflows.LoanApplication{ID: loan.id, CreatedBy: loan.createdBy, CreatedAt: time.Now()},
+			Status:          "processing",
+		}
+		require.NoError(t, store.SaveLoan(context.Background(), state))
+	}
+
+	authenticator, err := auth.NewAuthenticator(nil, time.Hour, auth.DemoUsers{Password: "demo"})
+	require.NoError(t, err)
+	handler := NewLoanHandler(nil, nil, "", store, nil, nil, "")
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	api := router.Group("/", authenticator.Middleware())
+	api.GET("/loans", handler.GetLoanApplications)
+	api.GET("/loans/:id", handler.RequireLoanOwner, handler.GetLoanApplication)
+
+	get := func(username, target string) *httptest.ResponseRecorder {
+		token, _, err := authenticator.Login(username, "demo")
+		require.NoError(t, err)
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	require.Equal(t, http.StatusOK, get("customer", "/loans/loan-1").Code)
+	require.Equal(t, http.StatusNotFound, get("customer", "/loans/loan-2").Code)
+	require.Equal(t, http.StatusOK, get("customer-2", "/loans/loan-2").Code)
+	require.Equal(t, http.StatusOK, get("underwriter", "/loans/loan-2").Code)
+
+	var page struct {
+		Loans []struct {
+			ID string `json:"id"`
+		} `json:"loans"`
+	}
+	rec := get("customer", "/loans?created_by=customer-002")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &page))
+	require.Len(t, page.Loans, 1)
+	require.Equal(t, "loan-1", page.Loans[0].ID)
+
+	rec = get("loan-processor", "/loans")
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &page))
+	require.Len(t, page.Loans, 2)
+}
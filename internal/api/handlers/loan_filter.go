@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"loan-origination-system/internal/projection"
+
+	"github.com/gin-gonic/gin"
+)
+
+// parseLoanFilter reads the GET /loans query parameters:
+//
+//	status          comma-separated list of statuses
+//	next_step       next step prefix, e.g. "Waiting for appraisal"
+//	created_by      creator of the application
+//	product         comma-separated list of loan products
+//	min_amount      minimum loan amount (inclusive)
+//	max_amount      maximum loan amount (inclusive)
+//	created_after   RFC 3339 timestamp or YYYY-MM-DD date (inclusive)
+//	created_before  RFC 3339 timestamp or YYYY-MM-DD date (exclusive)
+//	sort            created_at, updated_at or loan_amount; prefix "-" for descending
+//	page_size       results per page
+//	page_token      next_page_token from the previous page
+func parseLoanFilter(c *gin.Context) (projection.LoanFilter, error) {
+	filter := projection.LoanFilter{
+		NextStep:  c.Query("next_step"),
+		CreatedBy: c.Query("created_by"),
+		Sort:      c.Query("sort"),
+		PageToken: c.Query("page_token"),
+	}
+
+	if status := c.Query("status"); status != "" {
+		for _, s := range strings.Split(status, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				filter.Statuses = append(filter.Statuses, s)
+			}
+		}
+	}
+
+	if product := c.Query("product"); product != "" {
+		for _, p := range strings.Split(product, ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				filter.Products = append(filter.Products, p)
+			}
+		}
+	}
+
+	var err error
+	if filter.MinAmount, err = parseAmount(c, "min_amount"); err != nil {
+		return filter, err
+	}
+	if filter.MaxAmount, err = parseAmount(c, "max_amount"); err != nil {
+		return filter, err
+	}
+	if filter.CreatedAfter, err = parseDate(c, "created_after"); err != nil {
+		return filter, err
+	}
+	if filter.CreatedBefore, err = parseDate(c, "created_before"); err != nil {
+		return filter, err
+	}
+
+	if value := c.Query("page_size"); value != "" {
+		filter.PageSize, err = strconv.Atoi(value)
+		if err != nil || filter.PageSize <= 0 {
+			return filter, fmt.Errorf("page_size must be a positive integer")
+		}
+	}
+
+	return filter, nil
+}
+
+func parseAmount(c *gin.Context, name string) (*float64, error) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, nil
+	}
+	amount, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be a number", name)
+	}
+	return &amount, nil
+}
+
+func parseDate(c *gin.Context, name string) (*time.Time, error) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return &t, nil
+		}
+	}
+	return nil, fmt.Errorf("%s must be an RFC 3339 timestamp or YYYY-MM-DD date", name)
+}
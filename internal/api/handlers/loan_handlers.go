@@ -1,27 +1,52 @@
 package handlers
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"mime"
 	"net/http"
-	"strings"
+	"path"
+	"path/filepath"
 	"time"
 
+	"loan-origination-system/internal/api/auth"
+	"loan-origination-system/internal/policy"
+	"loan-origination-system/internal/projection"
+	"loan-origination-system/internal/storage"
 	"loan-origination-system/internal/workflows"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
-	"go.temporal.io/api/workflowservice/v1"
+	"go.temporal.io/api/serviceerror"
 	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/converter"
+	"go.temporal.io/sdk/temporal"
 )
 
+// maxDocumentSize is the largest document file accepted for upload.
+const maxDocumentSize = 25 << 20
+
 type LoanHandler struct {
 	temporalClient client.Client
-	activeLoans    map[string]workflows.LoanApplication // Simple in-memory registry
+	dataConverter  converter.DataConverter
+	taskQueue      string
+	store          *projection.Store
+	documents      storage.BlobStore
+	policies       *policy.Set
+	// searchKey keys the borrower email hash indexed for search
+	searchKey string
 }
 
-func NewLoanHandler(temporalClient client.Client) *LoanHandler {
+func NewLoanHandler(temporalClient client.Client, dataConverter converter.DataConverter, taskQueue string, store *projection.Store, documents storage.BlobStore, policies *policy.Set, searchKey string) *LoanHandler {
 	return &LoanHandler{
 		temporalClient: temporalClient,
-		activeLoans:    make(map[string]workflows.LoanApplication),
+		dataConverter:  dataConverter,
+		taskQueue:      taskQueue,
+		store:          store,
+		documents:      documents,
+		policies:       policies,
+		searchKey:      searchKey,
 	}
 }
 
@@ -33,7 +58,12 @@ func (h *LoanHandler) CreateLoanApplication(c *gin.Context) {
 		BorrowerPhone string  `json:"borrower_phone" binding:"required"`
 		LoanAmount    float64 `json:"loan_amount" binding:"required"`
 		LoanPurpose   string  `json:"loan_purpose" binding:"required"`
-		CreatedBy     string  `json:"created_by" binding:"required"`
+		// MonthlyIncome is optional; without it DTI is not computed
+		MonthlyIncome float64 `json:"monthly_income" binding:"gte=0"`
+		// Product defaults to the policy's default product
+		Product         policy.LoanProduct `json:"product"`
+		PropertyAddress string             `json:"property_address"`
+		Vehicle         *workflows.Vehicle `json:"vehicle"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -41,6 +71,22 @@ func (h *LoanHandler) CreateLoanApplication(c *gin.Context) {
 		return
 	}
 
+	// The workflow keeps the policy in force now, whatever later versions say
+	rules, err := h.policies.Snapshot(req.Product)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	product := rules.Product.Name
+	switch {
+	case product.Collateral() == policy.CollateralVehicle && req.Vehicle == nil:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "vehicle is required for auto loans"})
+		return
+	case product.RequiresLienCheck() && req.PropertyAddress == "":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "property_address is required for " + string(product) + " loans"})
+		return
+	}
+
 	// Create loan application data structure
 	loanID := uuid.New().String()
 	now := time.Now()
@@ -52,25 +98,35 @@ func (h *LoanHandler) CreateLoanApplication(c *gin.Context) {
 		BorrowerPhone: req.BorrowerPhone,
 		LoanAmount:    req.LoanAmount,
 		LoanPurpose:   req.LoanPurpose,
+		MonthlyIncome: req.MonthlyIncome,
 		Status:        "pending",
-		CreatedBy:     req.CreatedBy,
+		CreatedBy:     auth.PrincipalFrom(c).Subject,
 		CreatedAt:     now,
 		UpdatedAt:     now,
 		WorkflowID:    "loan-origination-" + loanID,
+
+		Product:         product,
+		PropertyAddress: req.PropertyAddress,
+		Vehicle:         req.Vehicle,
+	}
+	if h.searchKey != "" {
+		loanApp.BorrowerEmailHash = workflows.HashBorrowerEmail(h.searchKey, req.BorrowerEmail)
 	}
 
 	// Start Temporal workflow
 	workflowOptions := client.StartWorkflowOptions{
-		ID:        loanApp.WorkflowID,
-		TaskQueue: "loan-origination-task-queue",
+		ID:               loanApp.WorkflowID,
+		TaskQueue:        h.taskQueue,
+		SearchAttributes: workflows.StartSearchAttributes(loanApp),
 	}
 
-	_, err := h.temporalClient.ExecuteWorkflow(
+	_, err = h.temporalClient.ExecuteWorkflow(
 		c.Request.Context(),
 		workflowOptions,
 		workflows.LoanOriginationWorkflow,
 		workflows.LoanOriginationWorkflowInput{
 			LoanApplication: loanApp,
+			Policy:          &rules,
 		},
 	)
 
@@ -79,146 +135,227 @@ func (h *LoanHandler) CreateLoanApplication(c *gin.Context) {
 		return
 	}
 
-	// Store in active loans registry
-	h.activeLoans[loanID] = loanApp
-
 	c.JSON(http.StatusCreated, loanApp)
 }
 
-// GetLoanApplications returns all loan applications by querying active workflows
+// GetLoanApplications returns a filtered, sorted page of loan applications
+// from the projection. Customers only see the loans they applied for.
 func (h *LoanHandler) GetLoanApplications(c *gin.Context) {
-	h.activeLoans = make(map[string]workflows.LoanApplication)
+	filter, err := parseLoanFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if principal := auth.PrincipalFrom(c); principal.OwnLoansOnly() {
+		filter.CreatedBy = principal.Subject
+	}
 
-	res, err := h.temporalClient.ListWorkflow(c.Request.Context(), &workflowservice.ListWorkflowExecutionsRequest{})
-	if err == nil {
-		for _, wf := range res.Executions {
-			loanId, _ := strings.CutPrefix(wf.Execution.WorkflowId, "loan-origination-")
-			h.activeLoans[loanId] = workflows.LoanApplication{}
-		}
+	page, err := h.store.ListLoans(c.Request.Context(), filter)
+	if errors.Is(err, projection.ErrInvalidFilter) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load loan applications"})
+		return
 	}
 
-	var loanResponses []map[string]interface{}
-
-	// Query each active workflow for its current state
-	for loanID, _ := range h.activeLoans {
-		workflowID := "loan-origination-" + loanID
-
-		// Query the workflow for current state
-		resp, _ := h.temporalClient.QueryWorkflow(
-			c.Request.Context(),
-			workflowID,
-			"",
-			"getLoanApplication",
-		)
-
-		var loanData workflows.LoanOriginationState
-		if err := resp.Get(&loanData); err == nil {
-			// Flatten the response to match frontend expectations
-			flatLoan := map[string]interface{}{
-				"id":                    loanData.LoanApplication.ID,
-				"borrower_name":         loanData.LoanApplication.BorrowerName,
-				"borrower_email":        loanData.LoanApplication.BorrowerEmail,
-				"borrower_phone":        loanData.LoanApplication.BorrowerPhone,
-				"loan_amount":           loanData.LoanApplication.LoanAmount,
-				"loan_purpose":          loanData.LoanApplication.LoanPurpose,
-				"status":                loanData.LoanApplication.Status,
-				"next_step":             loanData.NextStep,
-				"created_by":            loanData.LoanApplication.CreatedBy,
-				"created_at":            loanData.LoanApplication.CreatedAt,
-				"updated_at":            loanData.LoanApplication.UpdatedAt,
-				"workflow_id":           loanData.LoanApplication.WorkflowID,
-				"documents":             loanData.Documents,
-				"appraisal":             loanData.Appraisal,
-				"credit_score":          loanData.CreditScore,
-				"underwriting_decision": loanData.UnderwritingDecision,
-			}
-			loanResponses = append(loanResponses, flatLoan)
+	loanResponses := make([]map[string]interface{}, 0, len(page.Loans))
+	for _, loanData := range page.Loans {
+		// Flatten the response to match frontend expectations
+		flatLoan := map[string]interface{}{
+			"id":                    loanData.LoanApplication.ID,
+			"borrower_name":         loanData.LoanApplication.BorrowerName,
+			"borrower_email":        loanData.LoanApplication.BorrowerEmail,
+			"borrower_phone":        loanData.LoanApplication.BorrowerPhone,
+			"loan_amount":           loanData.LoanApplication.LoanAmount,
+			"loan_purpose":          loanData.LoanApplication.LoanPurpose,
+			"product":               loanData.LoanApplication.Product,
+			"property_address":      loanData.LoanApplication.PropertyAddress,
+			"vehicle":               loanData.LoanApplication.Vehicle,
+			"monthly_income":        loanData.LoanApplication.MonthlyIncome,
+			"status":                loanData.LoanApplication.Status,
+			"next_step":             loanData.NextStep,
+			"created_by":            loanData.LoanApplication.CreatedBy,
+			"created_at":            loanData.LoanApplication.CreatedAt,
+			"updated_at":            loanData.LoanApplication.UpdatedAt,
+			"workflow_id":           loanData.LoanApplication.WorkflowID,
+			"documents":             loanData.Documents,
+			"document_checklist":    loanData.DocumentChecklist,
+			"appraisal":             loanData.Appraisal,
+			"vehicle_valuation":     loanData.VehicleValuation,
+			"lien_check":            loanData.LienCheck,
+			"credit_score":          loanData.CreditScore,
+			"recommendation":        loanData.Recommendation,
+			"policy":                loanData.Policy,
+			"underwriting_decision": loanData.UnderwritingDecision,
+			"closure":               loanData.Closure,
+			"terms":                 loanData.Terms,
+			"counter_offer":         loanData.CounterOffer,
+			"loan_agreement":        loanData.LoanAgreement,
+			"signature":             loanData.Signature,
+			"funding":               loanData.Funding,
+			"funding_failures":      loanData.FundingFailures,
+			"conditions":            loanData.Conditions,
+			"adverse_action_notice": loanData.AdverseActionNotice,
+			"notifications":         loanData.Notifications,
 		}
+		loanResponses = append(loanResponses, flatLoan)
 	}
 
-	c.JSON(http.StatusOK, loanResponses)
+	c.JSON(http.StatusOK, gin.H{
+		"loans":           loanResponses,
+		"next_page_token": page.NextPageToken,
+	})
 }
 
-// GetLoanApplication returns a specific loan application by querying the workflow
+// GetLoanApplication returns a specific loan application from the projection,
+// falling back to querying the workflow for loans not projected yet
 func (h *LoanHandler) GetLoanApplication(c *gin.Context) {
 	loanID := c.Param("id")
-	workflowID := "loan-origination-" + loanID
-
-	// Query the workflow for current state
-	resp, err := h.temporalClient.QueryWorkflow(
-		c.Request.Context(),
-		workflowID,
-		"",
-		"getLoanApplication",
-	)
 
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Loan application not found"})
+	loanData, err := h.store.GetLoan(c.Request.Context(), loanID)
+	if err == nil {
+		c.JSON(http.StatusOK, loanData)
+		return
+	}
+	if !errors.Is(err, projection.ErrNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load loan application"})
 		return
 	}
 
-	var loanData workflows.LoanOriginationState
-	if err := resp.Get(&loanData); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to parse loan data"})
+	loanData, err = h.queryLoan(c.Request.Context(), loanID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Loan application not found"})
 		return
 	}
 
 	c.JSON(http.StatusOK, loanData)
 }
 
-// UploadDocument handles document upload and sends signal to workflow
+// UploadDocument stores a multipart document upload and records it in the
+// workflow
 func (h *LoanHandler) UploadDocument(c *gin.Context) {
-	loanID := c.Param("id")
+	documentType := policy.DocumentType(c.PostForm("document_type"))
+	if documentType == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "document_type is required"})
+		return
+	}
+	if !documentType.Valid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unknown document_type " + string(documentType)})
+		return
+	}
 
-	var req struct {
-		DocumentType string `json:"document_type" binding:"required"`
-		FileName     string `json:"file_name" binding:"required"`
-		FilePath     string `json:"file_path" binding:"required"`
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
+		return
+	}
+	if fileHeader.Size > maxDocumentSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Document exceeds the 25 MB limit"})
+		return
 	}
 
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read uploaded file"})
 		return
 	}
+	defer file.Close()
+
+	contentType := fileHeader.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
 
-	// Generate document ID
 	documentID := uuid.New().String()
+	key := path.Join("loans", c.Param("id"), "documents", documentID)
 
-	// Send signal to workflow (workflow will store the document data)
-	workflowID := "loan-origination-" + loanID
-	err := h.temporalClient.SignalWorkflow(
-		c.Request.Context(),
-		workflowID,
-		"",
-		"document-uploaded",
-		workflows.DocumentUploadedSignal{
-			DocumentID:   documentID,
-			DocumentType: req.DocumentType,
-		},
-	)
+	object, err := storage.PutObject(c.Request.Context(), h.documents, key, file, fileHeader.Size, contentType)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store document"})
+		return
+	}
 
+	// Record the document through a workflow update so the response reflects
+	// the workflow's resulting state
+	ok := h.updateLoan(c, http.StatusCreated, "uploadDocument", workflows.DocumentUploadedSignal{
+		DocumentID:   documentID,
+		DocumentType: documentType,
+		FileName:     filepath.Base(fileHeader.Filename),
+		FilePath:     object.Key,
+		ContentType:  object.ContentType,
+		Size:         object.Size,
+		SHA256:       object.SHA256,
+		UploadedBy:   auth.PrincipalFrom(c).Subject,
+	})
+	if !ok {
+		// The workflow did not accept the document, so drop the stored file
+		h.documents.Delete(context.Background(), object.Key)
+	}
+}
+
+// DownloadDocument streams the exact file uploaded for a document
+func (h *LoanHandler) DownloadDocument(c *gin.Context) {
+	doc, err := h.findDocument(c.Request.Context(), c.Param("id"), c.Param("documentId"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send workflow signal"})
+		c.JSON(http.StatusNotFound, gin.H{"error": "Document not found"})
 		return
 	}
 
-	// Return document info
-	document := workflows.Document{
-		ID:                 documentID,
-		DocumentType:       req.DocumentType,
-		FileName:           req.FileName,
-		FilePath:           req.FilePath,
-		VerificationStatus: "pending",
-		UploadedAt:         time.Now(),
+	h.serveFile(c, doc.FilePath, doc.FileName, doc.ContentType, doc.Size, doc.SHA256)
+}
+
+// GetLoanAgreement downloads the loan's current agreement as PDF, or as HTML
+// with ?format=html
+func (h *LoanHandler) GetLoanAgreement(c *gin.Context) {
+	format := c.DefaultQuery("format", "pdf")
+	if format != "pdf" && format != "html" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be pdf or html"})
+		return
 	}
 
-	c.JSON(http.StatusCreated, document)
+	agreement, err := h.findAgreement(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Loan agreement not found"})
+		return
+	}
+
+	file := agreement.PDF
+	if format == "html" {
+		file = agreement.HTML
+	}
+	fileName := fmt.Sprintf("loan-agreement-%s-v%d.%s", c.Param("id"), agreement.Version, format)
+	h.serveFile(c, file.FilePath, fileName, file.ContentType, file.Size, file.SHA256)
+}
+
+// serveFile streams a stored file as an attachment. Its hash doubles as the
+// ETag, since stored files never change under the same hash.
+func (h *LoanHandler) serveFile(c *gin.Context, filePath, fileName, contentType string, size int64, sha256 string) {
+	reader, err := h.documents.Get(c.Request.Context(), filePath)
+	if errors.Is(err, storage.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Document file not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read document"})
+		return
+	}
+	defer reader.Close()
+
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	c.DataFromReader(http.StatusOK, size, contentType, reader, map[string]string{
+		"Content-Disposition": mime.FormatMediaType("attachment", map[string]string{"filename": fileName}),
+		"ETag":                `"` + sha256 + `"`,
+		"X-Checksum-SHA256":   sha256,
+	})
 }
 
 // VerifyDocument handles third-party document verification
 func (h *LoanHandler) VerifyDocument(c *gin.Context) {
-	loanID := c.Param("id")
-
 	var req struct {
 		DocumentID          string                 `json:"document_id" binding:"required"`
 		VerificationStatus  string                 `json:"verification_status" binding:"required"`
@@ -230,36 +367,19 @@ func (h *LoanHandler) VerifyDocument(c *gin.Context) {
 		return
 	}
 
-	// Send signal to workflow (workflow will update document verification status)
-	workflowID := "loan-origination-" + loanID
-	err := h.temporalClient.SignalWorkflow(
-		c.Request.Context(),
-		workflowID,
-		"",
-		"document-verified",
-		workflows.DocumentVerificationSignal{
-			DocumentID:          req.DocumentID,
-			VerificationStatus:  req.VerificationStatus,
-			VerificationDetails: req.VerificationDetails,
-		},
-	)
-
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send workflow signal"})
-		return
-	}
-
-	c.JSON(http.StatusOK, gin.H{"message": "Document verification processed"})
+	h.updateLoan(c, http.StatusOK, "verifyDocument", workflows.DocumentVerificationSignal{
+		DocumentID:          req.DocumentID,
+		VerificationStatus:  req.VerificationStatus,
+		VerificationDetails: req.VerificationDetails,
+		VerifiedBy:          auth.PrincipalFrom(c).Subject,
+	})
 }
 
 // CompleteAppraisal handles appraisal completion
 func (h *LoanHandler) CompleteAppraisal(c *gin.Context) {
-	loanID := c.Param("id")
-
 	var req struct {
 		PropertyValue  float64 `json:"property_value" binding:"required"`
 		AppraisalNotes string  `json:"appraisal_notes"`
-		AppraiserID    string  `json:"appraiser_id" binding:"required"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -267,48 +387,76 @@ func (h *LoanHandler) CompleteAppraisal(c *gin.Context) {
 		return
 	}
 
-	// Send signal to workflow (workflow will store the appraisal data)
-	workflowID := "loan-origination-" + loanID
-	err := h.temporalClient.SignalWorkflow(
-		c.Request.Context(),
-		workflowID,
-		"",
-		"appraisal-completed",
-		workflows.AppraisalCompletedSignal{
-			PropertyValue:  req.PropertyValue,
-			AppraisalNotes: req.AppraisalNotes,
-			AppraiserID:    req.AppraiserID,
-		},
-	)
+	h.updateLoan(c, http.StatusOK, "completeAppraisal", workflows.AppraisalCompletedSignal{
+		PropertyValue:  req.PropertyValue,
+		AppraisalNotes: req.AppraisalNotes,
+		AppraiserID:    auth.PrincipalFrom(c).Subject,
+	})
+}
 
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send workflow signal"})
-		return
+// MakeUnderwritingDecision handles underwriting decisions
+func (h *LoanHandler) MakeUnderwritingDecision(c *gin.Context) {
+	var req struct {
+		Decision string `json:"decision" binding:"required"`
+		Comments string `json:"comments"`
+		// RequestedDocuments names the documents a needs_more_info decision
+		// asks the borrower for
+		RequestedDocuments []workflows.DocumentRequest `json:"requested_documents"`
+		// CounterOffer holds the terms a counter_offer decision offers
+		// instead
+		CounterOffer *workflows.LoanTerms `json:"counter_offer"`
+		// Conditions make an approval conditional on clearing them
+		Conditions []workflows.ConditionRequest `json:"conditions"`
 	}
 
-	// Return appraisal info
-	now := time.Now()
-	appraisal := workflows.Appraisal{
-		ID:             "appraisal-" + loanID,
-		PropertyValue:  req.PropertyValue,
-		AppraisalNotes: req.AppraisalNotes,
-		AppraiserID:    req.AppraiserID,
-		Status:         "completed",
-		CompletedAt:    &now,
-		CreatedAt:      now,
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
-	c.JSON(http.StatusOK, appraisal)
+	h.updateLoan(c, http.StatusOK, "makeUnderwritingDecision", workflows.UnderwritingDecisionSignal{
+		Decision:           req.Decision,
+		Comments:           req.Comments,
+		UnderwriterID:      auth.PrincipalFrom(c).Subject,
+		RequestedDocuments: req.RequestedDocuments,
+		CounterOffer:       req.CounterOffer,
+		Conditions:         req.Conditions,
+	})
 }
 
-// MakeUnderwritingDecision handles underwriting decisions
-func (h *LoanHandler) MakeUnderwritingDecision(c *gin.Context) {
-	loanID := c.Param("id")
+// ClearCondition marks a condition of an approval satisfied or waived. Only
+// underwriters can waive a condition.
+func (h *LoanHandler) ClearCondition(c *gin.Context) {
+	var req struct {
+		Status   string `json:"status" binding:"required"`
+		Comments string `json:"comments"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	principal := auth.PrincipalFrom(c)
+	if req.Status == "waived" && !principal.HasRole(auth.RoleUnderwriter) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only underwriters can waive a condition"})
+		return
+	}
 
+	h.updateLoan(c, http.StatusOK, "clearCondition", workflows.ClearConditionSignal{
+		ConditionID: c.Param("conditionId"),
+		Status:      req.Status,
+		Comments:    req.Comments,
+		ClearedBy:   principal.Subject,
+	})
+}
+
+// RespondToCounterOffer records the borrower accepting or declining a
+// counter-offer
+func (h *LoanHandler) RespondToCounterOffer(c *gin.Context) {
 	var req struct {
-		Decision      string `json:"decision" binding:"required"`
-		Comments      string `json:"comments"`
-		UnderwriterID string `json:"underwriter_id" binding:"required"`
+		Accepted *bool  `json:"accepted" binding:"required"`
+		Comments string `json:"comments"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -316,43 +464,72 @@ func (h *LoanHandler) MakeUnderwritingDecision(c *gin.Context) {
 		return
 	}
 
-	// Send signal to workflow (workflow will store the underwriting decision)
-	workflowID := "loan-origination-" + loanID
-	err := h.temporalClient.SignalWorkflow(
-		c.Request.Context(),
-		workflowID,
-		"",
-		"underwriting-decision",
-		workflows.UnderwritingDecisionSignal{
-			Decision:      req.Decision,
-			Comments:      req.Comments,
-			UnderwriterID: req.UnderwriterID,
-		},
-	)
+	h.updateLoan(c, http.StatusOK, "respondToCounterOffer", workflows.CounterOfferResponseSignal{
+		Accepted:    *req.Accepted,
+		Comments:    req.Comments,
+		RespondedBy: auth.PrincipalFrom(c).Subject,
+	})
+}
 
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send workflow signal"})
+// SignAgreement records the borrower signing the current loan agreement, or
+// declining to, from the signing page. The client address and user agent
+// are taken from the request.
+func (h *LoanHandler) SignAgreement(c *gin.Context) {
+	var req struct {
+		Signed         *bool  `json:"signed" binding:"required"`
+		SignerName     string `json:"signer_name"`
+		DocumentSHA256 string `json:"document_sha256"`
+		Reason         string `json:"reason"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Return decision info
-	decision := workflows.UnderwritingDecision{
-		ID:            "decision-" + loanID,
-		Decision:      req.Decision,
-		Comments:      req.Comments,
-		UnderwriterID: req.UnderwriterID,
-		DecisionDate:  time.Now(),
+	h.updateLoan(c, http.StatusOK, "signAgreement", workflows.AgreementSignatureSignal{
+		Signed:         *req.Signed,
+		SignerName:     req.SignerName,
+		DocumentSHA256: req.DocumentSHA256,
+		Reason:         req.Reason,
+		SignedBy:       auth.PrincipalFrom(c).Subject,
+		IPAddress:      c.ClientIP(),
+		UserAgent:      c.Request.UserAgent(),
+	})
+}
+
+// WithdrawApplication records the borrower backing out of an application
+func (h *LoanHandler) WithdrawApplication(c *gin.Context) {
+	h.closeApplication(c, "withdrawn")
+}
+
+// CancelApplication records the lender stopping an application
+func (h *LoanHandler) CancelApplication(c *gin.Context) {
+	h.closeApplication(c, "cancelled")
+}
+
+func (h *LoanHandler) closeApplication(c *gin.Context, status string) {
+	var req struct {
+		ReasonCode workflows.ClosureReason `json:"reason_code" binding:"required"`
+		Comments   string                  `json:"comments"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
-	c.JSON(http.StatusOK, decision)
+	h.updateLoan(c, http.StatusOK, "closeApplication", workflows.CloseApplicationSignal{
+		Status:      status,
+		ReasonCode:  req.ReasonCode,
+		Comments:    req.Comments,
+		RequestedBy: auth.PrincipalFrom(c).Subject,
+	})
 }
 
 // ProcessFunding handles funding completion
 func (h *LoanHandler) ProcessFunding(c *gin.Context) {
-	loanID := c.Param("id")
-
 	var req struct {
-		FundManagerID string  `json:"fund_manager_id" binding:"required"`
 		FundingAmount float64 `json:"funding_amount" binding:"required"`
 		FundingNotes  string  `json:"funding_notes"`
 	}
@@ -362,26 +539,29 @@ func (h *LoanHandler) ProcessFunding(c *gin.Context) {
 		return
 	}
 
-	// Send signal to workflow (workflow will update status to funded)
-	workflowID := "loan-origination-" + loanID
-	err := h.temporalClient.SignalWorkflow(
-		c.Request.Context(),
-		workflowID,
-		"",
-		"funding-completed",
-		workflows.FundingCompletedSignal{
-			FundManagerID: req.FundManagerID,
-			FundingAmount: req.FundingAmount,
-			FundingNotes:  req.FundingNotes,
-		},
-	)
+	h.updateLoan(c, http.StatusOK, "completeFunding", workflows.FundingCompletedSignal{
+		FundManagerID: auth.PrincipalFrom(c).Subject,
+		FundingAmount: req.FundingAmount,
+		FundingNotes:  req.FundingNotes,
+	})
+}
 
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send workflow signal"})
+// RetryFunding returns a loan whose funding failed to the funding steps.
+// The borrower signs a new loan agreement before it is funded again.
+func (h *LoanHandler) RetryFunding(c *gin.Context) {
+	var req struct {
+		Comments string `json:"comments" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Funding processed successfully"})
+	h.updateLoan(c, http.StatusOK, "retryFunding", workflows.RetryFundingSignal{
+		RequestedBy: auth.PrincipalFrom(c).Subject,
+		Comments:    req.Comments,
+	})
 }
 
 // GetWorkflowStatus returns the current workflow status
@@ -406,3 +586,105 @@ func (h *LoanHandler) GetWorkflowStatus(c *gin.Context) {
 		"start_time":  resp.WorkflowExecutionInfo.StartTime,
 	})
 }
+
+// queryLoan queries the loan workflow for its current state
+func (h *LoanHandler) queryLoan(ctx context.Context, loanID string) (workflows.LoanOriginationState, error) {
+	var loanData workflows.LoanOriginationState
+
+	resp, err := h.temporalClient.QueryWorkflow(ctx, "loan-origination-"+loanID, "", "getLoanApplication")
+	if err != nil {
+		return loanData, err
+	}
+	err = resp.Get(&loanData)
+	return loanData, err
+}
+
+// findDocument looks up a document in the projection, falling back to the
+// workflow for documents uploaded since the loan was last projected
+func (h *LoanHandler) findDocument(ctx context.Context, loanID, documentID string) (workflows.Document, error) {
+	loanData, err := h.store.GetLoan(ctx, loanID)
+	if err == nil {
+		for _, doc := range loanData.Documents {
+			if doc.ID == documentID {
+				return doc, nil
+			}
+		}
+	} else if !errors.Is(err, projection.ErrNotFound) {
+		return workflows.Document{}, err
+	}
+
+	loanData, err = h.queryLoan(ctx, loanID)
+	if err != nil {
+		return workflows.Document{}, err
+	}
+	for _, doc := range loanData.Documents {
+		if doc.ID == documentID {
+			return doc, nil
+		}
+	}
+	return workflows.Document{}, projection.ErrNotFound
+}
+
+// findAgreement looks up the loan's current agreement, querying the workflow
+// when the projection has not caught up with it yet.
+func (h *LoanHandler) findAgreement(ctx context.Context, loanID string) (*workflows.LoanAgreement, error) {
+	loanData, err := h.store.GetLoan(ctx, loanID)
+	if err == nil && loanData.LoanAgreement != nil {
+		return loanData.LoanAgreement, nil
+	} else if err != nil && !errors.Is(err, projection.ErrNotFound) {
+		return nil, err
+	}
+
+	loanData, err = h.queryLoan(ctx, loanID)
+	if err != nil {
+		return nil, err
+	}
+	if loanData.LoanAgreement == nil {
+		return nil, projection.ErrNotFound
+	}
+	return loanData.LoanAgreement, nil
+}
+
+// updateLoan sends a workflow update for the loan in the request path and
+// responds with the resulting workflow state. Updates the workflow rejects for
+// its current stage are reported as 409 Conflict. It reports whether the
+// update succeeded.
+func (h *LoanHandler) updateLoan(c *gin.Context, successStatus int, updateName string, arg interface{}) bool {
+	workflowID := "loan-origination-" + c.Param("id")
+
+	handle, err := h.temporalClient.UpdateWorkflow(c.Request.Context(), workflowID, "", updateName, arg)
+	if err != nil {
+		h.respondUpdateError(c, workflowID, err)
+		return false
+	}
+
+	var loanData workflows.LoanOriginationState
+	if err := handle.Get(c.Request.Context(), &loanData); err != nil {
+		h.respondUpdateError(c, workflowID, err)
+		return false
+	}
+
+	c.JSON(successStatus, loanData)
+	return true
+}
+
+func (h *LoanHandler) respondUpdateError(c *gin.Context, workflowID string, err error) {
+	var appErr *temporal.ApplicationError
+	if errors.As(err, &appErr) && appErr.Type() == workflows.UpdateRejectedErrorType {
+		c.JSON(http.StatusConflict, gin.H{"error": appErr.Message()})
+		return
+	}
+
+	var notFound *serviceerror.NotFound
+	if errors.As(err, &notFound) {
+		// Updates are also refused once the workflow has completed
+		if _, err := h.temporalClient.DescribeWorkflowExecution(c.Request.Context(), workflowID, ""); err == nil {
+			c.JSON(http.StatusConflict, gin.H{"error": "Loan application is no longer active"})
+			return
+		}
+		c.JSON(http.StatusNotFound, gin.H{"error": "Loan application not found"})
+		return
+	}
+
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update workflow"})
+}
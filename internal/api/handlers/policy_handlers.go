@@ -0,0 +1,23 @@
+package handlers
+
+import (
+	"net/http"
+
+	"loan-origination-system/internal/policy"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PolicyHandler struct {
+	policies *policy.Set
+}
+
+func NewPolicyHandler(policies *policy.Set) *PolicyHandler {
+	return &PolicyHandler{policies: policies}
+}
+
+// GetPolicies returns the underwriting policy new loans are started under.
+// Loans already started keep the snapshot in their own state.
+func (h *PolicyHandler) GetPolicies(c *gin.Context) {
+	c.JSON(http.StatusOK, h.policies)
+}
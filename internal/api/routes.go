@@ -1,40 +1,101 @@
 package api
 
 import (
+	"loan-origination-system/internal/api/auth"
 	"loan-origination-system/internal/api/handlers"
+	"loan-origination-system/internal/config"
+	"loan-origination-system/internal/events"
+	"loan-origination-system/internal/ledger"
+	"loan-origination-system/internal/policy"
+	"loan-origination-system/internal/projection"
+	"loan-origination-system/internal/storage"
 
 	"github.com/gin-gonic/gin"
 	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/converter"
 )
 
-func SetupRoutes(router *gin.Engine, temporalClient client.Client) {
-	loanHandler := handlers.NewLoanHandler(temporalClient)
+func SetupRoutes(router *gin.Engine, cfg config.Config, temporalClient client.Client, dataConverter converter.DataConverter, store *projection.Store, documents storage.BlobStore, policies *policy.Set, book *ledger.Ledger, broker *events.Broker, authenticator *auth.Authenticator) {
+	loanHandler := handlers.NewLoanHandler(temporalClient, dataConverter, cfg.Temporal.TaskQueue, store, documents, policies, cfg.Temporal.SearchKey)
+	policyHandler := handlers.NewPolicyHandler(policies)
+	ledgerHandler := handlers.NewLedgerHandler(book)
+	eventHandler := handlers.NewEventHandler(broker, store)
+	authHandler := handlers.NewAuthHandler(authenticator)
+
+	// Role sets for the routes below
+	anyRole := auth.RequireRoles(auth.AllRoles...)
+	staff := auth.RequireRoles(auth.StaffRoles...)
+	loanOfficer := auth.RequireRoles(auth.RoleLoanOfficer)
+	applicant := auth.RequireRoles(auth.RoleCustomer, auth.RoleLoanOfficer)
+	uploader := auth.RequireRoles(auth.RoleCustomer, auth.RoleLoanOfficer)
+	withdrawer := auth.RequireRoles(auth.RoleCustomer, auth.RoleLoanOfficer)
+	borrower := auth.RequireRoles(auth.RoleCustomer)
+	documentReader := auth.RequireRoles(auth.RoleCustomer, auth.RoleLoanOfficer, auth.RoleLoanProcessor, auth.RoleUnderwriter)
+	loanProcessor := auth.RequireRoles(auth.RoleLoanProcessor)
+	appraiser := auth.RequireRoles(auth.RoleAppraiser)
+	underwriter := auth.RequireRoles(auth.RoleUnderwriter)
+	conditionClearer := auth.RequireRoles(auth.RoleUnderwriter, auth.RoleLoanProcessor)
+	fundManager := auth.RequireRoles(auth.RoleFundManager)
+	admin := auth.RequireRoles(auth.RoleAdmin)
+
+	// Customers may only reach the loans they applied for
+	owner := loanHandler.RequireLoanOwner
+
+	// Public routes
+	router.POST("/api/v1/auth/login", authHandler.Login)
 
 	// API routes
-	api := router.Group("/api/v1")
+	api := router.Group("/api/v1", authenticator.Middleware())
 	{
+		api.GET("/auth/me", anyRole, authHandler.Me)
+
 		// Loan application routes
-		api.POST("/loans", loanHandler.CreateLoanApplication)
-		api.GET("/loans", loanHandler.GetLoanApplications)
-		api.GET("/loans/:id", loanHandler.GetLoanApplication)
-		api.GET("/loans/:id/status", loanHandler.GetWorkflowStatus)
+		api.POST("/loans", applicant, loanHandler.CreateLoanApplication)
+		api.GET("/loans", anyRole, loanHandler.GetLoanApplications)
+		api.GET("/loans/:id", anyRole, owner, loanHandler.GetLoanApplication)
+		api.GET("/loans/:id/status", anyRole, owner, loanHandler.GetWorkflowStatus)
+		api.GET("/loans/:id/audit", staff, loanHandler.GetAuditTrail)
+
+		// Underwriting policy routes
+		api.GET("/policies", staff, policyHandler.GetPolicies)
+
+		// Live update routes
+		api.GET("/events", anyRole, eventHandler.StreamEvents)
+		api.GET("/loans/:id/events", anyRole, owner, eventHandler.StreamLoanEvents)
 
 		// Document routes
-		api.POST("/loans/:id/documents", loanHandler.UploadDocument)
-		api.POST("/loans/:id/verify-documents", loanHandler.VerifyDocument)
+		api.POST("/loans/:id/documents", uploader, owner, loanHandler.UploadDocument)
+		api.GET("/loans/:id/documents/:documentId", documentReader, owner, loanHandler.DownloadDocument)
+		api.GET("/loans/:id/agreement", documentReader, owner, loanHandler.GetLoanAgreement)
+		api.POST("/loans/:id/verify-documents", loanProcessor, loanHandler.VerifyDocument)
 
 		// Appraisal routes
-		api.POST("/loans/:id/appraisal", loanHandler.CompleteAppraisal)
+		api.POST("/loans/:id/appraisal", appraiser, loanHandler.CompleteAppraisal)
 
 		// Underwriting routes
-		api.POST("/loans/:id/underwriting", loanHandler.MakeUnderwritingDecision)
+		api.POST("/loans/:id/underwriting", underwriter, loanHandler.MakeUnderwritingDecision)
+		api.POST("/loans/:id/counter-offer", borrower, owner, loanHandler.RespondToCounterOffer)
+		api.POST("/loans/:id/signature", borrower, owner, loanHandler.SignAgreement)
+		api.POST("/loans/:id/conditions/:conditionId", conditionClearer, loanHandler.ClearCondition)
 
 		// Funding routes
-		api.POST("/loans/:id/funding", loanHandler.ProcessFunding)
+		api.POST("/loans/:id/funding", fundManager, loanHandler.ProcessFunding)
+		api.GET("/ledger", fundManager, ledgerHandler.GetLedger)
+
+		// Closure routes
+		api.POST("/loans/:id/withdraw", withdrawer, owner, loanHandler.WithdrawApplication)
+		api.POST("/loans/:id/cancel", loanOfficer, loanHandler.CancelApplication)
+
+		// Admin routes
+		api.POST("/admin/loans/:id/retry-funding", admin, loanHandler.RetryFunding)
 	}
 
 	// Serve static files for frontend
 	router.Static("/css", "./web/css")
 	router.Static("/js", "./web/js")
 	router.StaticFile("/", "./web/index.html")
+	// Local stand-in for an e-signature provider's signing page
+	router.GET("/sign/:id", func(c *gin.Context) {
+		c.File("./web/sign.html")
+	})
 }
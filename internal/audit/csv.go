@@ -0,0 +1,53 @@
+package audit
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"time"
+)
+
+var csvHeader = []string{"event_id", "time", "kind", "name", "actor", "attempt", "status", "failure", "payload"}
+
+// WriteCSV writes the timeline as CSV with one row per entry. Payloads are
+// written as JSON.
+func WriteCSV(w io.Writer, entries []Entry) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvHeader); err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		payload := ""
+		if entry.Payload != nil {
+			data, err := json.Marshal(entry.Payload)
+			if err != nil {
+				return err
+			}
+			payload = string(data)
+		}
+
+		attempt := ""
+		if entry.Attempt > 0 {
+			attempt = strconv.Itoa(int(entry.Attempt))
+		}
+
+		if err := writer.Write([]string{
+			strconv.FormatInt(entry.EventID, 10),
+			entry.Time.UTC().Format(time.RFC3339Nano),
+			entry.Kind,
+			entry.Name,
+			entry.Actor,
+			attempt,
+			entry.Status,
+			entry.Failure,
+			payload,
+		}); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,298 @@
+// Package audit builds a loan's audit trail from its workflow event history.
+// The history is written by the Temporal server and cannot be edited, so the
+// trail is a faithful record of who did what to the loan and when.
+package audit
+
+import (
+	"time"
+
+	"loan-origination-system/internal/workflows"
+
+	commonpb "go.temporal.io/api/common/v1"
+	enumspb "go.temporal.io/api/enums/v1"
+	failurepb "go.temporal.io/api/failure/v1"
+	historypb "go.temporal.io/api/history/v1"
+	"go.temporal.io/sdk/converter"
+)
+
+// Kinds of timeline entries.
+const (
+	KindWorkflowStarted    = "workflow_started"
+	KindWorkflowCompleted  = "workflow_completed"
+	KindWorkflowFailed     = "workflow_failed"
+	KindWorkflowTimedOut   = "workflow_timed_out"
+	KindWorkflowCanceled   = "workflow_canceled"
+	KindWorkflowTerminated = "workflow_terminated"
+	KindSignal             = "signal"
+	KindUpdateAccepted     = "update_accepted"
+	KindUpdateCompleted    = "update_completed"
+	KindActivityScheduled  = "activity_scheduled"
+	KindActivityStarted    = "activity_started"
+	KindActivityCompleted  = "activity_completed"
+	KindActivityFailed     = "activity_failed"
+	KindActivityTimedOut   = "activity_timed_out"
+	KindTimerStarted       = "timer_started"
+	KindTimerFired         = "timer_fired"
+	KindTimerCanceled      = "timer_canceled"
+	KindStatusChanged      = "status_changed"
+)
+
+// actorFields are the payload fields naming the person or service behind an
+// action, in the order they are looked up.
+var actorFields = []string{
+	"created_by",
+	"uploaded_by",
+	"verified_by",
+	"appraiser_id",
+	"underwriter_id",
+	"fund_manager_id",
+	"requested_by",
+	"responded_by",
+	"cleared_by",
+	"signed_by",
+}
+
+// Entry is one event of a loan's audit timeline.
+type Entry struct {
+	EventID int64       `json:"event_id"`
+	Time    time.Time   `json:"time"`
+	Kind    string      `json:"kind"`
+	Name    string      `json:"name,omitempty"`
+	Actor   string      `json:"actor,omitempty"`
+	Attempt int32       `json:"attempt,omitempty"`
+	Status  string      `json:"status,omitempty"`
+	Failure string      `json:"failure,omitempty"`
+	Payload interface{} `json:"payload,omitempty"`
+}
+
+// BuildTimeline turns workflow history events into an ordered audit timeline,
+// decoding payloads with dc. Workflow tasks and the internal projection
+// activity are left out.
+func BuildTimeline(events []*historypb.HistoryEvent, dc converter.DataConverter) []Entry {
+	b := &builder{
+		dc:         dc,
+		activities: make(map[int64]string),
+		timers:     make(map[string]time.Duration),
+		entries:    make([]Entry, 0, len(events)),
+	}
+	for _, event := range events {
+		b.add(event)
+	}
+	return b.entries
+}
+
+type builder struct {
+	dc converter.DataConverter
+	// activities maps scheduled event IDs of audited activities to their type
+	activities map[int64]string
+	timers     map[string]time.Duration
+	entries    []Entry
+}
+
+func (b *builder) add(event *historypb.HistoryEvent) {
+	entry := Entry{EventID: event.GetEventId()}
+	if t := event.GetEventTime(); t != nil {
+		entry.Time = *t
+	}
+
+	switch event.GetEventType() {
+	case enumspb.EVENT_TYPE_WORKFLOW_EXECUTION_STARTED:
+		attrs := event.GetWorkflowExecutionStartedEventAttributes()
+		entry.Kind = KindWorkflowStarted
+		entry.Payload = b.decodePayloads(attrs.GetInput())
+		entry.Actor = actorOf(entry.Payload, attrs.GetIdentity())
+		entry.Status = b.loanStatus(attrs.GetSearchAttributes())
+	case enumspb.EVENT_TYPE_WORKFLOW_EXECUTION_COMPLETED:
+		entry.Kind = KindWorkflowCompleted
+		entry.Payload = b.decodePayloads(event.GetWorkflowExecutionCompletedEventAttributes().GetResult())
+	case enumspb.EVENT_TYPE_WORKFLOW_EXECUTION_FAILED:
+		entry.Kind = KindWorkflowFailed
+		entry.Failure = failureMessage(event.GetWorkflowExecutionFailedEventAttributes().GetFailure())
+	case enumspb.EVENT_TYPE_WORKFLOW_EXECUTION_TIMED_OUT:
+		entry.Kind = KindWorkflowTimedOut
+	case enumspb.EVENT_TYPE_WORKFLOW_EXECUTION_CANCELED:
+		entry.Kind = KindWorkflowCanceled
+	case enumspb.EVENT_TYPE_WORKFLOW_EXECUTION_TERMINATED:
+		attrs := event.GetWorkflowExecutionTerminatedEventAttributes()
+		entry.Kind = KindWorkflowTerminated
+		entry.Actor = attrs.GetIdentity()
+		entry.Failure = attrs.GetReason()
+	case enumspb.EVENT_TYPE_WORKFLOW_EXECUTION_SIGNALED:
+		attrs := event.GetWorkflowExecutionSignaledEventAttributes()
+		entry.Kind = KindSignal
+		entry.Name = attrs.GetSignalName()
+		entry.Payload = b.decodePayloads(attrs.GetInput())
+		entry.Actor = actorOf(entry.Payload, attrs.GetIdentity())
+	case enumspb.EVENT_TYPE_WORKFLOW_EXECUTION_UPDATE_ACCEPTED:
+		request := event.GetWorkflowExecutionUpdateAcceptedEventAttributes().GetAcceptedRequest()
+		entry.Kind = KindUpdateAccepted
+		entry.Name = request.GetInput().GetName()
+		entry.Payload = b.decodePayloads(request.GetInput().GetArgs())
+		entry.Actor = actorOf(entry.Payload, request.GetMeta().GetIdentity())
+	case enumspb.EVENT_TYPE_WORKFLOW_EXECUTION_UPDATE_COMPLETED:
+		// The successful outcome is the whole loan state, which the status
+		// entries already cover, so only failures are recorded
+		entry.Kind = KindUpdateCompleted
+		entry.Failure = failureMessage(event.GetWorkflowExecutionUpdateCompletedEventAttributes().GetOutcome().GetFailure())
+	case enumspb.EVENT_TYPE_ACTIVITY_TASK_SCHEDULED:
+		attrs := event.GetActivityTaskScheduledEventAttributes()
+		name := attrs.GetActivityType().GetName()
+		if name == workflows.ProjectLoanStateActivity {
+			return
+		}
+		b.activities[entry.EventID] = name
+		entry.Kind = KindActivityScheduled
+		entry.Name = name
+		entry.Payload = b.decodePayloads(attrs.GetInput())
+	case enumspb.EVENT_TYPE_ACTIVITY_TASK_STARTED:
+		// Retried activities record a single started event carrying the final
+		// attempt number and the failure of the attempt before it
+		attrs := event.GetActivityTaskStartedEventAttributes()
+		name, ok := b.activities[attrs.GetScheduledEventId()]
+		if !ok {
+			return
+		}
+		entry.Kind = KindActivityStarted
+		entry.Name = name
+		entry.Actor = attrs.GetIdentity()
+		entry.Attempt = attrs.GetAttempt()
+		entry.Failure = failureMessage(attrs.GetLastFailure())
+	case enumspb.EVENT_TYPE_ACTIVITY_TASK_COMPLETED:
+		attrs := event.GetActivityTaskCompletedEventAttributes()
+		name, ok := b.activities[attrs.GetScheduledEventId()]
+		if !ok {
+			return
+		}
+		entry.Kind = KindActivityCompleted
+		entry.Name = name
+		entry.Actor = attrs.GetIdentity()
+		entry.Payload = b.decodePayloads(attrs.GetResult())
+	case enumspb.EVENT_TYPE_ACTIVITY_TASK_FAILED:
+		attrs := event.GetActivityTaskFailedEventAttributes()
+		name, ok := b.activities[attrs.GetScheduledEventId()]
+		if !ok {
+			return
+		}
+		entry.Kind = KindActivityFailed
+		entry.Name = name
+		entry.Actor = attrs.GetIdentity()
+		entry.Failure = failureMessage(attrs.GetFailure())
+	case enumspb.EVENT_TYPE_ACTIVITY_TASK_TIMED_OUT:
+		attrs := event.GetActivityTaskTimedOutEventAttributes()
+		name, ok := b.activities[attrs.GetScheduledEventId()]
+		if !ok {
+			return
+		}
+		entry.Kind = KindActivityTimedOut
+		entry.Name = name
+		entry.Failure = failureMessage(attrs.GetFailure())
+	case enumspb.EVENT_TYPE_TIMER_STARTED:
+		attrs := event.GetTimerStartedEventAttributes()
+		var timeout time.Duration
+		if d := attrs.GetStartToFireTimeout(); d != nil {
+			timeout = *d
+		}
+		b.timers[attrs.GetTimerId()] = timeout
+		entry.Kind = KindTimerStarted
+		entry.Name = attrs.GetTimerId()
+		entry.Payload = map[string]interface{}{"start_to_fire_timeout": timeout.String()}
+	case enumspb.EVENT_TYPE_TIMER_FIRED:
+		attrs := event.GetTimerFiredEventAttributes()
+		entry.Kind = KindTimerFired
+		entry.Name = attrs.GetTimerId()
+		entry.Payload = map[string]interface{}{"start_to_fire_timeout": b.timers[attrs.GetTimerId()].String()}
+	case enumspb.EVENT_TYPE_TIMER_CANCELED:
+		entry.Kind = KindTimerCanceled
+		entry.Name = event.GetTimerCanceledEventAttributes().GetTimerId()
+	case enumspb.EVENT_TYPE_UPSERT_WORKFLOW_SEARCH_ATTRIBUTES:
+		attributes := event.GetUpsertWorkflowSearchAttributesEventAttributes().GetSearchAttributes()
+		status := b.loanStatus(attributes)
+		nextStep := b.searchAttribute(attributes, workflows.SearchAttributeNextStep)
+		if status == "" && nextStep == nil {
+			return
+		}
+		entry.Kind = KindStatusChanged
+		entry.Status = status
+		entry.Payload = b.decodeSearchAttributes(attributes)
+	default:
+		return
+	}
+
+	b.entries = append(b.entries, entry)
+}
+
+// decodePayloads decodes a single payload to its value and several payloads
+// to a list of values.
+func (b *builder) decodePayloads(payloads *commonpb.Payloads) interface{} {
+	values := make([]interface{}, 0, len(payloads.GetPayloads()))
+	for _, payload := range payloads.GetPayloads() {
+		values = append(values, b.decodePayload(payload))
+	}
+	switch len(values) {
+	case 0:
+		return nil
+	case 1:
+		return values[0]
+	default:
+		return values
+	}
+}
+
+func (b *builder) decodePayload(payload *commonpb.Payload) interface{} {
+	var value interface{}
+	if err := b.dc.FromPayload(payload, &value); err != nil {
+		return map[string]interface{}{"undecodable": string(payload.GetMetadata()[converter.MetadataEncoding])}
+	}
+	return value
+}
+
+func (b *builder) searchAttribute(attributes *commonpb.SearchAttributes, name string) interface{} {
+	payload, ok := attributes.GetIndexedFields()[name]
+	if !ok {
+		return nil
+	}
+	return b.decodePayload(payload)
+}
+
+func (b *builder) decodeSearchAttributes(attributes *commonpb.SearchAttributes) map[string]interface{} {
+	values := make(map[string]interface{}, len(attributes.GetIndexedFields()))
+	for name, payload := range attributes.GetIndexedFields() {
+		values[name] = b.decodePayload(payload)
+	}
+	return values
+}
+
+func (b *builder) loanStatus(attributes *commonpb.SearchAttributes) string {
+	status, _ := b.searchAttribute(attributes, workflows.SearchAttributeLoanStatus).(string)
+	return status
+}
+
+// actorOf returns the actor named in a decoded payload, falling back to the
+// identity of the client that sent it.
+func actorOf(payload interface{}, identity string) string {
+	if fields, ok := payload.(map[string]interface{}); ok {
+		for _, field := range actorFields {
+			if actor, ok := fields[field].(string); ok && actor != "" {
+				return actor
+			}
+		}
+		// The workflow input nests the application that names its creator
+		if loan, ok := fields["loan_application"]; ok {
+			if actor := actorOf(loan, ""); actor != "" {
+				return actor
+			}
+		}
+	}
+	return identity
+}
+
+func failureMessage(failure *failurepb.Failure) string {
+	if failure == nil {
+		return ""
+	}
+	message := failure.GetMessage()
+	if cause := failure.GetCause(); cause != nil {
+		message += ": " + failureMessage(cause)
+	}
+	return message
+}
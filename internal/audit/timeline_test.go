@@ -0,0 +1,175 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+	"time"
+
+	"loan-origination-system/internal/workflows"
+
+	"github.com/stretchr/testify/require"
+	commonpb "go.temporal.io/api/common/v1"
+	enumspb "go.temporal.io/api/enums/v1"
+	failurepb "go.temporal.io/api/failure/v1"
+	historypb "go.temporal.io/api/history/v1"
+	updatepb "go.temporal.io/api/update/v1"
+	"go.temporal.io/sdk/converter"
+)
+
+var start = time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC)
+
+func payloads(t *testing.T, values ...interface{}) *commonpb.Payloads {
+	t.Helper()
+	p, err := converter.GetDefaultDataConverter().ToPayloads(values...)
+	require.NoError(t, err)
+	return p
+}
+
+func searchAttributes(t *testing.T, values map[string]interface{}) *commonpb.SearchAttributes {
+	t.Helper()
+	fields := make(map[string]*commonpb.Payload, len(values))
+	for name, value := range values {
+		p, err := converter.GetDefaultDataConverter().ToPayload(value)
+		require.NoError(t, err)
+		fields[name] = p
+	}
+	return &commonpb.SearchAttributes{IndexedFields: fields}
+}
+
+func event(id int64, eventType enumspb.EventType, attributes interface{}) *historypb.HistoryEvent {
+	eventTime := start.Add(time.Duration(id) * time.Minute)
+	e := &historypb.HistoryEvent{EventId: id, EventTime: &eventTime, EventType: eventType}
+	switch a := attributes.(type) {
+	case *historypb.WorkflowExecutionStartedEventAttributes:
+		e.Attributes = &historypb.HistoryEvent_WorkflowExecutionStartedEventAttributes{WorkflowExecutionStartedEventAttributes: a}
+	case *historypb.WorkflowExecutionSignaledEventAttributes:
+		e.Attributes = &historypb.HistoryEvent_WorkflowExecutionSignaledEventAttributes{WorkflowExecutionSignaledEventAttributes: a}
+	case *historypb.WorkflowExecutionUpdateAcceptedEventAttributes:
+		e.Attributes = &historypb.HistoryEvent_WorkflowExecutionUpdateAcceptedEventAttributes{WorkflowExecutionUpdateAcceptedEventAttributes: a}
+	case *historypb.ActivityTaskScheduledEventAttributes:
+		e.Attributes = &historypb.HistoryEvent_ActivityTaskScheduledEventAttributes{ActivityTaskScheduledEventAttributes: a}
+	case *historypb.ActivityTaskStartedEventAttributes:
+		e.Attributes = &historypb.HistoryEvent_ActivityTaskStartedEventAttributes{ActivityTaskStartedEventAttributes: a}
+	case *historypb.ActivityTaskCompletedEventAttributes:
+		e.Attributes = &historypb.HistoryEvent_ActivityTaskCompletedEventAttributes{ActivityTaskCompletedEventAttributes: a}
+	case *historypb.TimerStartedEventAttributes:
+		e.Attributes = &historypb.HistoryEvent_TimerStartedEventAttributes{TimerStartedEventAttributes: a}
+	case *historypb.TimerFiredEventAttributes:
+		e.Attributes = &historypb.HistoryEvent_TimerFiredEventAttributes{TimerFiredEventAttributes: a}
+	case *historypb.UpsertWorkflowSearchAttributesEventAttributes:
+		e.Attributes = &historypb.HistoryEvent_UpsertWorkflowSearchAttributesEventAttributes{UpsertWorkflowSearchAttributesEventAttributes: a}
+	}
+	return e
+}
+
+func testHistory(t *testing.T) []*historypb.HistoryEvent {
+	timeout := 7 * 24 * time.Hour
+	return []*historypb.HistoryEvent{
+		event(1, enumspb.EVENT_TYPE_WORKFLOW_EXECUTION_STARTED, &historypb.WorkflowExecutionStartedEventAttributes{
+			Input: payloads(t, workflows.LoanOriginationWorkflowInput{
+				LoanApplication: workflows.LoanApplication{ID: "loan-1", CreatedBy: "loan-officer"},
+			}),
+			Identity:         "api-server",
+			SearchAttributes: searchAttributes(t, map[string]interface{}{workflows.SearchAttributeLoanStatus: "submitted"}),
+		}),
+		event(2, enumspb.EVENT_TYPE_WORKFLOW_TASK_SCHEDULED, nil),
+		event(3, enumspb.EVENT_TYPE_TIMER_STARTED, &historypb.TimerStartedEventAttributes{TimerId: "5", StartToFireTimeout: &timeout}),
+		event(4, enumspb.EVENT_TYPE_WORKFLOW_EXECUTION_SIGNALED, &historypb.WorkflowExecutionSignaledEventAttributes{
+			SignalName: "documentUploaded",
+			Input:      payloads(t, workflows.DocumentUploadedSignal{DocumentID: "doc-1", UploadedBy: "customer"}),
+			Identity:   "api-server",
+		}),
+		event(5, enumspb.EVENT_TYPE_WORKFLOW_EXECUTION_UPDATE_ACCEPTED, &historypb.WorkflowExecutionUpdateAcceptedEventAttributes{
+			AcceptedRequest: &updatepb.Request{
+				Meta:  &updatepb.Meta{Identity: "api-server"},
+				Input: &updatepb.Input{Name: "completeAppraisal", Args: payloads(t, workflows.AppraisalCompletedSignal{PropertyValue: 500000, AppraiserID: "appraiser"})},
+			},
+		}),
+		event(6, enumspb.EVENT_TYPE_ACTIVITY_TASK_SCHEDULED, &historypb.ActivityTaskScheduledEventAttributes{
+			ActivityType: &commonpb.ActivityType{Name: "CreditScoreCheck"},
+			Input:        payloads(t, "loan-1"),
+		}),
+		event(7, enumspb.EVENT_TYPE_ACTIVITY_TASK_SCHEDULED, &historypb.ActivityTaskScheduledEventAttributes{
+			ActivityType: &commonpb.ActivityType{Name: workflows.ProjectLoanStateActivity},
+		}),
+		event(8, enumspb.EVENT_TYPE_ACTIVITY_TASK_STARTED, &historypb.ActivityTaskStartedEventAttributes{
+			ScheduledEventId: 6,
+			Identity:         "worker",
+			Attempt:          3,
+			LastFailure:      &failurepb.Failure{Message: "credit bureau unavailable"},
+		}),
+		event(9, enumspb.EVENT_TYPE_ACTIVITY_TASK_STARTED, &historypb.ActivityTaskStartedEventAttributes{ScheduledEventId: 7, Attempt: 1}),
+		event(10, enumspb.EVENT_TYPE_ACTIVITY_TASK_COMPLETED, &historypb.ActivityTaskCompletedEventAttributes{
+			ScheduledEventId: 6,
+			Identity:         "worker",
+			Result:           payloads(t, map[string]interface{}{"score": 720}),
+		}),
+		event(11, enumspb.EVENT_TYPE_TIMER_FIRED, &historypb.TimerFiredEventAttributes{TimerId: "5"}),
+		event(12, enumspb.EVENT_TYPE_UPSERT_WORKFLOW_SEARCH_ATTRIBUTES, &historypb.UpsertWorkflowSearchAttributesEventAttributes{
+			SearchAttributes: searchAttributes(t, map[string]interface{}{workflows.SearchAttributeLoanStatus: "approved"}),
+		}),
+		event(13, enumspb.EVENT_TYPE_UPSERT_WORKFLOW_SEARCH_ATTRIBUTES, &historypb.UpsertWorkflowSearchAttributesEventAttributes{
+			SearchAttributes: searchAttributes(t, map[string]interface{}{workflows.SearchAttributeCreditScore: 720}),
+		}),
+	}
+}
+
+func TestBuildTimeline(t *testing.T) {
+	timeline := BuildTimeline(testHistory(t), converter.GetDefaultDataConverter())
+
+	var kinds []string
+	for _, entry := range timeline {
+		kinds = append(kinds, entry.Kind)
+	}
+	require.Equal(t, []string{
+		KindWorkflowStarted,
+		KindTimerStarted,
+		KindSignal,
+		KindUpdateAccepted,
+		KindActivityScheduled,
+		KindActivityStarted,
+		KindActivityCompleted,
+		KindTimerFired,
+		KindStatusChanged,
+	}, kinds)
+
+	started := timeline[0]
+	require.Equal(t, int64(1), started.EventID)
+	require.Equal(t, start.Add(time.Minute), started.Time)
+	require.Equal(t, "loan-officer", started.Actor)
+	require.Equal(t, "submitted", started.Status)
+
+	signal := timeline[2]
+	require.Equal(t, "documentUploaded", signal.Name)
+	require.Equal(t, "customer", signal.Actor)
+	require.Equal(t, "doc-1", signal.Payload.(map[string]interface{})["document_id"])
+
+	update := timeline[3]
+	require.Equal(t, "completeAppraisal", update.Name)
+	require.Equal(t, "appraiser", update.Actor)
+
+	attempt := timeline[5]
+	require.Equal(t, "CreditScoreCheck", attempt.Name)
+	require.Equal(t, int32(3), attempt.Attempt)
+	require.Equal(t, "credit bureau unavailable", attempt.Failure)
+
+	fired := timeline[7]
+	require.Equal(t, "5", fired.Name)
+	require.Equal(t, map[string]interface{}{"start_to_fire_timeout": "168h0m0s"}, fired.Payload)
+
+	require.Equal(t, "approved", timeline[8].Status)
+}
+
+func TestWriteCSV(t *testing.T) {
+	timeline := BuildTimeline(testHistory(t), converter.GetDefaultDataConverter())
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteCSV(&buf, timeline))
+
+	rows, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, rows, len(timeline)+1)
+	require.Equal(t, csvHeader, rows[0])
+	require.Equal(t, []string{"8", "2024-03-01T09:08:00Z", KindActivityStarted, "CreditScoreCheck", "worker", "3", "", "credit bureau unavailable", ""}, rows[6])
+}
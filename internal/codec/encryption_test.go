@@ -0,0 +1,107 @@
+package codec
+
+import (
+	"bytes"
+	"testing"
+
+	"loan-origination-system/internal/workflows"
+
+	"github.com/stretchr/testify/require"
+	commonpb "go.temporal.io/api/common/v1"
+	"go.temporal.io/sdk/converter"
+)
+
+var (
+	key1 = bytes.Repeat([]byte{1}, 32)
+	key2 = bytes.Repeat([]byte{2}, 32)
+)
+
+func testPayload(t *testing.T) *commonpb.Payload {
+	payload, err := converter.GetDefaultDataConverter().ToPayload(workflows.LoanApplication{
+		BorrowerName:  "Jane Doe",
+		BorrowerEmail: "jane@example.com",
+	})
+	require.NoError(t, err)
+	return payload
+}
+
+func TestEncryptionCodec_RoundTrip(t *testing.T) {
+	codec, err := NewEncryptionCodec("k1", map[string][]byte{"k1": key1})
+	require.NoError(t, err)
+	payload := testPayload(t)
+
+	encoded, err := codec.Encode([]*commonpb.Payload{payload})
+	require.NoError(t, err)
+	require.Equal(t, EncodingEncrypted, string(encoded[0].Metadata[converter.MetadataEncoding]))
+	require.Equal(t, "k1", string(encoded[0].Metadata[MetadataKeyID]))
+	require.NotContains(t, string(encoded[0].Data), "jane@example.com")
+
+	decoded, err := codec.Decode(encoded)
+	require.NoError(t, err)
+	require.True(t, payload.Equal(decoded[0]))
+}
+
+func TestEncryptionCodec_RotatesKeys(t *testing.T) {
+	before, err := NewEncryptionCodec("k1", map[string][]byte{"k1": key1})
+	require.NoError(t, err)
+	encodedBefore, err := before.Encode([]*commonpb.Payload{testPayload(t)})
+	require.NoError(t, err)
+
+	// After rotation new payloads use k2 while k1 payloads still decode
+	after, err := NewEncryptionCodec("k2", map[string][]byte{"k1": key1, "k2": key2})
+	require.NoError(t, err)
+	encodedAfter, err := after.Encode([]*commonpb.Payload{testPayload(t)})
+	require.NoError(t, err)
+	require.Equal(t, "k2", string(encodedAfter[0].Metadata[MetadataKeyID]))
+
+	decoded, err := after.Decode([]*commonpb.Payload{encodedBefore[0], encodedAfter[0]})
+	require.NoError(t, err)
+	require.True(t, testPayload(t).Equal(decoded[0]))
+	require.True(t, testPayload(t).Equal(decoded[1]))
+
+	// Once k1 is retired its payloads can no longer be read
+	retired, err := NewEncryptionCodec("k2", map[string][]byte{"k2": key2})
+	require.NoError(t, err)
+	_, err = retired.Decode(encodedBefore)
+	require.ErrorContains(t, err, `unknown encryption key "k1"`)
+}
+
+func TestEncryptionCodec_RejectsTamperedPayloads(t *testing.T) {
+	codec, err := NewEncryptionCodec("k1", map[string][]byte{"k1": key1, "k2": key2})
+	require.NoError(t, err)
+
+	encoded, err := codec.Encode([]*commonpb.Payload{testPayload(t)})
+	require.NoError(t, err)
+
+	tampered := *encoded[0]
+	tampered.Data = append([]byte{}, encoded[0].Data...)
+	tampered.Data[len(tampered.Data)-1] ^= 1
+	_, err = codec.Decode([]*commonpb.Payload{&tampered})
+	require.Error(t, err)
+
+	relabelled := *encoded[0]
+	relabelled.Metadata = map[string][]byte{
+		converter.MetadataEncoding: []byte(EncodingEncrypted),
+		MetadataKeyID:              []byte("k2"),
+	}
+	_, err = codec.Decode([]*commonpb.Payload{&relabelled})
+	require.Error(t, err)
+}
+
+func TestEncryptionCodec_PassesThroughPlaintext(t *testing.T) {
+	codec, err := NewEncryptionCodec("k1", map[string][]byte{"k1": key1})
+	require.NoError(t, err)
+
+	payload := testPayload(t)
+	decoded, err := codec.Decode([]*commonpb.Payload{payload})
+	require.NoError(t, err)
+	require.Same(t, payload, decoded[0])
+}
+
+func TestNewEncryptionCodec_ValidatesKeys(t *testing.T) {
+	_, err := NewEncryptionCodec("missing", map[string][]byte{"k1": key1})
+	require.Error(t, err)
+
+	_, err = NewEncryptionCodec("k1", map[string][]byte{"k1": []byte("short")})
+	require.Error(t, err)
+}
@@ -0,0 +1,365 @@
+// Package config loads the settings shared by the worker, the API server and
+// the setup tool. Defaults suit a local Temporal dev server; an optional YAML
+// file and then environment variables override them.
+package config
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FileEnvVar names the environment variable holding the optional YAML file.
+const FileEnvVar = "CONFIG_FILE"
+
+// Config holds the settings of every binary.
+type Config struct {
+	Temporal     Temporal     `yaml:"temporal"`
+	Server       Server       `yaml:"server"`
+	Auth         Auth         `yaml:"auth"`
+	CodecServer  CodecServer  `yaml:"codec_server"`
+	CreditBureau CreditBureau `yaml:"credit_bureau"`
+	// Notifications are sent to borrowers by the worker
+	Notifications Notifications `yaml:"notifications"`
+	// Storage is shared by the API server, which stores uploads, and the
+	// worker, which stores generated documents
+	Storage Storage `yaml:"storage"`
+	// PolicyFile is the underwriting policy file read by the API server; the
+	// built-in policy is used when it is empty
+	PolicyFile string `yaml:"policy_file"`
+}
+
+// Temporal holds the connection to the Temporal service.
+type Temporal struct {
+	// HostPort is the frontend address, e.g. my-ns.a1b2c.tmprl.cloud:7233
+	HostPort  string `yaml:"host_port"`
+	Namespace string `yaml:"namespace"`
+	TaskQueue string `yaml:"task_queue"`
+	TLS       TLS    `yaml:"tls"`
+	// APIKey authenticates with Temporal Cloud instead of a client
+	// certificate. It requires TLS, which is enabled automatically.
+	APIKey     string     `yaml:"api_key"`
+	Encryption Encryption `yaml:"encryption"`
+	// SearchKey keys the hash of the borrower email indexed as the
+	// BorrowerEmail search attribute. The email is not indexed without it.
+	SearchKey string `yaml:"search_key"`
+}
+
+// TLS configures the connection's TLS and mTLS. Setting a client certificate
+// or CA enables TLS.
+type TLS struct {
+	Enabled    bool   `yaml:"enabled"`
+	CertFile   string `yaml:"cert_file"`
+	KeyFile    string `yaml:"key_file"`
+	CAFile     string `yaml:"ca_file"`
+	ServerName string `yaml:"server_name"`
+}
+
+// Encryption configures payload encryption. It is off unless KeyID is set.
+// Keys maps key IDs to base64-encoded AES keys; payloads are encrypted with
+// KeyID and older keys stay listed so existing histories can be decoded.
+type Encryption struct {
+	KeyID string            `yaml:"key_id"`
+	Keys  map[string]string `yaml:"keys"`
+}
+
+// Enabled reports whether payloads are encrypted.
+func (e Encryption) Enabled() bool {
+	return e.KeyID != ""
+}
+
+// DecodedKeys returns the keys decoded from base64.
+func (e Encryption) DecodedKeys() (map[string][]byte, error) {
+	keys := make(map[string][]byte, len(e.Keys))
+	for id, encoded := range e.Keys {
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("encryption key %q: %w", id, err)
+		}
+		keys[id] = key
+	}
+	return keys, nil
+}
+
+// CodecServer holds the settings of the codec server used by the Temporal
+// Web UI and CLI to decode encrypted payloads.
+type CodecServer struct {
+	ListenAddress string `yaml:"listen_address"`
+	// AllowedOrigins are the Web UI origins allowed to call the server
+	AllowedOrigins []string `yaml:"allowed_origins"`
+	// RequireAuth only decodes for callers with an API bearer token signed
+	// with Auth.JWTSecret. The Temporal Web UI cannot send such a token, so
+	// it is off by default and the server then only listens on loopback.
+	RequireAuth bool `yaml:"require_auth"`
+}
+
+// CreditBureau selects the bureau the worker pulls credit reports from.
+type CreditBureau struct {
+	// Provider is "simulator" or "http"
+	Provider string `yaml:"provider"`
+	URL      string `yaml:"url"`
+	APIKey   string `yaml:"api_key"`
+	// FailFirst fails the first pulls for each loan to exercise retries
+	FailFirst int `yaml:"fail_first"`
+}
+
+// Notifications configures how the worker emails and texts borrowers.
+type Notifications struct {
+	SMTP SMTP `yaml:"smtp"`
+	SMS  SMS  `yaml:"sms"`
+}
+
+// SMTP is the mail server email is sent through, such as MailHog on
+// localhost:1025 during development. Username and Password are only needed
+// by servers that require authentication.
+type SMTP struct {
+	Address  string `yaml:"address"`
+	From     string `yaml:"from"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+}
+
+// SMS selects the stand-in for an SMS gateway.
+type SMS struct {
+	// Provider is "file", which appends messages to File, or "log", which
+	// only logs them
+	Provider string `yaml:"provider"`
+	File     string `yaml:"file"`
+}
+
+// Storage selects where documents are kept.
+type Storage struct {
+	// Backend is "local" or "s3"
+	Backend  string `yaml:"backend"`
+	LocalDir string `yaml:"local_dir"`
+	S3       S3     `yaml:"s3"`
+}
+
+// S3 is an S3-compatible bucket, such as a local MinIO on localhost:9000.
+// The bucket is created if it does not exist.
+type S3 struct {
+	Endpoint  string `yaml:"endpoint"`
+	Region    string `yaml:"region"`
+	Bucket    string `yaml:"bucket"`
+	AccessKey string `yaml:"access_key"`
+	SecretKey string `yaml:"secret_key"`
+	UseSSL    bool   `yaml:"use_ssl"`
+}
+
+// Server holds the API server settings.
+type Server struct {
+	ListenAddress string `yaml:"listen_address"`
+}
+
+// Auth holds the bearer token settings shared by the API server, which
+// issues tokens, and the codec server, which verifies them.
+type Auth struct {
+	// JWTSecret signs the tokens. The API server uses a random secret per
+	// start when it is empty; the codec server cannot verify tokens then.
+	JWTSecret string `yaml:"jwt_secret"`
+	// DemoPassword is the password of the demo persona users
+	DemoPassword string `yaml:"demo_password"`
+}
+
+// Default returns the settings for a local Temporal dev server.
+func Default() Config {
+	return Config{
+		Temporal: Temporal{
+			HostPort:  "localhost:7233",
+			Namespace: "default",
+			TaskQueue: "loan-origination-task-queue",
+		},
+		Server: Server{
+			ListenAddress: ":8082",
+		},
+		Auth: Auth{
+			DemoPassword: "demo",
+		},
+		CodecServer: CodecServer{
+			ListenAddress:  "127.0.0.1:8081",
+			AllowedOrigins: []string{"http://localhost:8233"},
+		},
+		CreditBureau: CreditBureau{
+			Provider:  "simulator",
+			FailFirst: 2,
+		},
+		Notifications: Notifications{
+			SMTP: SMTP{
+				Address: "localhost:1025",
+				From:    "Loan Origination <loans@example.com>",
+			},
+			SMS: SMS{
+				Provider: "file",
+				File:     "sms.log",
+			},
+		},
+		Storage: Storage{
+			Backend:  "local",
+			LocalDir: "uploads",
+		},
+	}
+}
+
+// Load returns the default settings overridden by the YAML file named in
+// CONFIG_FILE, if any, and then by environment variables.
+func Load() (Config, error) {
+	cfg := Default()
+
+	if path := os.Getenv(FileEnvVar); path != "" {
+		if err := cfg.loadFile(path); err != nil {
+			return Config{}, err
+		}
+	}
+
+	if err := cfg.loadEnv(); err != nil {
+		return Config{}, err
+	}
+
+	return cfg, cfg.Validate()
+}
+
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read config file: %w", err)
+	}
+	if err := yaml.Unmarshal(data, c); err != nil {
+		return fmt.Errorf("parse config file %s: %w", path, err)
+	}
+	return nil
+}
+
+func (c *Config) loadEnv() error {
+	for name, field := range map[string]*string{
+		"TEMPORAL_ADDRESS":            &c.Temporal.HostPort,
+		"TEMPORAL_NAMESPACE":          &c.Temporal.Namespace,
+		"TEMPORAL_TASK_QUEUE":         &c.Temporal.TaskQueue,
+		"TEMPORAL_TLS_CERT":           &c.Temporal.TLS.CertFile,
+		"TEMPORAL_TLS_KEY":            &c.Temporal.TLS.KeyFile,
+		"TEMPORAL_TLS_CA":             &c.Temporal.TLS.CAFile,
+		"TEMPORAL_TLS_SERVER_NAME":    &c.Temporal.TLS.ServerName,
+		"TEMPORAL_API_KEY":            &c.Temporal.APIKey,
+		"TEMPORAL_ENCRYPTION_KEY_ID":  &c.Temporal.Encryption.KeyID,
+		"TEMPORAL_SEARCH_KEY":         &c.Temporal.SearchKey,
+		"LISTEN_ADDRESS":              &c.Server.ListenAddress,
+		"AUTH_JWT_SECRET":             &c.Auth.JWTSecret,
+		"AUTH_DEMO_PASSWORD":          &c.Auth.DemoPassword,
+		"CODEC_SERVER_LISTEN_ADDRESS": &c.CodecServer.ListenAddress,
+		"CREDIT_BUREAU_PROVIDER":      &c.CreditBureau.Provider,
+		"CREDIT_BUREAU_URL":           &c.CreditBureau.URL,
+		"CREDIT_BUREAU_API_KEY":       &c.CreditBureau.APIKey,
+		"SMTP_ADDRESS":                &c.Notifications.SMTP.Address,
+		"SMTP_FROM":                   &c.Notifications.SMTP.From,
+		"SMTP_USERNAME":               &c.Notifications.SMTP.Username,
+		"SMTP_PASSWORD":               &c.Notifications.SMTP.Password,
+		"SMS_PROVIDER":                &c.Notifications.SMS.Provider,
+		"SMS_FILE":                    &c.Notifications.SMS.File,
+		"DOCUMENT_STORAGE":            &c.Storage.Backend,
+		"DOCUMENT_STORAGE_DIR":        &c.Storage.LocalDir,
+		"S3_ENDPOINT":                 &c.Storage.S3.Endpoint,
+		"S3_REGION":                   &c.Storage.S3.Region,
+		"S3_BUCKET":                   &c.Storage.S3.Bucket,
+		"S3_ACCESS_KEY":               &c.Storage.S3.AccessKey,
+		"S3_SECRET_KEY":               &c.Storage.S3.SecretKey,
+		"POLICY_FILE":                 &c.PolicyFile,
+	} {
+		if value, ok := os.LookupEnv(name); ok {
+			*field = value
+		}
+	}
+
+	for name, field := range map[string]*bool{
+		"TEMPORAL_TLS":              &c.Temporal.TLS.Enabled,
+		"CODEC_SERVER_REQUIRE_AUTH": &c.CodecServer.RequireAuth,
+		"S3_USE_SSL":                &c.Storage.S3.UseSSL,
+	} {
+		if value, ok := os.LookupEnv(name); ok {
+			enabled, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			*field = enabled
+		}
+	}
+
+	if value, ok := os.LookupEnv("CREDIT_BUREAU_FAIL_FIRST"); ok {
+		failFirst, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("CREDIT_BUREAU_FAIL_FIRST: %w", err)
+		}
+		c.CreditBureau.FailFirst = failFirst
+	}
+
+	// Keys are listed as id=base64key pairs separated by commas
+	if value, ok := os.LookupEnv("TEMPORAL_ENCRYPTION_KEYS"); ok {
+		c.Temporal.Encryption.Keys = map[string]string{}
+		for _, pair := range strings.Split(value, ",") {
+			id, key, ok := strings.Cut(strings.TrimSpace(pair), "=")
+			if !ok {
+				return errors.New("TEMPORAL_ENCRYPTION_KEYS: expected id=key pairs")
+			}
+			c.Temporal.Encryption.Keys[id] = key
+		}
+	}
+
+	if value, ok := os.LookupEnv("CODEC_SERVER_ALLOWED_ORIGINS"); ok {
+		c.CodecServer.AllowedOrigins = strings.Split(value, ",")
+	}
+	return nil
+}
+
+// Validate reports settings that cannot work together.
+func (c Config) Validate() error {
+	switch {
+	case c.Temporal.HostPort == "":
+		return errors.New("temporal host_port is required")
+	case c.Temporal.Namespace == "":
+		return errors.New("temporal namespace is required")
+	case c.Temporal.TaskQueue == "":
+		return errors.New("temporal task_queue is required")
+	case (c.Temporal.TLS.CertFile == "") != (c.Temporal.TLS.KeyFile == ""):
+		return errors.New("temporal tls cert_file and key_file must be set together")
+	case c.Server.ListenAddress == "":
+		return errors.New("server listen_address is required")
+	case c.Auth.DemoPassword == "":
+		return errors.New("auth demo_password is required")
+	case c.CreditBureau.Provider != "simulator" && c.CreditBureau.Provider != "http":
+		return fmt.Errorf("unknown credit_bureau provider %q", c.CreditBureau.Provider)
+	case c.CreditBureau.Provider == "http" && c.CreditBureau.URL == "":
+		return errors.New("credit_bureau url is required for the http provider")
+	case c.Notifications.SMTP.Address == "" || c.Notifications.SMTP.From == "":
+		return errors.New("notifications smtp address and from are required")
+	case c.Notifications.SMS.Provider != "file" && c.Notifications.SMS.Provider != "log":
+		return fmt.Errorf("unknown notifications sms provider %q", c.Notifications.SMS.Provider)
+	case c.Notifications.SMS.Provider == "file" && c.Notifications.SMS.File == "":
+		return errors.New("notifications sms file is required for the file provider")
+	case c.Storage.Backend != "local" && c.Storage.Backend != "s3":
+		return fmt.Errorf("unknown storage backend %q", c.Storage.Backend)
+	case c.Storage.Backend == "local" && c.Storage.LocalDir == "":
+		return errors.New("storage local_dir is required for the local backend")
+	case c.Storage.Backend == "s3" && (c.Storage.S3.Endpoint == "" || c.Storage.S3.Bucket == ""):
+		return errors.New("storage s3 endpoint and bucket are required for the s3 backend")
+	case c.Storage.Backend == "s3" && (c.Storage.S3.AccessKey == "" || c.Storage.S3.SecretKey == ""):
+		return errors.New("storage s3 access_key and secret_key are required for the s3 backend")
+	}
+
+	if c.Temporal.Encryption.Enabled() {
+		if _, ok := c.Temporal.Encryption.Keys[c.Temporal.Encryption.KeyID]; !ok {
+			return fmt.Errorf("temporal encryption key %q is not listed in keys", c.Temporal.Encryption.KeyID)
+		}
+		if _, err := c.Temporal.Encryption.DecodedKeys(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TLSEnabled reports whether the Temporal connection uses TLS.
+func (t Temporal) TLSEnabled() bool {
+	return t.TLS.Enabled || t.TLS.CertFile != "" || t.TLS.CAFile != "" || t.APIKey != ""
+}
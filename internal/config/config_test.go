@@ -0,0 +1,134 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad_Defaults(t *testing.T) {
+	t.Setenv(FileEnvVar, "")
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	require.Equal(t, Default(), cfg)
+	require.False(t, cfg.Temporal.TLSEnabled())
+	require.False(t, cfg.CodecServer.RequireAuth)
+}
+
+func TestLoad_AuthFromEnv(t *testing.T) {
+	t.Setenv(FileEnvVar, "")
+	t.Setenv("AUTH_JWT_SECRET", "shared-secret")
+	t.Setenv("AUTH_DEMO_PASSWORD", "s3cret")
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	require.Equal(t, "shared-secret", cfg.Auth.JWTSecret)
+	require.Equal(t, "s3cret", cfg.Auth.DemoPassword)
+
+	t.Setenv("AUTH_DEMO_PASSWORD", "")
+	_, err = Load()
+	require.ErrorContains(t, err, "demo_password is required")
+}
+
+func TestLoad_FileThenEnv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+temporal:
+  host_port: staging.example.com:7233
+  namespace: loans-staging
+  tls:
+    cert_file: /certs/client.pem
+    key_file: /certs/client.key
+server:
+  listen_address: ":9090"
+policy_file: policies.yaml
+`), 0o600))
+	t.Setenv(FileEnvVar, path)
+	t.Setenv("TEMPORAL_NAMESPACE", "loans-prod")
+	t.Setenv("TEMPORAL_SEARCH_KEY", "search-secret")
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	require.Equal(t, "staging.example.com:7233", cfg.Temporal.HostPort)
+	require.Equal(t, "loans-prod", cfg.Temporal.Namespace)
+	require.Equal(t, "loan-origination-task-queue", cfg.Temporal.TaskQueue)
+	require.Equal(t, "/certs/client.pem", cfg.Temporal.TLS.CertFile)
+	require.Equal(t, "search-secret", cfg.Temporal.SearchKey)
+	require.True(t, cfg.Temporal.TLSEnabled())
+	require.Equal(t, ":9090", cfg.Server.ListenAddress)
+	require.Equal(t, "policies.yaml", cfg.PolicyFile)
+}
+
+func TestLoad_RejectsInvalidSettings(t *testing.T) {
+	t.Setenv(FileEnvVar, "")
+	t.Setenv("TEMPORAL_TLS_CERT", "/certs/client.pem")
+
+	_, err := Load()
+	require.ErrorContains(t, err, "cert_file and key_file")
+
+	t.Setenv("TEMPORAL_TLS_CERT", "")
+	t.Setenv("TEMPORAL_TLS", "maybe")
+	_, err = Load()
+	require.ErrorContains(t, err, "TEMPORAL_TLS")
+
+	t.Setenv("TEMPORAL_TLS", "false")
+	t.Setenv("SMS_PROVIDER", "pager")
+	_, err = Load()
+	require.ErrorContains(t, err, `unknown notifications sms provider "pager"`)
+}
+
+func TestLoad_StorageFromEnv(t *testing.T) {
+	t.Setenv(FileEnvVar, "")
+	t.Setenv("DOCUMENT_STORAGE", "s3")
+	t.Setenv("S3_ENDPOINT", "localhost:9000")
+	t.Setenv("S3_BUCKET", "loan-documents")
+	t.Setenv("S3_ACCESS_KEY", "minioadmin")
+	t.Setenv("S3_SECRET_KEY", "minioadmin")
+	t.Setenv("S3_USE_SSL", "true")
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	require.Equal(t, "s3", cfg.Storage.Backend)
+	require.Equal(t, "loan-documents", cfg.Storage.S3.Bucket)
+	require.True(t, cfg.Storage.S3.UseSSL)
+
+	t.Setenv("S3_SECRET_KEY", "")
+	_, err = Load()
+	require.ErrorContains(t, err, "access_key and secret_key are required")
+
+	t.Setenv("S3_BUCKET", "")
+	_, err = Load()
+	require.ErrorContains(t, err, "endpoint and bucket are required")
+
+	t.Setenv("DOCUMENT_STORAGE", "floppy")
+	_, err = Load()
+	require.ErrorContains(t, err, `unknown storage backend "floppy"`)
+}
+
+func TestTLSEnabled_ByAPIKey(t *testing.T) {
+	cfg := Default()
+	cfg.Temporal.APIKey = "key"
+	require.True(t, cfg.Temporal.TLSEnabled())
+}
+
+func TestLoad_EncryptionKeysFromEnv(t *testing.T) {
+	t.Setenv(FileEnvVar, "")
+	t.Setenv("TEMPORAL_ENCRYPTION_KEY_ID", "2024-06")
+	t.Setenv("TEMPORAL_ENCRYPTION_KEYS", "2024-01=AQEBAQEBAQEBAQEBAQEBAQ==, 2024-06=AgICAgICAgICAgICAgICAg==")
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	require.True(t, cfg.Temporal.Encryption.Enabled())
+
+	keys, err := cfg.Temporal.Encryption.DecodedKeys()
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	require.Len(t, keys["2024-06"], 16)
+
+	t.Setenv("TEMPORAL_ENCRYPTION_KEY_ID", "2025-01")
+	_, err = Load()
+	require.ErrorContains(t, err, `"2025-01" is not listed`)
+}
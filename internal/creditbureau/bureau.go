@@ -0,0 +1,122 @@
+// Package creditbureau pulls borrower credit reports from a credit bureau.
+// CreditScoreCheck talks to a CreditBureau, which is a deterministic
+// simulator by default or an HTTP bureau API in other environments.
+package creditbureau
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"loan-origination-system/internal/config"
+)
+
+// Scores are on the FICO scale.
+const (
+	MinScore = 300
+	MaxScore = 850
+)
+
+var (
+	// ErrBorrowerNotFound is returned when the bureau has no file for the
+	// borrower. Retrying does not help.
+	ErrBorrowerNotFound = errors.New("borrower not found at credit bureau")
+	// ErrInvalidReport is returned for reports that cannot be used.
+	ErrInvalidReport = errors.New("invalid credit report")
+)
+
+// PullType is the kind of credit inquiry. Soft pulls do not affect the
+// borrower's score; hard pulls are needed to underwrite a loan.
+type PullType string
+
+const (
+	PullSoft PullType = "soft"
+	PullHard PullType = "hard"
+)
+
+// Request asks a bureau for a borrower's credit report.
+type Request struct {
+	LoanApplicationID string   `json:"loan_application_id"`
+	BorrowerName      string   `json:"borrower_name"`
+	BorrowerEmail     string   `json:"borrower_email"`
+	PullType          PullType `json:"pull_type"`
+}
+
+// Tradeline is one credit account on a report.
+type Tradeline struct {
+	Creditor       string    `json:"creditor"`
+	AccountType    string    `json:"account_type"`
+	Status         string    `json:"status"`
+	Balance        float64   `json:"balance"`
+	CreditLimit    float64   `json:"credit_limit"`
+	MonthlyPayment float64   `json:"monthly_payment"`
+	LatePayments   int       `json:"late_payments"`
+	OpenedAt       time.Time `json:"opened_at"`
+}
+
+// Report is a borrower's credit report.
+type Report struct {
+	Bureau     string      `json:"bureau"`
+	Reference  string      `json:"reference"`
+	PullType   PullType    `json:"pull_type"`
+	Score      int         `json:"score"`
+	ScoreModel string      `json:"score_model"`
+	Tradelines []Tradeline `json:"tradelines"`
+	Inquiries  int         `json:"inquiries"`
+	PulledAt   time.Time   `json:"pulled_at"`
+}
+
+// MonthlyDebtPayments is the sum of the monthly payments of open tradelines.
+func (r Report) MonthlyDebtPayments() float64 {
+	var total float64
+	for _, tradeline := range r.Tradelines {
+		if tradeline.Status != "closed" {
+			total += tradeline.MonthlyPayment
+		}
+	}
+	return total
+}
+
+// Validate reports a report that is unusable for underwriting.
+func (r Report) Validate() error {
+	if r.Score < MinScore || r.Score > MaxScore {
+		return fmt.Errorf("%w: score %d outside %d-%d", ErrInvalidReport, r.Score, MinScore, MaxScore)
+	}
+	if r.Reference == "" {
+		return fmt.Errorf("%w: no reference from %s", ErrInvalidReport, r.Bureau)
+	}
+	return nil
+}
+
+// IsPermanent reports whether a pull failed in a way retrying cannot fix.
+func IsPermanent(err error) bool {
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.Permanent()
+	}
+	return errors.Is(err, ErrBorrowerNotFound) || errors.Is(err, ErrInvalidReport)
+}
+
+// CreditBureau pulls credit reports.
+type CreditBureau interface {
+	PullReport(ctx context.Context, req Request) (*Report, error)
+}
+
+// New returns the bureau selected by the configuration.
+func New(cfg config.CreditBureau) (CreditBureau, error) {
+	var bureau CreditBureau
+	switch cfg.Provider {
+	case "simulator":
+		bureau = Simulator{Name: "Simulated Bureau"}
+	case "http":
+		bureau = &HTTPBureau{BaseURL: cfg.URL, APIKey: cfg.APIKey}
+	default:
+		return nil, fmt.Errorf("unknown credit bureau provider %q", cfg.Provider)
+	}
+
+	if cfg.FailFirst > 0 {
+		bureau = &FailingBureau{Bureau: bureau, FailFirst: cfg.FailFirst}
+	}
+	return bureau, nil
+}
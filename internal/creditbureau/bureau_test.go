@@ -0,0 +1,119 @@
+package creditbureau
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"loan-origination-system/internal/config"
+
+	"github.com/stretchr/testify/require"
+)
+
+var testRequest = Request{
+	LoanApplicationID: "loan-1",
+	BorrowerName:      "Jane Doe",
+	BorrowerEmail:     "jane@example.com",
+	PullType:          PullHard,
+}
+
+func fixedNow() time.Time {
+	return time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+}
+
+func TestSimulator_IsDeterministic(t *testing.T) {
+	bureau := Simulator{Name: "Test Bureau", Now: fixedNow}
+
+	first, err := bureau.PullReport(context.Background(), testRequest)
+	require.NoError(t, err)
+	second, err := bureau.PullReport(context.Background(), testRequest)
+	require.NoError(t, err)
+
+	require.Equal(t, first, second)
+	require.NoError(t, first.Validate())
+	require.NotEmpty(t, first.Tradelines)
+	require.Equal(t, PullHard, first.PullType)
+
+	other := testRequest
+	other.BorrowerEmail = "john@example.com"
+	third, err := bureau.PullReport(context.Background(), other)
+	require.NoError(t, err)
+	require.NotEqual(t, first.Reference, third.Reference)
+}
+
+func TestSimulator_UnknownBorrower(t *testing.T) {
+	_, err := Simulator{}.PullReport(context.Background(), Request{LoanApplicationID: "loan-1"})
+	require.ErrorIs(t, err, ErrBorrowerNotFound)
+	require.True(t, IsPermanent(err))
+}
+
+func TestFailingBureau_FailsFirstAttemptsPerLoan(t *testing.T) {
+	bureau := &FailingBureau{Bureau: Simulator{Now: fixedNow}, FailFirst: 2}
+
+	for i := 0; i < 2; i++ {
+		_, err := bureau.PullReport(context.Background(), testRequest)
+		require.Error(t, err)
+		require.False(t, IsPermanent(err))
+	}
+	_, err := bureau.PullReport(context.Background(), testRequest)
+	require.NoError(t, err)
+
+	other := testRequest
+	other.LoanApplicationID = "loan-2"
+	_, err = bureau.PullReport(context.Background(), other)
+	require.Error(t, err)
+}
+
+func TestHTTPBureau_AgainstStubHandler(t *testing.T) {
+	simulator := Simulator{Name: "Stub Bureau", Now: fixedNow}
+	server := httptest.NewServer(NewHandler(simulator, "secret"))
+	defer server.Close()
+
+	bureau := &HTTPBureau{BaseURL: server.URL, APIKey: "secret"}
+	got, err := bureau.PullReport(context.Background(), testRequest)
+	require.NoError(t, err)
+
+	want, err := simulator.PullReport(context.Background(), testRequest)
+	require.NoError(t, err)
+	require.Equal(t, want.Reference, got.Reference)
+	require.Equal(t, want.Score, got.Score)
+	require.Equal(t, want.MonthlyDebtPayments(), got.MonthlyDebtPayments())
+
+	_, err = bureau.PullReport(context.Background(), Request{LoanApplicationID: "loan-1", PullType: PullHard})
+	require.ErrorIs(t, err, ErrBorrowerNotFound)
+
+	bureau.APIKey = "wrong"
+	_, err = bureau.PullReport(context.Background(), testRequest)
+	var statusErr *StatusError
+	require.ErrorAs(t, err, &statusErr)
+	require.Equal(t, 401, statusErr.StatusCode)
+	require.True(t, IsPermanent(err))
+}
+
+func TestHTTPBureau_OutageIsRetryable(t *testing.T) {
+	failing := &FailingBureau{Bureau: Simulator{Now: fixedNow}, FailFirst: 1}
+	server := httptest.NewServer(NewHandler(failing, ""))
+	defer server.Close()
+
+	bureau := &HTTPBureau{BaseURL: server.URL}
+	_, err := bureau.PullReport(context.Background(), testRequest)
+	require.Error(t, err)
+	require.False(t, IsPermanent(err))
+
+	_, err = bureau.PullReport(context.Background(), testRequest)
+	require.NoError(t, err)
+}
+
+func TestNew_SelectsProvider(t *testing.T) {
+	bureau, err := New(config.CreditBureau{Provider: "simulator"})
+	require.NoError(t, err)
+	require.IsType(t, Simulator{}, bureau)
+
+	bureau, err = New(config.CreditBureau{Provider: "http", URL: "http://localhost:8083", FailFirst: 2})
+	require.NoError(t, err)
+	require.IsType(t, &FailingBureau{}, bureau)
+
+	_, err = New(config.CreditBureau{Provider: "carrier-pigeon"})
+	require.Error(t, err)
+}
@@ -0,0 +1,33 @@
+package creditbureau
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// FailingBureau fails the first FailFirst pulls for each loan application
+// and then delegates to Bureau. It stands in for a bureau with transient
+// outages so retries can be demonstrated and tested.
+type FailingBureau struct {
+	Bureau    CreditBureau
+	FailFirst int
+
+	mu       sync.Mutex
+	attempts map[string]int
+}
+
+func (f *FailingBureau) PullReport(ctx context.Context, req Request) (*Report, error) {
+	f.mu.Lock()
+	if f.attempts == nil {
+		f.attempts = make(map[string]int)
+	}
+	f.attempts[req.LoanApplicationID]++
+	attempt := f.attempts[req.LoanApplicationID]
+	f.mu.Unlock()
+
+	if attempt <= f.FailFirst {
+		return nil, fmt.Errorf("credit bureau temporarily unavailable (attempt %d/%d)", attempt, f.FailFirst+1)
+	}
+	return f.Bureau.PullReport(ctx, req)
+}
@@ -0,0 +1,196 @@
+package creditbureau
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// reportsPath is the endpoint of the bureau API that pulls a report.
+const reportsPath = "/v1/credit-reports"
+
+// Wire format of the bureau API, shared by HTTPBureau and NewHandler.
+type (
+	apiConsumer struct {
+		Name  string `json:"name"`
+		Email string `json:"email"`
+	}
+
+	apiReportRequest struct {
+		ReferenceID string      `json:"reference_id"`
+		Consumer    apiConsumer `json:"consumer"`
+		PullType    PullType    `json:"pull_type"`
+	}
+
+	apiScore struct {
+		Value int    `json:"value"`
+		Model string `json:"model"`
+	}
+
+	apiReportResponse struct {
+		Bureau      string      `json:"bureau"`
+		ReportID    string      `json:"report_id"`
+		PullType    PullType    `json:"pull_type"`
+		Score       apiScore    `json:"score"`
+		Tradelines  []Tradeline `json:"tradelines"`
+		Inquiries   int         `json:"inquiries"`
+		GeneratedAt time.Time   `json:"generated_at"`
+	}
+
+	apiError struct {
+		Error string `json:"error"`
+	}
+)
+
+// StatusError is an error response from the bureau API.
+type StatusError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("credit bureau returned %d: %s", e.StatusCode, e.Message)
+}
+
+// Permanent reports whether the request is rejected whatever the retry:
+// client errors other than timeouts and rate limits.
+func (e *StatusError) Permanent() bool {
+	return e.StatusCode >= 400 && e.StatusCode < 500 &&
+		e.StatusCode != http.StatusRequestTimeout &&
+		e.StatusCode != http.StatusTooManyRequests
+}
+
+// HTTPBureau pulls reports from a bureau-style REST API, such as the stub
+// served by cmd/credit-bureau-stub.
+type HTTPBureau struct {
+	BaseURL string
+	APIKey  string
+	// Client defaults to a client with a 10 second timeout
+	Client *http.Client
+}
+
+func (b *HTTPBureau) PullReport(ctx context.Context, req Request) (*Report, error) {
+	body, err := json.Marshal(apiReportRequest{
+		ReferenceID: req.LoanApplicationID,
+		Consumer:    apiConsumer{Name: req.BorrowerName, Email: req.BorrowerEmail},
+		PullType:    req.PullType,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(b.BaseURL, "/")+reportsPath, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if b.APIKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+b.APIKey)
+	}
+
+	client := b.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("call credit bureau: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrBorrowerNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		var apiErr apiError
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		if json.Unmarshal(data, &apiErr) != nil || apiErr.Error == "" {
+			apiErr.Error = strings.TrimSpace(string(data))
+		}
+		return nil, &StatusError{StatusCode: resp.StatusCode, Message: apiErr.Error}
+	}
+
+	var apiResp apiReportResponse
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidReport, err)
+	}
+
+	report := &Report{
+		Bureau:     apiResp.Bureau,
+		Reference:  apiResp.ReportID,
+		PullType:   apiResp.PullType,
+		Score:      apiResp.Score.Value,
+		ScoreModel: apiResp.Score.Model,
+		Tradelines: apiResp.Tradelines,
+		Inquiries:  apiResp.Inquiries,
+		PulledAt:   apiResp.GeneratedAt,
+	}
+	if err := report.Validate(); err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// NewHandler serves the bureau API backed by bureau, for local development
+// and tests. Requests must carry apiKey as a bearer token when it is set.
+func NewHandler(bureau CreditBureau, apiKey string) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(reportsPath, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeJSON(w, http.StatusMethodNotAllowed, apiError{Error: "method not allowed"})
+			return
+		}
+		if apiKey != "" && r.Header.Get("Authorization") != "Bearer "+apiKey {
+			writeJSON(w, http.StatusUnauthorized, apiError{Error: "invalid API key"})
+			return
+		}
+
+		var req apiReportRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, apiError{Error: err.Error()})
+			return
+		}
+		if req.PullType != PullSoft && req.PullType != PullHard {
+			writeJSON(w, http.StatusBadRequest, apiError{Error: "pull_type must be soft or hard"})
+			return
+		}
+
+		report, err := bureau.PullReport(r.Context(), Request{
+			LoanApplicationID: req.ReferenceID,
+			BorrowerName:      req.Consumer.Name,
+			BorrowerEmail:     req.Consumer.Email,
+			PullType:          req.PullType,
+		})
+		if errors.Is(err, ErrBorrowerNotFound) {
+			writeJSON(w, http.StatusNotFound, apiError{Error: err.Error()})
+			return
+		}
+		if err != nil {
+			writeJSON(w, http.StatusServiceUnavailable, apiError{Error: err.Error()})
+			return
+		}
+
+		writeJSON(w, http.StatusOK, apiReportResponse{
+			Bureau:      report.Bureau,
+			ReportID:    report.Reference,
+			PullType:    report.PullType,
+			Score:       apiScore{Value: report.Score, Model: report.ScoreModel},
+			Tradelines:  report.Tradelines,
+			Inquiries:   report.Inquiries,
+			GeneratedAt: report.PulledAt,
+		})
+	})
+	return mux
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
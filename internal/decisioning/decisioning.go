@@ -0,0 +1,256 @@
+// Package decisioning computes the automated underwriting recommendation for
+// a loan: its loan-to-value and debt-to-income ratios and credit tier,
+// checked against a policy. The underwriter still makes the final decision.
+package decisioning
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Recommendation is the decision the policy suggests to the underwriter.
+type Recommendation string
+
+const (
+	Approve Recommendation = "approve"
+	Refer   Recommendation = "refer"
+	Decline Recommendation = "decline"
+)
+
+// severity orders recommendations from best to worst.
+func (r Recommendation) severity() int {
+	switch r {
+	case Decline:
+		return 2
+	case Refer:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// CreditTier buckets credit scores the way rate sheets do.
+type CreditTier string
+
+const (
+	TierExcellent   CreditTier = "excellent"
+	TierGood        CreditTier = "good"
+	TierFair        CreditTier = "fair"
+	TierPoor        CreditTier = "poor"
+	TierUnavailable CreditTier = "unavailable"
+)
+
+// TierFor returns the credit tier of a FICO score.
+func TierFor(score int) CreditTier {
+	switch {
+	case score >= 740:
+		return TierExcellent
+	case score >= 670:
+		return TierGood
+	case score >= 580:
+		return TierFair
+	default:
+		return TierPoor
+	}
+}
+
+// ReasonCode identifies a policy finding that moved the recommendation away
+// from approve.
+type ReasonCode string
+
+const (
+	ReasonLTVAboveMaximum         ReasonCode = "LTV_ABOVE_MAXIMUM"
+	ReasonLTVAboveThreshold       ReasonCode = "LTV_ABOVE_THRESHOLD"
+	ReasonDTIAboveMaximum         ReasonCode = "DTI_ABOVE_MAXIMUM"
+	ReasonDTIAboveThreshold       ReasonCode = "DTI_ABOVE_THRESHOLD"
+	ReasonCreditScoreBelowMinimum ReasonCode = "CREDIT_SCORE_BELOW_MINIMUM"
+	ReasonCreditScoreBelowTarget  ReasonCode = "CREDIT_SCORE_BELOW_THRESHOLD"
+	ReasonCreditReportUnavailable ReasonCode = "CREDIT_REPORT_UNAVAILABLE"
+	ReasonCollateralValueMissing  ReasonCode = "COLLATERAL_VALUE_MISSING"
+	ReasonLienCheckUnavailable    ReasonCode = "LIEN_CHECK_UNAVAILABLE"
+	ReasonIncomeNotProvided       ReasonCode = "INCOME_NOT_PROVIDED"
+	// ReasonDecisioningFailed is recorded by the workflow when the
+	// evaluation itself could not run
+	ReasonDecisioningFailed ReasonCode = "DECISIONING_FAILED"
+)
+
+// Reason is a policy finding with the outcome it calls for.
+type Reason struct {
+	Code    ReasonCode     `json:"code"`
+	Outcome Recommendation `json:"outcome"`
+	Message string         `json:"message"`
+}
+
+// Policy holds the thresholds loans are checked against. A value past a
+// Refer threshold sends the loan to manual review; past a Max or Min limit
+// it is declined. Ratios are fractions, so 0.8 is 80%. A MaxLTV of zero
+// skips the LTV check, for loans without collateral.
+type Policy struct {
+	MinCreditScore   int     `yaml:"min_credit_score" json:"min_credit_score"`
+	ReferCreditScore int     `yaml:"refer_credit_score" json:"refer_credit_score"`
+	MaxLTV           float64 `yaml:"max_ltv" json:"max_ltv"`
+	ReferLTV         float64 `yaml:"refer_ltv" json:"refer_ltv"`
+	MaxDTI           float64 `yaml:"max_dti" json:"max_dti"`
+	ReferDTI         float64 `yaml:"refer_dti" json:"refer_dti"`
+	// RequireIncome refers loans whose DTI cannot be computed
+	RequireIncome bool `yaml:"require_income" json:"require_income"`
+	// The new loan's monthly payment is estimated from this annual rate
+	// and term until pricing is known
+	AssumedRate       float64 `yaml:"assumed_rate" json:"assumed_rate"`
+	AssumedTermMonths int     `yaml:"assumed_term_months" json:"assumed_term_months"`
+}
+
+// DefaultPolicy returns thresholds close to a conforming mortgage.
+func DefaultPolicy() Policy {
+	return Policy{
+		MinCreditScore:    580,
+		ReferCreditScore:  660,
+		MaxLTV:            0.97,
+		ReferLTV:          0.80,
+		MaxDTI:            0.50,
+		ReferDTI:          0.43,
+		AssumedRate:       0.07,
+		AssumedTermMonths: 360,
+	}
+}
+
+// Validate reports thresholds that contradict each other.
+func (p Policy) Validate() error {
+	switch {
+	case p.ReferCreditScore < p.MinCreditScore:
+		return errors.New("refer_credit_score must not be below min_credit_score")
+	case p.MaxLTV < 0 || p.ReferLTV > p.MaxLTV:
+		return errors.New("max_ltv must not be negative and must be at least refer_ltv")
+	case p.MaxDTI <= 0 || p.ReferDTI > p.MaxDTI:
+		return errors.New("max_dti must be positive and at least refer_dti")
+	case p.AssumedRate < 0 || p.AssumedTermMonths <= 0:
+		return errors.New("assumed_rate must not be negative and assumed_term_months must be positive")
+	}
+	return nil
+}
+
+// Application is what the policy is evaluated on.
+type Application struct {
+	LoanAmount float64 `json:"loan_amount"`
+	// CollateralValue is the appraised property or vehicle value
+	CollateralValue float64 `json:"collateral_value"`
+	// ExistingLienBalance is owed on liens ahead of this loan, which makes
+	// LTV a combined LTV
+	ExistingLienBalance float64 `json:"existing_lien_balance"`
+	// LiensUnknown is true when liens had to be checked and could not be
+	LiensUnknown bool `json:"liens_unknown"`
+	// CreditReportAvailable is false when the credit check failed
+	CreditReportAvailable bool    `json:"credit_report_available"`
+	CreditScore           int     `json:"credit_score"`
+	MonthlyIncome         float64 `json:"monthly_income"`
+	// MonthlyDebtPayments are the borrower's existing obligations from the
+	// credit report
+	MonthlyDebtPayments float64 `json:"monthly_debt_payments"`
+}
+
+// Result is the computed metrics and the recommendation they lead to.
+type Result struct {
+	Recommendation Recommendation `json:"recommendation"`
+	Reasons        []Reason       `json:"reasons"`
+	LTV            float64        `json:"ltv"`
+	// DTI is nil until the borrower's income is known
+	DTI                     *float64   `json:"dti"`
+	CreditTier              CreditTier `json:"credit_tier"`
+	EstimatedMonthlyPayment float64    `json:"estimated_monthly_payment"`
+}
+
+// Evaluate computes the metrics of app and checks them against the policy.
+// The recommendation is the most severe outcome among the findings.
+func (p Policy) Evaluate(app Application) Result {
+	result := Result{
+		Recommendation:          Approve,
+		Reasons:                 []Reason{},
+		EstimatedMonthlyPayment: p.MonthlyPayment(app.LoanAmount),
+	}
+	add := func(code ReasonCode, outcome Recommendation, format string, args ...interface{}) {
+		result.Reasons = append(result.Reasons, Reason{Code: code, Outcome: outcome, Message: fmt.Sprintf(format, args...)})
+		if outcome.severity() > result.Recommendation.severity() {
+			result.Recommendation = outcome
+		}
+	}
+
+	// Loan to value
+	switch {
+	case p.MaxLTV == 0:
+		// Unsecured
+	case app.CollateralValue > 0:
+		result.LTV = round((app.LoanAmount + app.ExistingLienBalance) / app.CollateralValue)
+		switch {
+		case result.LTV > p.MaxLTV:
+			add(ReasonLTVAboveMaximum, Decline, "LTV %s exceeds the maximum of %s", percent(result.LTV), percent(p.MaxLTV))
+		case result.LTV > p.ReferLTV:
+			add(ReasonLTVAboveThreshold, Refer, "LTV %s exceeds %s", percent(result.LTV), percent(p.ReferLTV))
+		}
+	default:
+		add(ReasonCollateralValueMissing, Refer, "No collateral value to compute LTV")
+	}
+	if p.MaxLTV > 0 && app.LiensUnknown {
+		add(ReasonLienCheckUnavailable, Refer, "Existing liens could not be checked")
+	}
+
+	// Debt to income, including the new loan's payment
+	if app.MonthlyIncome > 0 {
+		dti := round((app.MonthlyDebtPayments + result.EstimatedMonthlyPayment) / app.MonthlyIncome)
+		result.DTI = &dti
+		switch {
+		case dti > p.MaxDTI:
+			add(ReasonDTIAboveMaximum, Decline, "DTI %s exceeds the maximum of %s", percent(dti), percent(p.MaxDTI))
+		case dti > p.ReferDTI:
+			add(ReasonDTIAboveThreshold, Refer, "DTI %s exceeds %s", percent(dti), percent(p.ReferDTI))
+		}
+	} else if p.RequireIncome {
+		add(ReasonIncomeNotProvided, Refer, "No income provided to compute DTI")
+	}
+
+	// Credit
+	if app.CreditReportAvailable {
+		result.CreditTier = TierFor(app.CreditScore)
+		switch {
+		case app.CreditScore < p.MinCreditScore:
+			add(ReasonCreditScoreBelowMinimum, Decline, "Credit score %d is below the minimum of %d", app.CreditScore, p.MinCreditScore)
+		case app.CreditScore < p.ReferCreditScore:
+			add(ReasonCreditScoreBelowTarget, Refer, "Credit score %d is below %d", app.CreditScore, p.ReferCreditScore)
+		}
+	} else {
+		result.CreditTier = TierUnavailable
+		add(ReasonCreditReportUnavailable, Refer, "Credit report could not be pulled")
+	}
+
+	return result
+}
+
+// MonthlyPayment estimates the amortized monthly payment of a loan at the
+// policy's assumed rate and term.
+func (p Policy) MonthlyPayment(amount float64) float64 {
+	return MonthlyPayment(amount, p.AssumedRate, p.AssumedTermMonths)
+}
+
+// MonthlyPayment returns the amortized monthly payment of a loan at an
+// annual rate over a term in months, rounded to cents.
+func MonthlyPayment(amount, rate float64, termMonths int) float64 {
+	if termMonths <= 0 {
+		return 0
+	}
+	n := float64(termMonths)
+	r := rate / 12
+	if r == 0 {
+		return math.Round(amount/n*100) / 100
+	}
+	return math.Round(amount*r/(1-math.Pow(1+r, -n))*100) / 100
+}
+
+// round keeps ratios to four decimal places so they compare and display
+// predictably.
+func round(ratio float64) float64 {
+	return math.Round(ratio*10000) / 10000
+}
+
+func percent(ratio float64) string {
+	return fmt.Sprintf("%.1f%%", ratio*100)
+}
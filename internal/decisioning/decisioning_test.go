@@ -0,0 +1,116 @@
+package decisioning
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func strongApplication() Application {
+	return Application{
+		LoanAmount:            240000,
+		CollateralValue:       300000,
+		CreditReportAvailable: true,
+		CreditScore:           760,
+		MonthlyIncome:         12000,
+		MonthlyDebtPayments:   400,
+	}
+}
+
+func reasonCodes(result Result) []ReasonCode {
+	codes := []ReasonCode{}
+	for _, reason := range result.Reasons {
+		codes = append(codes, reason.Code)
+	}
+	return codes
+}
+
+func TestEvaluate_Approve(t *testing.T) {
+	result := DefaultPolicy().Evaluate(strongApplication())
+
+	require.Equal(t, Approve, result.Recommendation)
+	require.Empty(t, result.Reasons)
+	require.Equal(t, 0.8, result.LTV)
+	require.Equal(t, TierExcellent, result.CreditTier)
+	require.InDelta(t, 1596.73, result.EstimatedMonthlyPayment, 0.01)
+	require.NotNil(t, result.DTI)
+	require.InDelta(t, (400+1596.73)/12000, *result.DTI, 0.0001)
+}
+
+func TestEvaluate_ReferAndDeclineThresholds(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(*Application)
+		want   Recommendation
+		codes  []ReasonCode
+	}{
+		{"high LTV", func(a *Application) { a.LoanAmount = 270000 }, Refer, []ReasonCode{ReasonLTVAboveThreshold}},
+		{"LTV over maximum", func(a *Application) { a.LoanAmount = 295000 }, Decline, []ReasonCode{ReasonLTVAboveMaximum}},
+		{"fair credit", func(a *Application) { a.CreditScore = 640 }, Refer, []ReasonCode{ReasonCreditScoreBelowTarget}},
+		{"poor credit", func(a *Application) { a.CreditScore = 540 }, Decline, []ReasonCode{ReasonCreditScoreBelowMinimum}},
+		{"no credit report", func(a *Application) { a.CreditReportAvailable = false }, Refer, []ReasonCode{ReasonCreditReportUnavailable}},
+		{"high DTI", func(a *Application) { a.MonthlyDebtPayments = 3700 }, Refer, []ReasonCode{ReasonDTIAboveThreshold}},
+		{"DTI over maximum", func(a *Application) { a.MonthlyIncome = 3000 }, Decline, []ReasonCode{ReasonDTIAboveMaximum}},
+		{"worst finding wins", func(a *Application) { a.LoanAmount = 270000; a.CreditScore = 540 }, Decline, []ReasonCode{ReasonLTVAboveThreshold, ReasonCreditScoreBelowMinimum}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := strongApplication()
+			tt.change(&app)
+			result := DefaultPolicy().Evaluate(app)
+			require.Equal(t, tt.want, result.Recommendation)
+			require.Equal(t, tt.codes, reasonCodes(result))
+		})
+	}
+}
+
+func TestEvaluate_CombinedLTV(t *testing.T) {
+	app := strongApplication()
+	app.LoanAmount = 50000
+	app.ExistingLienBalance = 190000
+
+	result := DefaultPolicy().Evaluate(app)
+	require.Equal(t, 0.8, result.LTV)
+	require.Equal(t, Approve, result.Recommendation)
+
+	app.LiensUnknown = true
+	result = DefaultPolicy().Evaluate(app)
+	require.Equal(t, []ReasonCode{ReasonLienCheckUnavailable}, reasonCodes(result))
+}
+
+func TestEvaluate_WithoutIncome(t *testing.T) {
+	app := strongApplication()
+	app.MonthlyIncome = 0
+
+	result := DefaultPolicy().Evaluate(app)
+	require.Equal(t, Approve, result.Recommendation)
+	require.Nil(t, result.DTI)
+
+	policy := DefaultPolicy()
+	policy.RequireIncome = true
+	result = policy.Evaluate(app)
+	require.Equal(t, Refer, result.Recommendation)
+	require.Equal(t, []ReasonCode{ReasonIncomeNotProvided}, reasonCodes(result))
+}
+
+func TestEvaluate_UnsecuredSkipsLTV(t *testing.T) {
+	app := strongApplication()
+	app.CollateralValue = 0
+
+	policy := DefaultPolicy()
+	require.Equal(t, []ReasonCode{ReasonCollateralValueMissing}, reasonCodes(policy.Evaluate(app)))
+
+	policy.MaxLTV, policy.ReferLTV = 0, 0
+	result := policy.Evaluate(app)
+	require.Equal(t, Approve, result.Recommendation)
+	require.Zero(t, result.LTV)
+}
+
+func TestPolicy_Validate(t *testing.T) {
+	require.NoError(t, DefaultPolicy().Validate())
+
+	policy := DefaultPolicy()
+	policy.ReferLTV = 0.99
+	require.Error(t, policy.Validate())
+}
@@ -0,0 +1,153 @@
+// Package events fans projected loan changes out to live subscribers, such
+// as the API server's Server-Sent Events streams.
+package events
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"loan-origination-system/internal/projection"
+)
+
+const (
+	// batchSize is the most changes read from the source per query.
+	batchSize = 100
+	// subscriberBuffer is how many changes a subscriber may fall behind
+	// before it is dropped.
+	subscriberBuffer = 64
+)
+
+// Source is the projection the broker follows.
+type Source interface {
+	LatestSequence(ctx context.Context) (int64, error)
+	ChangesSince(ctx context.Context, sequence int64, loanID string, limit int) ([]projection.LoanChange, error)
+}
+
+// Broker polls the projection for loan changes and delivers them to its
+// subscribers. A single broker serves every stream, so the projection is read
+// once per interval however many clients are listening.
+type Broker struct {
+	source   Source
+	interval time.Duration
+
+	mu          sync.Mutex
+	subscribers map[*Subscription]struct{}
+}
+
+// Subscription receives the changes of one loan, or of every loan.
+type Subscription struct {
+	loanID string
+	events chan projection.LoanChange
+}
+
+// Events delivers changes in sequence order. It is closed when the
+// subscription ends, including when the subscriber falls too far behind.
+func (s *Subscription) Events() <-chan projection.LoanChange {
+	return s.events
+}
+
+func NewBroker(source Source, interval time.Duration) *Broker {
+	return &Broker{
+		source:      source,
+		interval:    interval,
+		subscribers: make(map[*Subscription]struct{}),
+	}
+}
+
+// Subscribe starts delivering the changes of loanID, or of every loan if
+// loanID is empty, that the broker reads from now on.
+func (b *Broker) Subscribe(loanID string) *Subscription {
+	sub := &Subscription{
+		loanID: loanID,
+		events: make(chan projection.LoanChange, subscriberBuffer),
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subscribers[sub] = struct{}{}
+	return sub
+}
+
+// Unsubscribe ends the subscription. It is safe to call more than once.
+func (b *Broker) Unsubscribe(sub *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.remove(sub)
+}
+
+// Run polls the source until ctx is done.
+func (b *Broker) Run(ctx context.Context) error {
+	cursor, err := b.source.LatestSequence(ctx)
+	if err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			b.closeAll()
+			return nil
+		case <-ticker.C:
+			cursor, err = b.poll(ctx, cursor)
+			if err != nil && ctx.Err() == nil {
+				log.Println("Failed to read loan changes:", err)
+			}
+		}
+	}
+}
+
+// poll publishes every change after cursor and returns the new cursor.
+func (b *Broker) poll(ctx context.Context, cursor int64) (int64, error) {
+	for {
+		changes, err := b.source.ChangesSince(ctx, cursor, "", batchSize)
+		if err != nil {
+			return cursor, err
+		}
+		for _, change := range changes {
+			b.publish(change)
+			cursor = change.Sequence
+		}
+		if len(changes) < batchSize {
+			return cursor, nil
+		}
+	}
+}
+
+func (b *Broker) publish(change projection.LoanChange) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for sub := range b.subscribers {
+		if sub.loanID != "" && sub.loanID != change.State.LoanApplication.ID {
+			continue
+		}
+		select {
+		case sub.events <- change:
+		default:
+			// Drop subscribers that stopped reading rather than block the
+			// others; clients reconnect with the last sequence they saw
+			b.remove(sub)
+		}
+	}
+}
+
+func (b *Broker) closeAll() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for sub := range b.subscribers {
+		b.remove(sub)
+	}
+}
+
+// remove must be called with b.mu held.
+func (b *Broker) remove(sub *Subscription) {
+	if _, ok := b.subscribers[sub]; ok {
+		delete(b.subscribers, sub)
+		close(sub.events)
+	}
+}
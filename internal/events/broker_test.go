@@ -0,0 +1,74 @@
+package events
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"loan-origination-system/internal/projection"
+	"loan-origination-system/internal/workflows"
+
+	"github.com/stretchr/testify/require"
+)
+
+func openTestStore(t *testing.T) *projection.Store {
+	store, err := projection.Open(filepath.Join(t.TempDir(), "loans.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func saveLoan(t *testing.T, store *projection.Store, id, status string) {
+	require.NoError(t, store.SaveLoan(context.Background(), workflows.LoanOriginationState{
+		LoanApplication: workflows.LoanApplication{ID: id, CreatedAt: time.Now()},
+		Status:          status,
+	}))
+}
+
+func receive(t *testing.T, sub *Subscription) projection.LoanChange {
+	t.Helper()
+	select {
+	case change, ok := <-sub.Events():
+		require.True(t, ok, "subscription closed")
+		return change
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for loan change")
+		return projection.LoanChange{}
+	}
+}
+
+func TestBroker_DeliversChangesToSubscribers(t *testing.T) {
+	store := openTestStore(t)
+	saveLoan(t, store, "loan-0", "processing")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	broker := NewBroker(store, 10*time.Millisecond)
+	all := broker.Subscribe("")
+	loan2 := broker.Subscribe("loan-2")
+
+	done := make(chan error)
+	go func() { done <- broker.Run(ctx) }()
+
+	// Wait for the broker to take its starting cursor, so loan-0 is skipped
+	time.Sleep(50 * time.Millisecond)
+	saveLoan(t, store, "loan-1", "processing")
+	saveLoan(t, store, "loan-2", "approved")
+
+	require.Equal(t, "loan-1", receive(t, all).State.LoanApplication.ID)
+	require.Equal(t, "loan-2", receive(t, all).State.LoanApplication.ID)
+
+	change := receive(t, loan2)
+	require.Equal(t, "loan-2", change.State.LoanApplication.ID)
+	require.Equal(t, "approved", change.State.Status)
+	require.Equal(t, int64(3), change.Sequence)
+
+	broker.Unsubscribe(loan2)
+	_, ok := <-loan2.Events()
+	require.False(t, ok)
+
+	cancel()
+	require.NoError(t, <-done)
+	_, ok = <-all.Events()
+	require.False(t, ok)
+}
@@ -0,0 +1,322 @@
+// Package ledger is the double-entry book of the money the lender moves.
+// Every journal entry debits and credits accounts by the same total, so the
+// balances of all accounts always sum to zero. Amounts are whole cents.
+package ledger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// DefaultDatabasePath is the SQLite file shared by the worker, which posts
+// entries, and the API server, which reads them.
+const DefaultDatabasePath = "ledger.db"
+
+var (
+	// ErrUnbalanced is returned for entries whose debits and credits differ.
+	ErrUnbalanced = errors.New("journal entry is not balanced")
+	// ErrUnknownAccount is returned for lines posting to an account that is
+	// not in the chart of accounts.
+	ErrUnknownAccount = errors.New("unknown ledger account")
+	// ErrConflict is returned when an entry is posted again under the same
+	// ID with different lines.
+	ErrConflict = errors.New("journal entry already posted with different lines")
+	// ErrNotFound is returned for entries that have not been posted.
+	ErrNotFound = errors.New("journal entry not found")
+)
+
+// AccountType decides which side of an account its balance is kept on.
+type AccountType string
+
+const (
+	Asset     AccountType = "asset"
+	Liability AccountType = "liability"
+)
+
+// Account codes of the chart of accounts.
+const (
+	// AccountLoansReceivable holds the principal borrowers owe
+	AccountLoansReceivable = "loans_receivable"
+	// AccountFundingCash is the account loans are disbursed from
+	AccountFundingCash = "funding_cash"
+)
+
+// Account is an account of the chart of accounts.
+type Account struct {
+	Code string      `gorm:"primaryKey" json:"code"`
+	Name string      `json:"name"`
+	Type AccountType `json:"type"`
+}
+
+func (Account) TableName() string { return "ledger_accounts" }
+
+// chartOfAccounts is created when the ledger is opened.
+var chartOfAccounts = []Account{
+	{Code: AccountLoansReceivable, Name: "Loans receivable", Type: Asset},
+	{Code: AccountFundingCash, Name: "Funding cash", Type: Asset},
+}
+
+// Entry is a journal entry. Its ID doubles as an idempotency key: posting
+// an entry again under the same ID returns the entry already posted.
+type Entry struct {
+	ID          string    `gorm:"primaryKey" json:"id"`
+	LoanID      string    `gorm:"index" json:"loan_id"`
+	Description string    `json:"description"`
+	PostedAt    time.Time `gorm:"index" json:"posted_at"`
+	Lines       []Line    `gorm:"foreignKey:EntryID" json:"lines"`
+}
+
+func (Entry) TableName() string { return "journal_entries" }
+
+// Line debits or credits one account. Exactly one of Debit and Credit is
+// set.
+type Line struct {
+	ID      uint   `gorm:"primaryKey" json:"-"`
+	EntryID string `gorm:"index" json:"-"`
+	Account string `gorm:"index" json:"account"`
+	Debit   int64  `json:"debit"`
+	Credit  int64  `json:"credit"`
+}
+
+func (Line) TableName() string { return "journal_lines" }
+
+// Balance is an account's total debits and credits, and the difference on
+// the account's normal side.
+type Balance struct {
+	Account string      `json:"account"`
+	Name    string      `json:"name"`
+	Type    AccountType `json:"type"`
+	Debits  int64       `json:"debits"`
+	Credits int64       `json:"credits"`
+	Balance int64       `json:"balance"`
+}
+
+// Cents converts a dollar amount to whole cents.
+func Cents(amount float64) int64 {
+	return int64(math.Round(amount * 100))
+}
+
+// DisbursementID is the ID of the entry paying out a loan's funding
+// attempt.
+func DisbursementID(loanID string, attempt int) string {
+	return fmt.Sprintf("disbursement-%s-%d", loanID, attempt)
+}
+
+// Disbursement is the entry that pays out a loan's principal. It is keyed by
+// the loan and the funding attempt, so each attempt is disbursed at most
+// once.
+func Disbursement(loanID string, attempt int, amount int64, postedAt time.Time) Entry {
+	return Entry{
+		ID:          DisbursementID(loanID, attempt),
+		LoanID:      loanID,
+		Description: fmt.Sprintf("Disbursement of loan %s, attempt %d", loanID, attempt),
+		PostedAt:    postedAt,
+		Lines: []Line{
+			{Account: AccountLoansReceivable, Debit: amount},
+			{Account: AccountFundingCash, Credit: amount},
+		},
+	}
+}
+
+// Reversal is the entry that cancels entry out, swapping the debits and
+// credits of its lines. It is keyed by the entry it reverses.
+func Reversal(entry Entry, postedAt time.Time) Entry {
+	reversal := Entry{
+		ID:          "reversal-" + entry.ID,
+		LoanID:      entry.LoanID,
+		Description: "Reversal of " + entry.ID,
+		PostedAt:    postedAt,
+	}
+	for _, line := range entry.Lines {
+		reversal.Lines = append(reversal.Lines, Line{Account: line.Account, Debit: line.Credit, Credit: line.Debit})
+	}
+	return reversal
+}
+
+// Ledger is the journal and its accounts, stored in SQLite.
+type Ledger struct {
+	db *gorm.DB
+}
+
+// Open opens (creating if needed) the ledger database at path, migrates its
+// tables and creates the chart of accounts.
+func Open(path string) (*Ledger, error) {
+	// WAL mode lets the API server read while the worker posts
+	db, err := gorm.Open(sqlite.Open(path+"?_journal_mode=WAL&_busy_timeout=5000"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Warn),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.AutoMigrate(&Account{}, &Entry{}, &Line{})
+	if err != nil {
+		return nil, err
+	}
+	for _, account := range chartOfAccounts {
+		if err := db.FirstOrCreate(&Account{}, account).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	return &Ledger{db: db}, nil
+}
+
+// Close closes the underlying database connection.
+func (l *Ledger) Close() error {
+	sqlDB, err := l.db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
+
+// Post records a balanced entry. An entry already posted under the same ID
+// is returned as it was posted if its lines are the same, and reported as
+// ErrConflict otherwise.
+func (l *Ledger) Post(ctx context.Context, entry Entry) (Entry, error) {
+	if err := validate(entry); err != nil {
+		return Entry{}, err
+	}
+
+	err := l.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existing Entry
+		err := tx.Preload("Lines", orderLines).Where("id = ?", entry.ID).Take(&existing).Error
+		if err == nil {
+			if !sameLines(existing, entry) {
+				return fmt.Errorf("%w: %s", ErrConflict, entry.ID)
+			}
+			entry = existing
+			return nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		var known int64
+		if err := tx.Model(&Account{}).Where("code IN ?", accountCodes(entry)).Count(&known).Error; err != nil {
+			return err
+		}
+		if int(known) != len(accountCodes(entry)) {
+			return fmt.Errorf("%w in entry %s", ErrUnknownAccount, entry.ID)
+		}
+
+		entry.PostedAt = entry.PostedAt.UTC()
+		return tx.Create(&entry).Error
+	})
+	if err != nil {
+		return Entry{}, err
+	}
+	return entry, nil
+}
+
+// Entry returns the entry posted under id, or ErrNotFound.
+func (l *Ledger) Entry(ctx context.Context, id string) (Entry, error) {
+	var entry Entry
+	err := l.db.WithContext(ctx).Preload("Lines", orderLines).Where("id = ?", id).Take(&entry).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return Entry{}, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	if err != nil {
+		return Entry{}, err
+	}
+	return entry, nil
+}
+
+// Entries returns the journal in posting order, only the entries of loanID
+// if it is set.
+func (l *Ledger) Entries(ctx context.Context, loanID string) ([]Entry, error) {
+	query := l.db.WithContext(ctx).Preload("Lines", orderLines).Order("posted_at, id")
+	if loanID != "" {
+		query = query.Where("loan_id = ?", loanID)
+	}
+
+	entries := []Entry{}
+	if err := query.Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// Balances returns the balance of every account in the chart of accounts.
+func (l *Ledger) Balances(ctx context.Context) ([]Balance, error) {
+	balances := []Balance{}
+	err := l.db.WithContext(ctx).Model(&Account{}).
+		Select("ledger_accounts.code AS account, ledger_accounts.name, ledger_accounts.type, " +
+			"COALESCE(SUM(journal_lines.debit), 0) AS debits, COALESCE(SUM(journal_lines.credit), 0) AS credits").
+		Joins("LEFT JOIN journal_lines ON journal_lines.account = ledger_accounts.code").
+		Group("ledger_accounts.code").
+		Order("ledger_accounts.code").
+		Scan(&balances).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range balances {
+		balance := &balances[i]
+		balance.Balance = balance.Debits - balance.Credits
+		if balance.Type != Asset {
+			balance.Balance = -balance.Balance
+		}
+	}
+	return balances, nil
+}
+
+func validate(entry Entry) error {
+	if entry.ID == "" {
+		return errors.New("journal entry ID is required")
+	}
+	if len(entry.Lines) < 2 {
+		return fmt.Errorf("%w: entry %s needs at least two lines", ErrUnbalanced, entry.ID)
+	}
+
+	var debits, credits int64
+	for _, line := range entry.Lines {
+		if line.Debit < 0 || line.Credit < 0 || (line.Debit > 0) == (line.Credit > 0) {
+			return fmt.Errorf("%w: each line of entry %s must either debit or credit a positive amount", ErrUnbalanced, entry.ID)
+		}
+		debits += line.Debit
+		credits += line.Credit
+	}
+	if debits != credits {
+		return fmt.Errorf("%w: entry %s debits %d and credits %d", ErrUnbalanced, entry.ID, debits, credits)
+	}
+	return nil
+}
+
+func sameLines(a, b Entry) bool {
+	if a.LoanID != b.LoanID || len(a.Lines) != len(b.Lines) {
+		return false
+	}
+	for i := range a.Lines {
+		x, y := a.Lines[i], b.Lines[i]
+		if x.Account != y.Account || x.Debit != y.Debit || x.Credit != y.Credit {
+			return false
+		}
+	}
+	return true
+}
+
+func accountCodes(entry Entry) []string {
+	seen := map[string]bool{}
+	var codes []string
+	for _, line := range entry.Lines {
+		if !seen[line.Account] {
+			seen[line.Account] = true
+			codes = append(codes, line.Account)
+		}
+	}
+	return codes
+}
+
+// orderLines keeps lines in the order they were posted.
+func orderLines(db *gorm.DB) *gorm.DB {
+	return db.Order("journal_lines.id")
+}
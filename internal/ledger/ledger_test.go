@@ -0,0 +1,136 @@
+package ledger
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func openTestLedger(t *testing.T) *Ledger {
+	ledger, err := Open(filepath.Join(t.TempDir(), "ledger.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { ledger.Close() })
+	return ledger
+}
+
+func TestPost_DisbursementIsIdempotent(t *testing.T) {
+	ledger := openTestLedger(t)
+	ctx := context.Background()
+	postedAt := time.Date(2024, 6, 3, 15, 0, 0, 0, time.UTC)
+
+	entry, err := ledger.Post(ctx, Disbursement("loan-1", 1, Cents(250000), postedAt))
+	require.NoError(t, err)
+	require.Equal(t, "disbursement-loan-1-1", entry.ID)
+	require.Len(t, entry.Lines, 2)
+
+	// A retried activity posts the same entry again
+	again, err := ledger.Post(ctx, Disbursement("loan-1", 1, Cents(250000), postedAt.Add(time.Minute)))
+	require.NoError(t, err)
+	require.Equal(t, postedAt, again.PostedAt)
+	require.Equal(t, entry.Lines, again.Lines)
+
+	_, err = ledger.Post(ctx, Disbursement("loan-1", 1, Cents(200000), postedAt))
+	require.ErrorIs(t, err, ErrConflict)
+
+	_, err = ledger.Post(ctx, Disbursement("loan-2", 1, Cents(10000.5), postedAt))
+	require.NoError(t, err)
+
+	entries, err := ledger.Entries(ctx, "")
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	loanEntries, err := ledger.Entries(ctx, "loan-1")
+	require.NoError(t, err)
+	require.Len(t, loanEntries, 1)
+	require.Equal(t, AccountLoansReceivable, loanEntries[0].Lines[0].Account)
+	require.Equal(t, int64(25000000), loanEntries[0].Lines[0].Debit)
+
+	balances, err := ledger.Balances(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []Balance{
+		{Account: AccountFundingCash, Name: "Funding cash", Type: Asset, Credits: 26000050, Balance: -26000050},
+		{Account: AccountLoansReceivable, Name: "Loans receivable", Type: Asset, Debits: 26000050, Balance: 26000050},
+	}, balances)
+}
+
+func TestReversal_CancelsDisbursement(t *testing.T) {
+	ledger := openTestLedger(t)
+	ctx := context.Background()
+	postedAt := time.Date(2024, 6, 3, 15, 0, 0, 0, time.UTC)
+
+	_, err := ledger.Entry(ctx, DisbursementID("loan-1", 1))
+	require.ErrorIs(t, err, ErrNotFound)
+
+	_, err = ledger.Post(ctx, Disbursement("loan-1", 1, Cents(250000), postedAt))
+	require.NoError(t, err)
+	entry, err := ledger.Entry(ctx, DisbursementID("loan-1", 1))
+	require.NoError(t, err)
+
+	for attempt := 0; attempt < 2; attempt++ {
+		reversal, err := ledger.Post(ctx, Reversal(entry, postedAt.Add(time.Hour)))
+		require.NoError(t, err)
+		require.Equal(t, "reversal-disbursement-loan-1-1", reversal.ID)
+		require.Equal(t, []Line{
+			{Account: AccountLoansReceivable, Credit: 25000000},
+			{Account: AccountFundingCash, Debit: 25000000},
+		}, stripIDs(reversal.Lines))
+	}
+
+	// The next funding attempt is a new entry
+	_, err = ledger.Post(ctx, Disbursement("loan-1", 2, Cents(250000), postedAt.Add(2*time.Hour)))
+	require.NoError(t, err)
+
+	entries, err := ledger.Entries(ctx, "loan-1")
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	balances, err := ledger.Balances(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(25000000), balances[1].Balance)
+}
+
+func stripIDs(lines []Line) []Line {
+	stripped := make([]Line, len(lines))
+	for i, line := range lines {
+		stripped[i] = Line{Account: line.Account, Debit: line.Debit, Credit: line.Credit}
+	}
+	return stripped
+}
+
+func TestPost_RejectsInvalidEntries(t *testing.T) {
+	ledger := openTestLedger(t)
+	ctx := context.Background()
+
+	tests := map[string]struct {
+		entry Entry
+		err   error
+	}{
+		"unbalanced": {
+			entry: Entry{ID: "e1", Lines: []Line{{Account: AccountLoansReceivable, Debit: 100}, {Account: AccountFundingCash, Credit: 99}}},
+			err:   ErrUnbalanced,
+		},
+		"single line": {
+			entry: Entry{ID: "e2", Lines: []Line{{Account: AccountLoansReceivable, Debit: 100}}},
+			err:   ErrUnbalanced,
+		},
+		"debit and credit": {
+			entry: Entry{ID: "e3", Lines: []Line{{Account: AccountLoansReceivable, Debit: 100, Credit: 100}, {Account: AccountFundingCash, Credit: 0}}},
+			err:   ErrUnbalanced,
+		},
+		"unknown account": {
+			entry: Entry{ID: "e4", Lines: []Line{{Account: "petty_cash", Debit: 100}, {Account: AccountFundingCash, Credit: 100}}},
+			err:   ErrUnknownAccount,
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := ledger.Post(ctx, test.entry)
+			require.ErrorIs(t, err, test.err)
+		})
+	}
+
+	entries, err := ledger.Entries(ctx, "")
+	require.NoError(t, err)
+	require.Empty(t, entries)
+}
@@ -0,0 +1,64 @@
+// Package notify tells borrowers about their loan by email and SMS. Messages
+// are rendered from templates for each workflow event and delivered through
+// a Mailer, which speaks SMTP, and an SMSProvider, which is a file or log
+// stub until a real SMS gateway is wired in.
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"loan-origination-system/internal/config"
+)
+
+// ErrInvalidRecipient is returned for addresses and numbers a message cannot
+// be sent to. Retrying does not help.
+var ErrInvalidRecipient = errors.New("invalid recipient")
+
+// Channel is the way a message reaches the borrower.
+type Channel string
+
+const (
+	ChannelEmail Channel = "email"
+	ChannelSMS   Channel = "sms"
+)
+
+// Message is a rendered notification. SMS messages have no subject.
+type Message struct {
+	Channel Channel `json:"channel"`
+	To      string  `json:"to"`
+	Subject string  `json:"subject,omitempty"`
+	Body    string  `json:"body"`
+}
+
+// Mailer delivers email and returns the message ID it was sent under.
+type Mailer interface {
+	SendEmail(ctx context.Context, msg Message) (string, error)
+}
+
+// SMSProvider delivers text messages and returns the provider's message ID.
+type SMSProvider interface {
+	SendSMS(ctx context.Context, msg Message) (string, error)
+}
+
+// New returns the mailer and SMS provider configured by cfg.
+func New(cfg config.Notifications) (Mailer, SMSProvider, error) {
+	mailer := &SMTPMailer{
+		Address:  cfg.SMTP.Address,
+		From:     cfg.SMTP.From,
+		Username: cfg.SMTP.Username,
+		Password: cfg.SMTP.Password,
+	}
+
+	var sms SMSProvider
+	switch cfg.SMS.Provider {
+	case "file":
+		sms = &FileSMSProvider{Path: cfg.SMS.File}
+	case "log":
+		sms = &FileSMSProvider{}
+	default:
+		return nil, nil, fmt.Errorf("unknown sms provider %q", cfg.SMS.Provider)
+	}
+	return mailer, sms, nil
+}
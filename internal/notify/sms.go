@@ -0,0 +1,67 @@
+package notify
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FileSMSProvider stands in for an SMS gateway. It appends each message to
+// Path as a line of JSON, or only logs it when Path is empty.
+type FileSMSProvider struct {
+	Path string
+
+	mu sync.Mutex
+}
+
+type smsRecord struct {
+	ID     string    `json:"id"`
+	To     string    `json:"to"`
+	Body   string    `json:"body"`
+	SentAt time.Time `json:"sent_at"`
+}
+
+func (p *FileSMSProvider) SendSMS(ctx context.Context, msg Message) (string, error) {
+	if msg.To == "" || strings.ContainsAny(msg.To, "\r\n") {
+		return "", fmt.Errorf("%w: phone number %q", ErrInvalidRecipient, msg.To)
+	}
+	random := make([]byte, 8)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	record := smsRecord{
+		ID:     "sms-" + hex.EncodeToString(random),
+		To:     msg.To,
+		Body:   msg.Body,
+		SentAt: time.Now().UTC(),
+	}
+
+	log.Printf("SMS %s to %s: %s", record.ID, record.To, record.Body)
+	if p.Path == "" {
+		return record.ID, nil
+	}
+
+	line, err := json.Marshal(record)
+	if err != nil {
+		return "", err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	file, err := os.OpenFile(p.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return "", err
+	}
+	return record.ID, nil
+}
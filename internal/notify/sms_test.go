@@ -0,0 +1,36 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileSMSProvider_AppendsMessages(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sms.log")
+	provider := &FileSMSProvider{Path: path}
+
+	first, err := provider.SendSMS(context.Background(), Message{Channel: ChannelSMS, To: "555-0100", Body: "Your loan has been funded."})
+	require.NoError(t, err)
+	second, err := provider.SendSMS(context.Background(), Message{Channel: ChannelSMS, To: "555-0100", Body: "Loan application loan-1 has expired."})
+	require.NoError(t, err)
+	require.NotEqual(t, first, second)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	require.Len(t, lines, 2)
+	var record smsRecord
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &record))
+	require.Equal(t, first, record.ID)
+	require.Equal(t, "555-0100", record.To)
+	require.Equal(t, "Your loan has been funded.", record.Body)
+
+	_, err = provider.SendSMS(context.Background(), Message{Channel: ChannelSMS, Body: "no number"})
+	require.ErrorIs(t, err, ErrInvalidRecipient)
+}
@@ -0,0 +1,90 @@
+package notify
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// SMTPMailer sends email through an SMTP server, such as MailHog on
+// localhost:1025 during development. It authenticates only when Username is
+// set.
+type SMTPMailer struct {
+	Address  string
+	From     string
+	Username string
+	Password string
+}
+
+func (m *SMTPMailer) SendEmail(ctx context.Context, msg Message) (string, error) {
+	from, err := mail.ParseAddress(m.From)
+	if err != nil {
+		return "", fmt.Errorf("smtp from address: %w", err)
+	}
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidRecipient, err)
+	}
+	messageID, err := newMessageID(from.Address)
+	if err != nil {
+		return "", err
+	}
+
+	var auth smtp.Auth
+	if m.Username != "" {
+		host, _, err := net.SplitHostPort(m.Address)
+		if err != nil {
+			return "", err
+		}
+		auth = smtp.PlainAuth("", m.Username, m.Password, host)
+	}
+
+	// net/smtp has no context support, so the deadline only bounds the wait
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(m.Address, auth, from.Address, []string{to.Address}, format(from, to, msg, messageID))
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			return "", fmt.Errorf("send email to %s: %w", msg.To, err)
+		}
+		return messageID, nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// format renders msg as a plain text RFC 5322 message.
+func format(from, to *mail.Address, msg Message, messageID string) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", to)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&b, "Message-ID: %s\r\n", messageID)
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
+
+func newMessageID(from string) (string, error) {
+	random := make([]byte, 12)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	domain := "localhost"
+	if _, host, ok := strings.Cut(from, "@"); ok {
+		domain = host
+	}
+	return fmt.Sprintf("<%s@%s>", hex.EncodeToString(random), domain), nil
+}
@@ -0,0 +1,121 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// smtpSink is a minimal SMTP server, like MailHog, that accepts every
+// message and hands it to the test.
+type smtpSink struct {
+	listener net.Listener
+	messages chan sunkMessage
+}
+
+type sunkMessage struct {
+	from string
+	to   []string
+	data string
+}
+
+func newSMTPSink(t *testing.T) *smtpSink {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	sink := &smtpSink{listener: listener, messages: make(chan sunkMessage, 10)}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go sink.serve(conn)
+		}
+	}()
+	return sink
+}
+
+func (s *smtpSink) serve(conn net.Conn) {
+	defer conn.Close()
+	text := textproto.NewConn(conn)
+	var msg sunkMessage
+
+	text.PrintfLine("220 sink ESMTP")
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+		command := strings.ToUpper(line)
+		switch {
+		case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+			text.PrintfLine("250 sink")
+		case strings.HasPrefix(command, "MAIL FROM:"):
+			msg.from = strings.Trim(line[len("MAIL FROM:"):], "<>")
+			text.PrintfLine("250 OK")
+		case strings.HasPrefix(command, "RCPT TO:"):
+			msg.to = append(msg.to, strings.Trim(line[len("RCPT TO:"):], "<>"))
+			text.PrintfLine("250 OK")
+		case command == "DATA":
+			text.PrintfLine("354 End data with <CR><LF>.<CR><LF>")
+			data, err := text.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			msg.data = string(data)
+			s.messages <- msg
+			msg = sunkMessage{}
+			text.PrintfLine("250 OK")
+		case command == "QUIT":
+			text.PrintfLine("221 Bye")
+			return
+		default:
+			text.PrintfLine("250 OK")
+		}
+	}
+}
+
+func TestSMTPMailer_SendsToSink(t *testing.T) {
+	sink := newSMTPSink(t)
+	mailer := &SMTPMailer{Address: sink.listener.Addr().String(), From: "Loan Origination <loans@example.com>"}
+
+	messageID, err := mailer.SendEmail(context.Background(), Message{
+		Channel: ChannelEmail,
+		To:      "jane@example.com",
+		Subject: "Your loan has been funded",
+		Body:    "Dear Jane Doe,\n\nYour loan has been funded.\n",
+	})
+	require.NoError(t, err)
+	require.True(t, strings.HasSuffix(messageID, "@example.com>"), messageID)
+
+	var msg sunkMessage
+	select {
+	case msg = <-sink.messages:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no message reached the sink")
+	}
+	require.Equal(t, "loans@example.com", msg.from)
+	require.Equal(t, []string{"jane@example.com"}, msg.to)
+
+	header, err := textproto.NewReader(bufio.NewReader(strings.NewReader(msg.data))).ReadMIMEHeader()
+	require.NoError(t, err)
+	require.Equal(t, `"Loan Origination" <loans@example.com>`, header.Get("From"))
+	require.Equal(t, "Your loan has been funded", header.Get("Subject"))
+	require.Equal(t, messageID, header.Get("Message-Id"))
+	require.Contains(t, msg.data, "Your loan has been funded.\n")
+}
+
+func TestSMTPMailer_RejectsInvalidRecipient(t *testing.T) {
+	mailer := &SMTPMailer{Address: "127.0.0.1:1", From: "loans@example.com"}
+
+	_, err := mailer.SendEmail(context.Background(), Message{To: "jane@example.com\r\nBcc: everyone@example.com"})
+	require.ErrorIs(t, err, ErrInvalidRecipient)
+}
@@ -0,0 +1,181 @@
+package notify
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Event is a loan transition the borrower is told about.
+type Event string
+
+const (
+	EventApplicationReceived   Event = "application_received"
+	EventDocumentRejected      Event = "document_rejected"
+	EventDocumentsRequested    Event = "documents_requested"
+	EventCounterOffered        Event = "counter_offered"
+	EventConditionallyApproved Event = "conditionally_approved"
+	EventSignatureRequested    Event = "signature_requested"
+	EventFunded                Event = "funded"
+	EventFundingFailed         Event = "funding_failed"
+	EventRejected              Event = "rejected"
+	EventWithdrawn             Event = "withdrawn"
+	EventCancelled             Event = "cancelled"
+	EventIncomplete            Event = "incomplete"
+)
+
+// Data is what the templates may refer to. Fields that do not apply to an
+// event are left empty.
+type Data struct {
+	LoanApplicationID string    `json:"loan_application_id"`
+	BorrowerName      string    `json:"borrower_name"`
+	LoanAmount        float64   `json:"loan_amount"`
+	DocumentType      string    `json:"document_type,omitempty"`
+	Reason            string    `json:"reason,omitempty"`
+	Documents         []string  `json:"documents,omitempty"`
+	Conditions        []string  `json:"conditions,omitempty"`
+	ExpiresAt         time.Time `json:"expires_at,omitempty"`
+}
+
+type messageTemplate struct {
+	subject string
+	email   string
+	sms     string
+}
+
+var messageTemplates = map[Event]messageTemplate{
+	EventApplicationReceived: {
+		subject: "We received your loan application",
+		email: `Dear {{.BorrowerName}},
+
+We received your application {{.LoanApplicationID}} for a loan of {{money .LoanAmount}}. We will let you know which documents we need and when a decision has been made.`,
+		sms: `We received your loan application {{.LoanApplicationID}} for {{money .LoanAmount}}.`,
+	},
+	EventDocumentRejected: {
+		subject: "A document for your loan application was not accepted",
+		email: `Dear {{.BorrowerName}},
+
+We could not accept the {{.DocumentType}} you provided for application {{.LoanApplicationID}}{{if .Reason}}: {{.Reason}}{{end}}. Please upload a new one so we can continue with your application.`,
+		sms: `Your {{.DocumentType}} for loan application {{.LoanApplicationID}} was not accepted. Please upload a new one.`,
+	},
+	EventDocumentsRequested: {
+		subject: "More documents are needed for your loan application",
+		email: `Dear {{.BorrowerName}},
+
+To make a decision on application {{.LoanApplicationID}} we need the following documents:
+{{range .Documents}}
+- {{.}}{{end}}
+
+Please upload them as soon as you can.`,
+		sms: `We need more documents for loan application {{.LoanApplicationID}}: {{join .Documents ", "}}.`,
+	},
+	EventCounterOffered: {
+		subject: "We can offer you a loan on different terms",
+		email: `Dear {{.BorrowerName}},
+
+We cannot approve application {{.LoanApplicationID}} as requested, but we can offer you a loan of {{money .LoanAmount}}. Please accept or decline the offer by {{date .ExpiresAt}}.`,
+		sms: `We can offer you a loan of {{money .LoanAmount}} for application {{.LoanApplicationID}}. Please respond by {{date .ExpiresAt}}.`,
+	},
+	EventConditionallyApproved: {
+		subject: "Your loan is approved with conditions",
+		email: `Dear {{.BorrowerName}},
+
+Your loan of {{money .LoanAmount}} is approved, subject to these conditions:
+{{range .Conditions}}
+- {{.}}{{end}}
+
+We will fund the loan once they have been met.`,
+		sms: `Your loan of {{money .LoanAmount}} is approved with conditions. Application {{.LoanApplicationID}}.`,
+	},
+	EventSignatureRequested: {
+		subject: "Your loan is approved: please sign your loan agreement",
+		email: `Dear {{.BorrowerName}},
+
+Your loan of {{money .LoanAmount}} is approved. Please review and sign your loan agreement for application {{.LoanApplicationID}} by {{date .ExpiresAt}}. We will fund the loan once it is signed.`,
+		sms: `Your loan of {{money .LoanAmount}} is approved. Please sign your loan agreement by {{date .ExpiresAt}}.`,
+	},
+	EventFunded: {
+		subject: "Your loan has been funded",
+		email: `Dear {{.BorrowerName}},
+
+Your loan of {{money .LoanAmount}} for application {{.LoanApplicationID}} has been funded.`,
+		sms: `Your loan of {{money .LoanAmount}} has been funded.`,
+	},
+	EventFundingFailed: {
+		subject: "Your loan funding is delayed",
+		email: `Dear {{.BorrowerName}},
+
+We could not fund your loan for application {{.LoanApplicationID}}. No money has been moved, and we are working to resolve the problem. You will need to sign a new loan agreement before the loan is funded.`,
+		sms: `Funding of your loan {{.LoanApplicationID}} is delayed. We will be in touch.`,
+	},
+	EventRejected: {
+		subject: "A decision on your loan application",
+		email: `Dear {{.BorrowerName}},
+
+We are unable to approve application {{.LoanApplicationID}}. The reasons for our decision and your rights are set out in the adverse action notice with your application documents.`,
+		sms: `We are unable to approve loan application {{.LoanApplicationID}}. Details are in your application documents.`,
+	},
+	EventWithdrawn: {
+		subject: "Your loan application has been withdrawn",
+		email: `Dear {{.BorrowerName}},
+
+Application {{.LoanApplicationID}} has been withdrawn. No further action is needed.`,
+		sms: `Loan application {{.LoanApplicationID}} has been withdrawn.`,
+	},
+	EventCancelled: {
+		subject: "Your loan application has been cancelled",
+		email: `Dear {{.BorrowerName}},
+
+We have cancelled application {{.LoanApplicationID}}. Please contact your loan officer with any questions.`,
+		sms: `Loan application {{.LoanApplicationID}} has been cancelled.`,
+	},
+	EventIncomplete: {
+		subject: "Your loan application has expired",
+		email: `Dear {{.BorrowerName}},
+
+Application {{.LoanApplicationID}} has expired because it could not be completed in time. You are welcome to apply again.`,
+		sms: `Loan application {{.LoanApplicationID}} has expired.`,
+	},
+}
+
+var templateFuncs = template.FuncMap{
+	"money": func(amount float64) string {
+		return fmt.Sprintf("$%.2f", amount)
+	},
+	"date": func(t time.Time) string {
+		return t.Format("January 2, 2006")
+	},
+	"join": strings.Join,
+}
+
+// Render renders the message for event on channel, addressed to to.
+func Render(event Event, channel Channel, to string, data Data) (Message, error) {
+	templates, ok := messageTemplates[event]
+	if !ok {
+		return Message{}, fmt.Errorf("no message template for event %q", event)
+	}
+
+	msg := Message{Channel: channel, To: to}
+	var body string
+	switch channel {
+	case ChannelEmail:
+		msg.Subject = templates.subject
+		body = templates.email + "\n\nLoan Origination Team\n"
+	case ChannelSMS:
+		body = templates.sms
+	default:
+		return Message{}, fmt.Errorf("unknown channel %q", channel)
+	}
+
+	tmpl, err := template.New(string(event)).Funcs(templateFuncs).Option("missingkey=error").Parse(body)
+	if err != nil {
+		return Message{}, err
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return Message{}, fmt.Errorf("render %s %s message: %w", event, channel, err)
+	}
+	msg.Body = b.String()
+	return msg, nil
+}
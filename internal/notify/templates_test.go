@@ -0,0 +1,61 @@
+package notify
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRender_EveryEventOnEveryChannel(t *testing.T) {
+	data := Data{
+		LoanApplicationID: "loan-1",
+		BorrowerName:      "Jane Doe",
+		LoanAmount:        250000,
+		DocumentType:      "bank_statement",
+		Documents:         []string{"bank_statement", "tax_return"},
+		Conditions:        []string{"Provide final pay stub"},
+		ExpiresAt:         time.Date(2024, 6, 17, 12, 0, 0, 0, time.UTC),
+	}
+
+	for event := range messageTemplates {
+		email, err := Render(event, ChannelEmail, "jane@example.com", data)
+		require.NoError(t, err, event)
+		require.NotEmpty(t, email.Subject, event)
+		require.Contains(t, email.Body, "Dear Jane Doe", event)
+		require.NotContains(t, email.Body, "<no value>", event)
+
+		sms, err := Render(event, ChannelSMS, "555-0100", data)
+		require.NoError(t, err, event)
+		require.Empty(t, sms.Subject, event)
+		require.Less(t, len(sms.Body), 160, event)
+	}
+}
+
+func TestRender_FillsEventDetails(t *testing.T) {
+	msg, err := Render(EventDocumentRejected, ChannelEmail, "jane@example.com", Data{
+		LoanApplicationID: "loan-1",
+		BorrowerName:      "Jane Doe",
+		DocumentType:      "bank_statement",
+		Reason:            "the statement is older than 60 days",
+	})
+	require.NoError(t, err)
+	require.Equal(t, Message{
+		Channel: ChannelEmail,
+		To:      "jane@example.com",
+		Subject: "A document for your loan application was not accepted",
+		Body: "Dear Jane Doe,\n\nWe could not accept the bank_statement you provided for application loan-1: " +
+			"the statement is older than 60 days. Please upload a new one so we can continue with your application.\n\n" +
+			"Loan Origination Team\n",
+	}, msg)
+
+	msg, err = Render(EventSignatureRequested, ChannelSMS, "555-0100", Data{
+		LoanAmount: 200000,
+		ExpiresAt:  time.Date(2024, 6, 17, 12, 0, 0, 0, time.UTC),
+	})
+	require.NoError(t, err)
+	require.Equal(t, "Your loan of $200000.00 is approved. Please sign your loan agreement by June 17, 2024.", msg.Body)
+
+	_, err = Render("loan_exploded", ChannelEmail, "jane@example.com", Data{})
+	require.ErrorContains(t, err, "no message template")
+}
@@ -0,0 +1,180 @@
+// Package pdf lays out plain text as a PDF document. It covers the generated
+// loan paperwork, which is text with headings, without a rendering
+// dependency: lines starting with "# " are set as headings, blank lines
+// separate paragraphs and long lines are wrapped to the page.
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// US Letter in points, with one-inch margins.
+const (
+	pageWidth  = 612
+	pageHeight = 792
+	margin     = 72
+)
+
+type style struct {
+	font    string
+	size    float64
+	leading float64
+	// wrap is how many characters fit on a line in this style
+	wrap int
+}
+
+var (
+	bodyStyle    = style{font: "F1", size: 10, leading: 14, wrap: 92}
+	headingStyle = style{font: "F2", size: 13, leading: 22, wrap: 70}
+)
+
+type line struct {
+	text  string
+	style style
+}
+
+// FromText lays out text on as many pages as it needs and returns the PDF.
+// Characters outside printable ASCII are replaced, since only the standard
+// Helvetica fonts are used.
+func FromText(title, text string) []byte {
+	var lines []line
+	for _, raw := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		raw = strings.TrimRight(raw, " \t")
+		st := bodyStyle
+		if strings.HasPrefix(raw, "# ") {
+			st = headingStyle
+			raw = strings.TrimPrefix(raw, "# ")
+		}
+		for _, wrapped := range wrap(raw, st.wrap) {
+			lines = append(lines, line{text: wrapped, style: st})
+		}
+	}
+	return write(title, paginate(lines))
+}
+
+// wrap breaks s into lines of at most width characters at spaces. Words
+// longer than width are split.
+func wrap(s string, width int) []string {
+	if s == "" {
+		return []string{""}
+	}
+	indent := s[:len(s)-len(strings.TrimLeft(s, " "))]
+	var lines []string
+	current := indent
+	for _, word := range strings.Fields(s) {
+		for len(word) > width-len(indent) {
+			if current != indent {
+				lines = append(lines, current)
+				current = indent
+			}
+			cut := width - len(indent)
+			lines = append(lines, indent+word[:cut])
+			word = word[cut:]
+		}
+		switch {
+		case current == indent:
+			current += word
+		case len(current)+1+len(word) <= width:
+			current += " " + word
+		default:
+			lines = append(lines, current)
+			current = indent + word
+		}
+	}
+	return append(lines, current)
+}
+
+func paginate(lines []line) [][]line {
+	var pages [][]line
+	var page []line
+	used := 0.0
+	for _, l := range lines {
+		if used+l.style.leading > pageHeight-2*margin && len(page) > 0 {
+			pages = append(pages, page)
+			page, used = nil, 0
+		}
+		// A blank line at the top of a page adds nothing
+		if len(page) == 0 && l.text == "" {
+			continue
+		}
+		page = append(page, l)
+		used += l.style.leading
+	}
+	if len(page) > 0 || len(pages) == 0 {
+		pages = append(pages, page)
+	}
+	return pages
+}
+
+func contentStream(page []line) []byte {
+	var b bytes.Buffer
+	y := float64(pageHeight - margin)
+	for _, l := range page {
+		y -= l.style.leading
+		if l.text == "" {
+			continue
+		}
+		fmt.Fprintf(&b, "BT /%s %g Tf %d %g Td (%s) Tj ET\n", l.style.font, l.style.size, margin, y, escape(l.text))
+	}
+	return b.Bytes()
+}
+
+// escape makes s safe inside a PDF literal string.
+func escape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '\\' || r == '(' || r == ')':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r >= 0x20 && r < 0x7f:
+			b.WriteRune(r)
+		case r == '\t':
+			b.WriteString("    ")
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
+
+// write serializes the pages. Object 1 is the catalog, 2 the page tree, 3
+// and 4 the regular and bold fonts, 5 the document info, and each page
+// takes a page object and a content stream after that.
+func write(title string, pages [][]line) []byte {
+	var b bytes.Buffer
+	var offsets []int
+	object := func(body string) {
+		offsets = append(offsets, b.Len())
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	b.WriteString("%PDF-1.4\n")
+
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 6+2*i)
+	}
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	object(fmt.Sprintf("<< /Title (%s) /Producer (loan-origination-system) >>", escape(title)))
+
+	for i, page := range pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			pageWidth, pageHeight, 7+2*i))
+		content := contentStream(page)
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", len(content), content))
+	}
+
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root 1 0 R /Info 5 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	return b.Bytes()
+}
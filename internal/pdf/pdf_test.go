@@ -0,0 +1,54 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromText(t *testing.T) {
+	doc := FromText("Loan (Agreement)", "# Loan Agreement\n\nBorrower: Jane (Doe) \\ café\n")
+
+	require.True(t, bytes.HasPrefix(doc, []byte("%PDF-1.4\n")))
+	require.True(t, bytes.HasSuffix(doc, []byte("%%EOF\n")))
+	require.Contains(t, string(doc), "/Count 1")
+	require.Contains(t, string(doc), "/Title (Loan \\(Agreement\\))")
+	require.Contains(t, string(doc), "/F2 13 Tf 72 698 Td (Loan Agreement) Tj")
+	require.Contains(t, string(doc), "(Borrower: Jane \\(Doe\\) \\\\ caf?) Tj")
+
+	// Output is deterministic, so its hash identifies the content
+	require.Equal(t, doc, FromText("Loan (Agreement)", "# Loan Agreement\n\nBorrower: Jane (Doe) \\ café\n"))
+}
+
+func TestFromText_XrefOffsetsPointAtObjects(t *testing.T) {
+	doc := FromText("Offsets", strings.Repeat("A line of text.\n", 120))
+
+	startxref := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(doc)
+	require.NotNil(t, startxref)
+	xref, err := strconv.Atoi(string(startxref[1]))
+	require.NoError(t, err)
+	require.True(t, bytes.HasPrefix(doc[xref:], []byte("xref\n")))
+
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(doc[xref:], -1)
+	require.NotEmpty(t, entries)
+	for i, entry := range entries {
+		offset, err := strconv.Atoi(string(entry[1]))
+		require.NoError(t, err)
+		require.True(t, bytes.HasPrefix(doc[offset:], []byte(fmt.Sprintf("%d 0 obj\n", i+1))), "object %d", i+1)
+	}
+
+	// 120 lines at 14pt do not fit on one page
+	require.Contains(t, string(doc), "/Count 3")
+}
+
+func TestWrap(t *testing.T) {
+	require.Equal(t, []string{"one two", "three"}, wrap("one two three", 8))
+	require.Equal(t, []string{"  one", "  two"}, wrap("  one two", 6))
+	require.Equal(t, []string{"abcd", "efgh", "ij"}, wrap("abcdefghij", 4))
+	require.Equal(t, []string{""}, wrap("", 10))
+}
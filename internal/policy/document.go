@@ -0,0 +1,59 @@
+package policy
+
+// DocumentType is the kind of document a borrower provides. Required
+// documents are tracked by type, so an upload only satisfies the checklist
+// item it matches.
+type DocumentType string
+
+const (
+	DocumentIDProof                DocumentType = "id_proof"
+	DocumentPayStub                DocumentType = "pay_stub"
+	DocumentIncomeStatement        DocumentType = "income_statement"
+	DocumentBankStatement          DocumentType = "bank_statement"
+	DocumentEmploymentVerification DocumentType = "employment_verification"
+	DocumentTaxReturns             DocumentType = "tax_returns"
+	DocumentPurchaseAgreement      DocumentType = "purchase_agreement"
+	DocumentDriversLicense         DocumentType = "drivers_license"
+	DocumentProofOfInsurance       DocumentType = "proof_of_insurance"
+	DocumentMortgageStatement      DocumentType = "mortgage_statement"
+	DocumentHomeownersInsurance    DocumentType = "homeowners_insurance"
+	// DocumentOther is accepted as a supporting upload but never satisfies
+	// a required document
+	DocumentOther DocumentType = "other"
+)
+
+// DocumentAdverseActionNotice is generated for the borrower when a loan is
+// declined. It is not in DocumentTypes, so it cannot be uploaded.
+const DocumentAdverseActionNotice DocumentType = "adverse_action_notice"
+
+// DocumentTypes lists every document type a borrower can upload.
+var DocumentTypes = []DocumentType{
+	DocumentIDProof,
+	DocumentPayStub,
+	DocumentIncomeStatement,
+	DocumentBankStatement,
+	DocumentEmploymentVerification,
+	DocumentTaxReturns,
+	DocumentPurchaseAgreement,
+	DocumentDriversLicense,
+	DocumentProofOfInsurance,
+	DocumentMortgageStatement,
+	DocumentHomeownersInsurance,
+	DocumentOther,
+}
+
+// Valid reports whether t is a known document type.
+func (t DocumentType) Valid() bool {
+	for _, documentType := range DocumentTypes {
+		if t == documentType {
+			return true
+		}
+	}
+	return false
+}
+
+// Requirable reports whether t can be required of the borrower. "other"
+// names nothing in particular, so it cannot.
+func (t DocumentType) Requirable() bool {
+	return t.Valid() && t != DocumentOther
+}
@@ -0,0 +1,285 @@
+// Package policy defines the underwriting rules of each loan product: the
+// documents it requires, whether it needs an appraisal, its decisioning
+// thresholds and its SLA timers. Policies are loaded from a versioned YAML
+// file by the API server, and the product's rules are snapshotted into each
+// workflow when it starts so that replays never see a later version.
+package policy
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"loan-origination-system/internal/decisioning"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrUnknownProduct is returned for products the policy does not define.
+var ErrUnknownProduct = errors.New("unknown loan product")
+
+// Set is the content of a policy file.
+type Set struct {
+	// Version identifies the file, e.g. 2024-06-01; it is recorded on every
+	// loan started under it
+	Version        string                  `yaml:"version" json:"version"`
+	DefaultProduct LoanProduct             `yaml:"default_product" json:"default_product"`
+	Products       map[LoanProduct]Product `yaml:"products" json:"products"`
+}
+
+// Product holds the rules of one loan product.
+type Product struct {
+	Name        LoanProduct `yaml:"-" json:"name"`
+	Description string      `yaml:"description" json:"description"`
+	// RequiredDocuments lists the document types the borrower must provide
+	RequiredDocuments []DocumentType `yaml:"required_documents" json:"required_documents"`
+	// RequireAppraisal requires the collateral to be valued: an appraisal
+	// for property, a valuation for vehicles
+	RequireAppraisal bool               `yaml:"require_appraisal" json:"require_appraisal"`
+	Decisioning      decisioning.Policy `yaml:"decisioning" json:"decisioning"`
+	SLA              SLA                `yaml:"sla" json:"sla"`
+}
+
+// SLA holds how long each stage may wait before the loan times out.
+type SLA struct {
+	// Processing covers documents, appraisal and underwriting
+	Processing Duration `yaml:"processing" json:"processing"`
+	Funding    Duration `yaml:"funding" json:"funding"`
+	// CounterOffer is how long the borrower has to answer a counter-offer.
+	// Zero means DefaultCounterOfferExpiry.
+	CounterOffer Duration `yaml:"counter_offer,omitempty" json:"counter_offer,omitempty"`
+	// Conditions is how long the conditions of an approval may stay open
+	// before funding. Zero means DefaultConditionsWindow.
+	Conditions Duration `yaml:"conditions,omitempty" json:"conditions,omitempty"`
+	// Signature is how long the borrower has to sign the loan agreement.
+	// Zero means DefaultSignatureExpiry.
+	Signature Duration `yaml:"signature,omitempty" json:"signature,omitempty"`
+}
+
+// DefaultCounterOfferExpiry applies to policies that do not set
+// sla.counter_offer, including snapshots taken before it existed.
+const DefaultCounterOfferExpiry = 7 * 24 * time.Hour
+
+// CounterOfferExpiry returns how long a counter-offer stays open.
+func (s SLA) CounterOfferExpiry() time.Duration {
+	if s.CounterOffer <= 0 {
+		return DefaultCounterOfferExpiry
+	}
+	return time.Duration(s.CounterOffer)
+}
+
+// DefaultConditionsWindow applies to policies that do not set
+// sla.conditions.
+const DefaultConditionsWindow = 30 * 24 * time.Hour
+
+// ConditionsWindow returns how long approval conditions may stay open.
+func (s SLA) ConditionsWindow() time.Duration {
+	if s.Conditions <= 0 {
+		return DefaultConditionsWindow
+	}
+	return time.Duration(s.Conditions)
+}
+
+// DefaultSignatureExpiry applies to policies that do not set sla.signature.
+const DefaultSignatureExpiry = 14 * 24 * time.Hour
+
+// SignatureExpiry returns how long a signing request stays open.
+func (s SLA) SignatureExpiry() time.Duration {
+	if s.Signature <= 0 {
+		return DefaultSignatureExpiry
+	}
+	return time.Duration(s.Signature)
+}
+
+// Snapshot is the policy a loan was started under.
+type Snapshot struct {
+	Version string  `json:"version"`
+	Product Product `json:"product"`
+}
+
+// Default returns the built-in policy used when no policy file is
+// configured.
+func Default() *Set {
+	return &Set{
+		Version:        "builtin",
+		DefaultProduct: ProductMortgage,
+		Products: map[LoanProduct]Product{
+			ProductMortgage: {
+				Name:              ProductMortgage,
+				Description:       "Home purchase or refinance secured by the property",
+				RequiredDocuments: []DocumentType{DocumentIncomeStatement, DocumentBankStatement},
+				RequireAppraisal:  true,
+				Decisioning:       decisioning.DefaultPolicy(),
+				SLA: SLA{
+					Processing:   Days(30),
+					Funding:      Days(7),
+					CounterOffer: Days(7),
+					Conditions:   Days(30),
+					Signature:    Days(14),
+				},
+			},
+			ProductAuto: {
+				Name:              ProductAuto,
+				Description:       "New or used vehicle purchase secured by the vehicle",
+				RequiredDocuments: []DocumentType{DocumentIncomeStatement, DocumentDriversLicense, DocumentProofOfInsurance},
+				RequireAppraisal:  true,
+				Decisioning: decisioning.Policy{
+					MinCreditScore:    600,
+					ReferCreditScore:  660,
+					MaxLTV:            1.25,
+					ReferLTV:          1.00,
+					MaxDTI:            0.50,
+					ReferDTI:          0.45,
+					AssumedRate:       0.08,
+					AssumedTermMonths: 72,
+				},
+				SLA: SLA{
+					Processing:   Days(7),
+					Funding:      Days(3),
+					CounterOffer: Days(3),
+					Conditions:   Days(7),
+					Signature:    Days(7),
+				},
+			},
+			ProductPersonal: {
+				Name:              ProductPersonal,
+				Description:       "Unsecured personal loan",
+				RequiredDocuments: []DocumentType{DocumentIncomeStatement, DocumentBankStatement},
+				Decisioning: decisioning.Policy{
+					MinCreditScore:    640,
+					ReferCreditScore:  680,
+					MaxDTI:            0.40,
+					ReferDTI:          0.36,
+					RequireIncome:     true,
+					AssumedRate:       0.12,
+					AssumedTermMonths: 60,
+				},
+				SLA: SLA{
+					Processing:   Days(5),
+					Funding:      Days(3),
+					CounterOffer: Days(3),
+					Conditions:   Days(7),
+					Signature:    Days(7),
+				},
+			},
+			ProductHELOC: {
+				Name:              ProductHELOC,
+				Description:       "Home equity line of credit behind the first mortgage",
+				RequiredDocuments: []DocumentType{DocumentIncomeStatement, DocumentMortgageStatement, DocumentHomeownersInsurance},
+				RequireAppraisal:  true,
+				Decisioning: decisioning.Policy{
+					MinCreditScore:    680,
+					ReferCreditScore:  720,
+					MaxLTV:            0.90,
+					ReferLTV:          0.80,
+					MaxDTI:            0.45,
+					ReferDTI:          0.43,
+					AssumedRate:       0.09,
+					AssumedTermMonths: 120,
+				},
+				SLA: SLA{
+					Processing:   Days(30),
+					Funding:      Days(7),
+					CounterOffer: Days(7),
+					Conditions:   Days(30),
+					Signature:    Days(14),
+				},
+			},
+		},
+	}
+}
+
+// Load reads the policy file at path, or returns Default if path is empty.
+func Load(path string) (*Set, error) {
+	if path == "" {
+		return Default(), nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read policy file: %w", err)
+	}
+	return Parse(data)
+}
+
+// Parse decodes and validates a policy file.
+func Parse(data []byte) (*Set, error) {
+	var set Set
+	if err := yaml.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("parse policy file: %w", err)
+	}
+	for name, product := range set.Products {
+		product.Name = name
+		set.Products[name] = product
+	}
+	if err := set.Validate(); err != nil {
+		return nil, err
+	}
+	return &set, nil
+}
+
+// Validate reports policies that cannot be applied.
+func (s *Set) Validate() error {
+	if s.Version == "" {
+		return errors.New("policy version is required")
+	}
+	if len(s.Products) == 0 {
+		return errors.New("policy defines no products")
+	}
+	if _, ok := s.Products[s.DefaultProduct]; !ok {
+		return fmt.Errorf("default_product %q: %w", s.DefaultProduct, ErrUnknownProduct)
+	}
+
+	for _, name := range s.ProductNames() {
+		product := s.Products[name]
+		if !name.Valid() {
+			return fmt.Errorf("product %q: %w", name, ErrUnknownProduct)
+		}
+		for _, documentType := range product.RequiredDocuments {
+			if !documentType.Requirable() {
+				return fmt.Errorf("product %s: %q cannot be a required document", name, documentType)
+			}
+		}
+		if product.RequireAppraisal && name.Collateral() == CollateralNone {
+			return fmt.Errorf("product %s has no collateral to appraise", name)
+		}
+		if err := product.Decisioning.Validate(); err != nil {
+			return fmt.Errorf("product %s decisioning: %w", name, err)
+		}
+		if product.SLA.Processing <= 0 || product.SLA.Funding <= 0 {
+			return fmt.Errorf("product %s: sla processing and funding are required", name)
+		}
+		if product.SLA.CounterOffer < 0 || product.SLA.Conditions < 0 || product.SLA.Signature < 0 {
+			return fmt.Errorf("product %s: sla counter_offer, conditions and signature cannot be negative", name)
+		}
+		if product.Decisioning.MaxLTV > 0 && !product.RequireAppraisal {
+			return fmt.Errorf("product %s: max_ltv needs require_appraisal for the property value", name)
+		}
+	}
+	return nil
+}
+
+// ProductNames returns the product names in order.
+func (s *Set) ProductNames() []LoanProduct {
+	names := make([]LoanProduct, 0, len(s.Products))
+	for name := range s.Products {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i] < names[j] })
+	return names
+}
+
+// Snapshot returns the rules of product, or of the default product if it is
+// empty, to start a loan with.
+func (s *Set) Snapshot(product LoanProduct) (Snapshot, error) {
+	if product == "" {
+		product = s.DefaultProduct
+	}
+	rules, ok := s.Products[product]
+	if !ok {
+		return Snapshot{}, fmt.Errorf("%w %q", ErrUnknownProduct, product)
+	}
+	return Snapshot{Version: s.Version, Product: rules}, nil
+}
@@ -0,0 +1,94 @@
+package policy
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad_ExampleFile(t *testing.T) {
+	set, err := Load(filepath.Join("..", "..", "policies.example.yaml"))
+	require.NoError(t, err)
+
+	require.Equal(t, "2024-06-01", set.Version)
+	mortgage := set.Products["mortgage"]
+	require.Equal(t, ProductMortgage, mortgage.Name)
+	require.Equal(t, []DocumentType{DocumentIncomeStatement, DocumentBankStatement}, mortgage.RequiredDocuments)
+	require.True(t, mortgage.RequireAppraisal)
+	require.Equal(t, 0.97, mortgage.Decisioning.MaxLTV)
+	require.Equal(t, Days(30), mortgage.SLA.Processing)
+
+	// The example documents the built-in rules for every product
+	require.Equal(t, Default().ProductNames(), set.ProductNames())
+	for name, builtin := range Default().Products {
+		builtin.Description = set.Products[name].Description
+		require.Equal(t, builtin, set.Products[name], name)
+	}
+}
+
+func TestLoad_EmptyPathUsesDefault(t *testing.T) {
+	set, err := Load("")
+	require.NoError(t, err)
+	require.Equal(t, Default(), set)
+	require.NoError(t, set.Validate())
+}
+
+func TestParse_RejectsInvalidPolicies(t *testing.T) {
+	tests := map[string]string{
+		"no version":       "default_product: a\nproducts: {a: {}}",
+		"unknown default":  "version: v1\ndefault_product: b\nproducts: {a: {}}",
+		"missing sla":      "version: v1\ndefault_product: a\nproducts: {a: {decisioning: {assumed_term_months: 12}}}",
+		"bad duration":     "version: v1\ndefault_product: a\nproducts: {a: {sla: {processing: soon}}}",
+		"unknown document": "version: v1\ndefault_product: personal\nproducts: {personal: {required_documents: [selfie], sla: {processing: 1d, funding: 1d}}}",
+		"other document":   "version: v1\ndefault_product: personal\nproducts: {personal: {required_documents: [other], sla: {processing: 1d, funding: 1d}}}",
+		"ltv without appr": "version: v1\ndefault_product: a\nproducts: {a: {decisioning: {max_ltv: 0.8, assumed_term_months: 12}, sla: {processing: 1d, funding: 1d}}}",
+		"negative sla":     "version: v1\ndefault_product: personal\nproducts: {personal: {sla: {processing: 1d, funding: 1d, signature: -1h}}}",
+	}
+	for name, data := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := Parse([]byte(data))
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policies.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+version: v2
+default_product: personal
+products:
+  personal:
+    required_documents: [income_statement]
+    decisioning: {min_credit_score: 640, refer_credit_score: 680, max_dti: 0.4, refer_dti: 0.35, assumed_rate: 0.12, assumed_term_months: 60}
+    sla: {processing: 72h, funding: 2d}
+`), 0o600))
+	set, err := Load(path)
+	require.NoError(t, err)
+
+	snapshot, err := set.Snapshot("")
+	require.NoError(t, err)
+	require.Equal(t, "v2", snapshot.Version)
+	require.Equal(t, ProductPersonal, snapshot.Product.Name)
+	require.False(t, snapshot.Product.RequireAppraisal)
+	require.Equal(t, Duration(72*time.Hour), snapshot.Product.SLA.Processing)
+	require.Equal(t, DefaultSignatureExpiry, snapshot.Product.SLA.SignatureExpiry())
+
+	_, err = set.Snapshot("mortgage")
+	require.ErrorIs(t, err, ErrUnknownProduct)
+}
+
+func TestDuration_JSONRoundTrip(t *testing.T) {
+	data, err := json.Marshal(SLA{Processing: Days(30), Funding: Duration(90 * time.Minute)})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"processing": "30d", "funding": "1h30m0s"}`, string(data))
+
+	var sla SLA
+	require.NoError(t, json.Unmarshal(data, &sla))
+	require.Equal(t, Days(30), sla.Processing)
+	require.Equal(t, Duration(90*time.Minute), sla.Funding)
+}
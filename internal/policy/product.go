@@ -0,0 +1,55 @@
+package policy
+
+// LoanProduct is the kind of loan applied for. It decides which stages the
+// workflow runs: how the collateral is valued and whether existing liens
+// are checked.
+type LoanProduct string
+
+const (
+	ProductMortgage LoanProduct = "mortgage"
+	ProductAuto     LoanProduct = "auto"
+	ProductPersonal LoanProduct = "personal"
+	ProductHELOC    LoanProduct = "heloc"
+)
+
+// LoanProducts lists every product.
+var LoanProducts = []LoanProduct{ProductMortgage, ProductAuto, ProductPersonal, ProductHELOC}
+
+// Valid reports whether p is a known product.
+func (p LoanProduct) Valid() bool {
+	for _, product := range LoanProducts {
+		if p == product {
+			return true
+		}
+	}
+	return false
+}
+
+// Collateral is what secures a loan.
+type Collateral string
+
+const (
+	CollateralNone     Collateral = ""
+	CollateralProperty Collateral = "property"
+	CollateralVehicle  Collateral = "vehicle"
+)
+
+// Collateral returns what secures loans of this product. Property is
+// valued by an appraiser and vehicles by a valuation guide.
+func (p LoanProduct) Collateral() Collateral {
+	switch p {
+	case ProductMortgage, ProductHELOC:
+		return CollateralProperty
+	case ProductAuto:
+		return CollateralVehicle
+	default:
+		return CollateralNone
+	}
+}
+
+// RequiresLienCheck reports whether existing liens on the collateral must
+// be found before underwriting. HELOCs sit behind the first mortgage, so
+// their LTV includes its balance.
+func (p LoanProduct) RequiresLienCheck() bool {
+	return p == ProductHELOC
+}
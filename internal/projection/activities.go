@@ -0,0 +1,18 @@
+package projection
+
+import (
+	"context"
+
+	"loan-origination-system/internal/workflows"
+)
+
+// Activities writes workflow state changes to the projection store. The
+// workflow calls ProjectLoanState by name (workflows.ProjectLoanStateActivity)
+// because this package depends on the workflow types.
+type Activities struct {
+	Store *Store
+}
+
+func (a *Activities) ProjectLoanState(ctx context.Context, state workflows.LoanOriginationState) error {
+	return a.Store.SaveLoan(ctx, state)
+}
@@ -0,0 +1,44 @@
+package projection
+
+import (
+	"context"
+
+	"loan-origination-system/internal/workflows"
+)
+
+// LoanChange is a projected loan snapshot and the sequence it was saved at.
+// Sequences increase with every save across all loans, so a reader can follow
+// changes by remembering the last sequence it saw.
+type LoanChange struct {
+	Sequence int64
+	State    workflows.LoanOriginationState
+}
+
+// LatestSequence returns the sequence of the most recent save, or 0 if
+// nothing has been projected.
+func (s *Store) LatestSequence(ctx context.Context) (int64, error) {
+	var latest int64
+	err := s.db.WithContext(ctx).Model(&LoanRecord{}).Select("COALESCE(MAX(sequence), 0)").Scan(&latest).Error
+	return latest, err
+}
+
+// ChangesSince returns up to limit loans saved after sequence, oldest first.
+// Only the latest snapshot of each loan is kept, so a loan saved several
+// times since sequence appears once. An empty loanID matches every loan.
+func (s *Store) ChangesSince(ctx context.Context, sequence int64, loanID string, limit int) ([]LoanChange, error) {
+	query := s.preload(ctx).Where("sequence > ?", sequence)
+	if loanID != "" {
+		query = query.Where("id = ?", loanID)
+	}
+
+	var loans []LoanRecord
+	if err := query.Order("sequence").Limit(limit).Find(&loans).Error; err != nil {
+		return nil, err
+	}
+
+	changes := make([]LoanChange, 0, len(loans))
+	for _, loan := range loans {
+		changes = append(changes, LoanChange{Sequence: loan.Sequence, State: loan.toState()})
+	}
+	return changes, nil
+}
@@ -0,0 +1,259 @@
+package projection
+
+import (
+	"loan-origination-system/internal/creditbureau"
+	"loan-origination-system/internal/decisioning"
+	"loan-origination-system/internal/policy"
+	"loan-origination-system/internal/workflows"
+)
+
+func toLoanRecord(state workflows.LoanOriginationState) LoanRecord {
+	app := state.LoanApplication
+	loan := LoanRecord{
+		ID:                  app.ID,
+		WorkflowID:          app.WorkflowID,
+		BorrowerName:        app.BorrowerName,
+		BorrowerEmail:       app.BorrowerEmail,
+		BorrowerPhone:       app.BorrowerPhone,
+		LoanAmount:          app.LoanAmount,
+		LoanPurpose:         app.LoanPurpose,
+		MonthlyIncome:       app.MonthlyIncome,
+		Product:             string(app.Product),
+		PropertyAddress:     app.PropertyAddress,
+		Vehicle:             app.Vehicle,
+		Status:              state.Status,
+		NextStep:            state.NextStep,
+		Policy:              state.Policy,
+		VehicleValuation:    state.VehicleValuation,
+		LienCheck:           state.LienCheck,
+		Closure:             state.Closure,
+		Terms:               state.Terms,
+		CounterOffer:        state.CounterOffer,
+		LoanAgreement:       state.LoanAgreement,
+		Signature:           state.Signature,
+		Funding:             state.Funding,
+		FundingFailures:     state.FundingFailures,
+		Conditions:          state.Conditions,
+		AdverseActionNotice: state.AdverseActionNotice,
+		Notifications:       state.Notifications,
+		CreatedBy:           app.CreatedBy,
+		// Timestamps are stored in UTC so they order correctly as text
+		CreatedAt: app.CreatedAt.UTC(),
+		UpdatedAt: app.UpdatedAt.UTC(),
+	}
+
+	for i, doc := range state.Documents {
+		loan.Documents = append(loan.Documents, DocumentRecord{
+			ID:                  doc.ID,
+			LoanID:              app.ID,
+			Position:            i,
+			DocumentType:        string(doc.DocumentType),
+			FileName:            doc.FileName,
+			FilePath:            doc.FilePath,
+			ContentType:         doc.ContentType,
+			Size:                doc.Size,
+			SHA256:              doc.SHA256,
+			VerificationStatus:  doc.VerificationStatus,
+			VerificationDetails: doc.VerificationDetails,
+			UploadedBy:          doc.UploadedBy,
+			VerifiedBy:          doc.VerifiedBy,
+			UploadedAt:          doc.UploadedAt,
+			VerifiedAt:          doc.VerifiedAt,
+		})
+	}
+
+	for i, item := range state.DocumentChecklist {
+		loan.Checklist = append(loan.Checklist, ChecklistItemRecord{
+			LoanID:       app.ID,
+			Position:     i,
+			DocumentType: string(item.DocumentType),
+			Status:       item.Status,
+			DocumentID:   item.DocumentID,
+			Reason:       item.Reason,
+			RequestedBy:  item.RequestedBy,
+			RequestedAt:  item.RequestedAt,
+		})
+	}
+
+	if a := state.Appraisal; a != nil {
+		loan.Appraisal = &AppraisalRecord{
+			ID:             a.ID,
+			LoanID:         app.ID,
+			PropertyValue:  a.PropertyValue,
+			AppraisalNotes: a.AppraisalNotes,
+			AppraiserID:    a.AppraiserID,
+			Status:         a.Status,
+			CompletedAt:    a.CompletedAt,
+			CreatedAt:      a.CreatedAt,
+		}
+	}
+
+	if cs := state.CreditScore; cs != nil {
+		loan.CreditScore = &CreditScoreRecord{
+			ID:              cs.ID,
+			LoanID:          app.ID,
+			Score:           cs.Score,
+			Status:          cs.Status,
+			Bureau:          cs.Bureau,
+			PullType:        string(cs.PullType),
+			ReportReference: cs.ReportReference,
+			ScoreModel:      cs.ScoreModel,
+			Tradelines:      cs.Tradelines,
+			Inquiries:       cs.Inquiries,
+			Error:           cs.Error,
+			CompletedAt:     cs.CompletedAt,
+			CreatedAt:       cs.CreatedAt,
+		}
+	}
+
+	if r := state.Recommendation; r != nil {
+		loan.Recommendation = &RecommendationRecord{
+			LoanID:                  app.ID,
+			Recommendation:          string(r.Recommendation),
+			Reasons:                 r.Reasons,
+			LTV:                     r.LTV,
+			DTI:                     r.DTI,
+			CreditTier:              string(r.CreditTier),
+			EstimatedMonthlyPayment: r.EstimatedMonthlyPayment,
+			EvaluatedAt:             r.EvaluatedAt,
+		}
+	}
+
+	if d := state.UnderwritingDecision; d != nil {
+		loan.Decision = &DecisionRecord{
+			ID:             d.ID,
+			LoanID:         app.ID,
+			Decision:       d.Decision,
+			Comments:       d.Comments,
+			UnderwriterID:  d.UnderwriterID,
+			Recommendation: string(d.Recommendation),
+			DecisionDate:   d.DecisionDate,
+		}
+	}
+
+	return loan
+}
+
+func (loan LoanRecord) toState() workflows.LoanOriginationState {
+	state := workflows.LoanOriginationState{
+		LoanApplication: workflows.LoanApplication{
+			ID:              loan.ID,
+			BorrowerName:    loan.BorrowerName,
+			BorrowerEmail:   loan.BorrowerEmail,
+			BorrowerPhone:   loan.BorrowerPhone,
+			LoanAmount:      loan.LoanAmount,
+			LoanPurpose:     loan.LoanPurpose,
+			MonthlyIncome:   loan.MonthlyIncome,
+			Product:         policy.LoanProduct(loan.Product),
+			PropertyAddress: loan.PropertyAddress,
+			Vehicle:         loan.Vehicle,
+			Status:          loan.Status,
+			NextStep:        loan.NextStep,
+			CreatedBy:       loan.CreatedBy,
+			CreatedAt:       loan.CreatedAt,
+			UpdatedAt:       loan.UpdatedAt,
+			WorkflowID:      loan.WorkflowID,
+		},
+		Documents:           []workflows.Document{},
+		Policy:              loan.Policy,
+		VehicleValuation:    loan.VehicleValuation,
+		LienCheck:           loan.LienCheck,
+		Closure:             loan.Closure,
+		Terms:               loan.Terms,
+		CounterOffer:        loan.CounterOffer,
+		LoanAgreement:       loan.LoanAgreement,
+		Signature:           loan.Signature,
+		Funding:             loan.Funding,
+		FundingFailures:     loan.FundingFailures,
+		Conditions:          loan.Conditions,
+		AdverseActionNotice: loan.AdverseActionNotice,
+		Notifications:       loan.Notifications,
+		Status:              loan.Status,
+		NextStep:            loan.NextStep,
+	}
+
+	for _, doc := range loan.Documents {
+		state.Documents = append(state.Documents, workflows.Document{
+			ID:                  doc.ID,
+			DocumentType:        policy.DocumentType(doc.DocumentType),
+			FileName:            doc.FileName,
+			FilePath:            doc.FilePath,
+			ContentType:         doc.ContentType,
+			Size:                doc.Size,
+			SHA256:              doc.SHA256,
+			VerificationStatus:  doc.VerificationStatus,
+			VerificationDetails: doc.VerificationDetails,
+			UploadedBy:          doc.UploadedBy,
+			VerifiedBy:          doc.VerifiedBy,
+			UploadedAt:          doc.UploadedAt,
+			VerifiedAt:          doc.VerifiedAt,
+		})
+	}
+
+	for _, item := range loan.Checklist {
+		state.DocumentChecklist = append(state.DocumentChecklist, workflows.ChecklistItem{
+			DocumentType: policy.DocumentType(item.DocumentType),
+			Status:       item.Status,
+			DocumentID:   item.DocumentID,
+			Reason:       item.Reason,
+			RequestedBy:  item.RequestedBy,
+			RequestedAt:  item.RequestedAt,
+		})
+	}
+
+	if a := loan.Appraisal; a != nil {
+		state.Appraisal = &workflows.Appraisal{
+			ID:             a.ID,
+			PropertyValue:  a.PropertyValue,
+			AppraisalNotes: a.AppraisalNotes,
+			AppraiserID:    a.AppraiserID,
+			Status:         a.Status,
+			CompletedAt:    a.CompletedAt,
+			CreatedAt:      a.CreatedAt,
+		}
+	}
+
+	if cs := loan.CreditScore; cs != nil {
+		state.CreditScore = &workflows.CreditScore{
+			ID:              cs.ID,
+			Score:           cs.Score,
+			Status:          cs.Status,
+			Bureau:          cs.Bureau,
+			PullType:        creditbureau.PullType(cs.PullType),
+			ReportReference: cs.ReportReference,
+			ScoreModel:      cs.ScoreModel,
+			Tradelines:      cs.Tradelines,
+			Inquiries:       cs.Inquiries,
+			Error:           cs.Error,
+			CompletedAt:     cs.CompletedAt,
+			CreatedAt:       cs.CreatedAt,
+		}
+	}
+
+	if r := loan.Recommendation; r != nil {
+		state.Recommendation = &workflows.Recommendation{
+			Result: decisioning.Result{
+				Recommendation:          decisioning.Recommendation(r.Recommendation),
+				Reasons:                 r.Reasons,
+				LTV:                     r.LTV,
+				DTI:                     r.DTI,
+				CreditTier:              decisioning.CreditTier(r.CreditTier),
+				EstimatedMonthlyPayment: r.EstimatedMonthlyPayment,
+			},
+			EvaluatedAt: r.EvaluatedAt,
+		}
+	}
+
+	if d := loan.Decision; d != nil {
+		state.UnderwritingDecision = &workflows.UnderwritingDecision{
+			ID:             d.ID,
+			Decision:       d.Decision,
+			Comments:       d.Comments,
+			UnderwriterID:  d.UnderwriterID,
+			Recommendation: decisioning.Recommendation(d.Recommendation),
+			DecisionDate:   d.DecisionDate,
+		}
+	}
+
+	return state
+}
@@ -0,0 +1,155 @@
+package projection
+
+import (
+	"time"
+
+	"loan-origination-system/internal/creditbureau"
+	"loan-origination-system/internal/decisioning"
+	"loan-origination-system/internal/policy"
+	"loan-origination-system/internal/workflows"
+)
+
+// LoanRecord is the projected row for a loan application and its workflow
+// progress.
+type LoanRecord struct {
+	ID              string `gorm:"primaryKey"`
+	WorkflowID      string `gorm:"index"`
+	BorrowerName    string
+	BorrowerEmail   string
+	BorrowerPhone   string
+	LoanAmount      float64 `gorm:"index"`
+	LoanPurpose     string
+	MonthlyIncome   float64
+	Product         string `gorm:"index"`
+	PropertyAddress string
+	Vehicle         *workflows.Vehicle `gorm:"serializer:json"`
+	Status          string             `gorm:"index"`
+	NextStep        string
+	Policy          policy.Snapshot `gorm:"serializer:json"`
+	// Automated collateral checks are stored inline as JSON
+	VehicleValuation *workflows.VehicleValuation `gorm:"serializer:json"`
+	LienCheck        *workflows.LienCheck        `gorm:"serializer:json"`
+	Closure          *workflows.Closure          `gorm:"serializer:json"`
+	Terms            workflows.LoanTerms         `gorm:"serializer:json"`
+	CounterOffer     *workflows.CounterOffer     `gorm:"serializer:json"`
+	LoanAgreement    *workflows.LoanAgreement    `gorm:"serializer:json"`
+	Signature        *workflows.Signature        `gorm:"serializer:json"`
+	Funding          *workflows.Funding          `gorm:"serializer:json"`
+	FundingFailures  []workflows.FundingFailure  `gorm:"serializer:json"`
+	Conditions       []workflows.Condition       `gorm:"serializer:json"`
+	// AdverseActionNotice is set for declined loans; the notice itself is
+	// one of the loan's documents
+	AdverseActionNotice *workflows.AdverseActionNotice `gorm:"serializer:json"`
+	Notifications       []workflows.Notification       `gorm:"serializer:json"`
+	CreatedBy           string                         `gorm:"index"`
+	CreatedAt           time.Time                      `gorm:"index"`
+	UpdatedAt           time.Time                      `gorm:"index"`
+	// Sequence orders saves across all loans; see ChangesSince
+	Sequence int64 `gorm:"index"`
+
+	Documents      []DocumentRecord      `gorm:"foreignKey:LoanID"`
+	Checklist      []ChecklistItemRecord `gorm:"foreignKey:LoanID"`
+	Appraisal      *AppraisalRecord      `gorm:"foreignKey:LoanID"`
+	CreditScore    *CreditScoreRecord    `gorm:"foreignKey:LoanID"`
+	Recommendation *RecommendationRecord `gorm:"foreignKey:LoanID"`
+	Decision       *DecisionRecord       `gorm:"foreignKey:LoanID"`
+}
+
+func (LoanRecord) TableName() string { return "loans" }
+
+// DocumentRecord is a projected document uploaded for a loan.
+type DocumentRecord struct {
+	ID                  string `gorm:"primaryKey"`
+	LoanID              string `gorm:"index"`
+	Position            int
+	DocumentType        string
+	FileName            string
+	FilePath            string
+	ContentType         string
+	Size                int64
+	SHA256              string
+	VerificationStatus  string
+	VerificationDetails map[string]interface{} `gorm:"serializer:json"`
+	UploadedBy          string
+	VerifiedBy          string
+	UploadedAt          time.Time
+	VerifiedAt          *time.Time
+}
+
+func (DocumentRecord) TableName() string { return "documents" }
+
+// ChecklistItemRecord is a projected required-document checklist item.
+// Items are only ever added, so they are keyed by their position.
+type ChecklistItemRecord struct {
+	LoanID       string `gorm:"primaryKey"`
+	Position     int    `gorm:"primaryKey"`
+	DocumentType string
+	Status       string `gorm:"index"`
+	DocumentID   string
+	Reason       string
+	RequestedBy  string
+	RequestedAt  time.Time
+}
+
+func (ChecklistItemRecord) TableName() string { return "document_checklist" }
+
+// AppraisalRecord is a projected property appraisal.
+type AppraisalRecord struct {
+	ID             string `gorm:"primaryKey"`
+	LoanID         string `gorm:"uniqueIndex"`
+	PropertyValue  float64
+	AppraisalNotes string
+	AppraiserID    string
+	Status         string
+	CompletedAt    *time.Time
+	CreatedAt      time.Time
+}
+
+func (AppraisalRecord) TableName() string { return "appraisals" }
+
+// CreditScoreRecord is a projected credit score check and the report it
+// pulled.
+type CreditScoreRecord struct {
+	ID              string `gorm:"primaryKey"`
+	LoanID          string `gorm:"uniqueIndex"`
+	Score           int
+	Status          string
+	Bureau          string
+	PullType        string
+	ReportReference string
+	ScoreModel      string
+	Tradelines      []creditbureau.Tradeline `gorm:"serializer:json"`
+	Inquiries       int
+	Error           string
+	CompletedAt     *time.Time
+	CreatedAt       time.Time
+}
+
+func (CreditScoreRecord) TableName() string { return "credit_scores" }
+
+// RecommendationRecord is the projected automated decisioning result.
+type RecommendationRecord struct {
+	LoanID                  string               `gorm:"primaryKey"`
+	Recommendation          string               `gorm:"index"`
+	Reasons                 []decisioning.Reason `gorm:"serializer:json"`
+	LTV                     float64
+	DTI                     *float64
+	CreditTier              string
+	EstimatedMonthlyPayment float64
+	EvaluatedAt             time.Time
+}
+
+func (RecommendationRecord) TableName() string { return "recommendations" }
+
+// DecisionRecord is the latest projected underwriting decision.
+type DecisionRecord struct {
+	ID             string `gorm:"primaryKey"`
+	LoanID         string `gorm:"uniqueIndex"`
+	Decision       string
+	Comments       string
+	UnderwriterID  string
+	Recommendation string
+	DecisionDate   time.Time
+}
+
+func (DecisionRecord) TableName() string { return "decisions" }
@@ -0,0 +1,180 @@
+package projection
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"loan-origination-system/internal/workflows"
+)
+
+const (
+	DefaultPageSize = 50
+	MaxPageSize     = 200
+)
+
+// ErrInvalidFilter is returned for unknown sort fields and malformed page
+// tokens.
+var ErrInvalidFilter = errors.New("invalid loan filter")
+
+// sortColumns maps the sort fields accepted by ListLoans to their columns.
+var sortColumns = map[string]string{
+	"created_at":  "created_at",
+	"updated_at":  "updated_at",
+	"loan_amount": "loan_amount",
+}
+
+// LoanFilter selects, orders and pages projected loans. Zero values mean no
+// restriction.
+type LoanFilter struct {
+	Statuses      []string
+	NextStep      string // prefix match
+	CreatedBy     string
+	Products      []string
+	MinAmount     *float64
+	MaxAmount     *float64
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	Sort          string // field name, prefixed with "-" for descending
+	PageSize      int
+	PageToken     string
+}
+
+// LoanPage is one page of ListLoans results.
+type LoanPage struct {
+	Loans         []workflows.LoanOriginationState
+	NextPageToken string
+}
+
+// pageCursor is the keyset position encoded in a page token: the sort value
+// and ID of the last loan on the previous page.
+type pageCursor struct {
+	Sort   string     `json:"s"`
+	Time   *time.Time `json:"t,omitempty"`
+	Amount *float64   `json:"a,omitempty"`
+	ID     string     `json:"id"`
+}
+
+// ListLoans returns the page of projected loans matching filter. Results are
+// ordered by the sort field and then by ID so page tokens stay stable while
+// new loans are added.
+func (s *Store) ListLoans(ctx context.Context, filter LoanFilter) (LoanPage, error) {
+	if filter.Sort == "" {
+		filter.Sort = "-created_at"
+	}
+	field := strings.TrimPrefix(filter.Sort, "-")
+	descending := field != filter.Sort
+	column, ok := sortColumns[field]
+	if !ok {
+		return LoanPage{}, fmt.Errorf("%w: unknown sort field %q", ErrInvalidFilter, field)
+	}
+
+	pageSize := filter.PageSize
+	if pageSize <= 0 {
+		pageSize = DefaultPageSize
+	}
+	if pageSize > MaxPageSize {
+		pageSize = MaxPageSize
+	}
+
+	query := s.preload(ctx)
+	if len(filter.Statuses) > 0 {
+		query = query.Where("status IN ?", filter.Statuses)
+	}
+	if filter.NextStep != "" {
+		query = query.Where(`next_step LIKE ? ESCAPE '\'`, escapeLike(filter.NextStep)+"%")
+	}
+	if filter.CreatedBy != "" {
+		query = query.Where("created_by = ?", filter.CreatedBy)
+	}
+	if len(filter.Products) > 0 {
+		query = query.Where("product IN ?", filter.Products)
+	}
+	if filter.MinAmount != nil {
+		query = query.Where("loan_amount >= ?", *filter.MinAmount)
+	}
+	if filter.MaxAmount != nil {
+		query = query.Where("loan_amount <= ?", *filter.MaxAmount)
+	}
+	if filter.CreatedAfter != nil {
+		query = query.Where("created_at >= ?", filter.CreatedAfter.UTC())
+	}
+	if filter.CreatedBefore != nil {
+		query = query.Where("created_at < ?", filter.CreatedBefore.UTC())
+	}
+
+	direction, op := "ASC", ">"
+	if descending {
+		direction, op = "DESC", "<"
+	}
+
+	if filter.PageToken != "" {
+		cursor, err := decodePageToken(filter.PageToken)
+		if err != nil || cursor.Sort != filter.Sort {
+			return LoanPage{}, fmt.Errorf("%w: page token does not match this query", ErrInvalidFilter)
+		}
+		var value interface{}
+		if cursor.Amount != nil {
+			value = *cursor.Amount
+		} else if cursor.Time != nil {
+			value = cursor.Time.UTC()
+		}
+		query = query.Where(
+			fmt.Sprintf("(%s %s ?) OR (%s = ? AND id %s ?)", column, op, column, op),
+			value, value, cursor.ID,
+		)
+	}
+
+	var loans []LoanRecord
+	err := query.
+		Order(column + " " + direction).
+		Order("id " + direction).
+		Limit(pageSize + 1).
+		Find(&loans).Error
+	if err != nil {
+		return LoanPage{}, err
+	}
+
+	page := LoanPage{Loans: make([]workflows.LoanOriginationState, 0, len(loans))}
+	if len(loans) > pageSize {
+		loans = loans[:pageSize]
+		page.NextPageToken = encodePageToken(filter.Sort, loans[len(loans)-1])
+	}
+	for _, loan := range loans {
+		page.Loans = append(page.Loans, loan.toState())
+	}
+	return page, nil
+}
+
+func encodePageToken(sort string, last LoanRecord) string {
+	cursor := pageCursor{Sort: sort, ID: last.ID}
+	switch strings.TrimPrefix(sort, "-") {
+	case "loan_amount":
+		cursor.Amount = &last.LoanAmount
+	case "updated_at":
+		cursor.Time = &last.UpdatedAt
+	default:
+		cursor.Time = &last.CreatedAt
+	}
+
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodePageToken(token string) (pageCursor, error) {
+	var cursor pageCursor
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return cursor, err
+	}
+	err = json.Unmarshal(data, &cursor)
+	return cursor, err
+}
+
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
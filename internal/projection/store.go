@@ -0,0 +1,139 @@
+package projection
+
+import (
+	"context"
+	"errors"
+
+	"loan-origination-system/internal/workflows"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/logger"
+)
+
+// DefaultDatabasePath is the SQLite file shared by the worker, which writes
+// the projection, and the API server, which reads it.
+const DefaultDatabasePath = "loans.db"
+
+// ErrNotFound is returned when a loan has not been projected yet.
+var ErrNotFound = errors.New("loan not found in projection")
+
+// Store is the SQLite read model of loan workflow state.
+type Store struct {
+	db *gorm.DB
+}
+
+// Open opens (creating if needed) the projection database at path and
+// migrates its tables.
+func Open(path string) (*Store, error) {
+	// WAL mode lets the API server read while the worker writes
+	db, err := gorm.Open(sqlite.Open(path+"?_journal_mode=WAL&_busy_timeout=5000"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Warn),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.AutoMigrate(
+		&LoanRecord{},
+		&DocumentRecord{},
+		&ChecklistItemRecord{},
+		&AppraisalRecord{},
+		&CreditScoreRecord{},
+		&RecommendationRecord{},
+		&DecisionRecord{},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Store{db: db}, nil
+}
+
+// Close closes the underlying database connection.
+func (s *Store) Close() error {
+	sqlDB, err := s.db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
+
+// SaveLoan writes a snapshot of the workflow state, replacing whatever was
+// projected for the loan before, and gives it the next change sequence.
+func (s *Store) SaveLoan(ctx context.Context, state workflows.LoanOriginationState) error {
+	loan := toLoanRecord(state)
+
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		upsert := func(value interface{}) error {
+			return tx.Omit(clause.Associations).
+				Clauses(clause.OnConflict{UpdateAll: true}).
+				Create(value).Error
+		}
+
+		var latest int64
+		if err := tx.Model(&LoanRecord{}).Select("COALESCE(MAX(sequence), 0)").Scan(&latest).Error; err != nil {
+			return err
+		}
+		loan.Sequence = latest + 1
+
+		if err := upsert(&loan); err != nil {
+			return err
+		}
+		if len(loan.Documents) > 0 {
+			if err := upsert(&loan.Documents); err != nil {
+				return err
+			}
+		}
+		if len(loan.Checklist) > 0 {
+			if err := upsert(&loan.Checklist); err != nil {
+				return err
+			}
+		}
+		if loan.Appraisal != nil {
+			if err := upsert(loan.Appraisal); err != nil {
+				return err
+			}
+		}
+		if loan.CreditScore != nil {
+			if err := upsert(loan.CreditScore); err != nil {
+				return err
+			}
+		}
+		if loan.Recommendation != nil {
+			if err := upsert(loan.Recommendation); err != nil {
+				return err
+			}
+		}
+		if loan.Decision != nil {
+			if err := upsert(loan.Decision); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetLoan returns the projected state of a single loan.
+func (s *Store) GetLoan(ctx context.Context, loanID string) (workflows.LoanOriginationState, error) {
+	var loan LoanRecord
+	err := s.preload(ctx).First(&loan, "id = ?", loanID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return workflows.LoanOriginationState{}, ErrNotFound
+	}
+	if err != nil {
+		return workflows.LoanOriginationState{}, err
+	}
+	return loan.toState(), nil
+}
+
+func (s *Store) preload(ctx context.Context) *gorm.DB {
+	return s.db.WithContext(ctx).
+		Preload("Documents", func(db *gorm.DB) *gorm.DB { return db.Order("position") }).
+		Preload("Checklist", func(db *gorm.DB) *gorm.DB { return db.Order("position") }).
+		Preload("Appraisal").
+		Preload("CreditScore").
+		Preload("Recommendation").
+		Preload("Decision")
+}
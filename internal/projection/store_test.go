@@ -0,0 +1,294 @@
+package projection
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"loan-origination-system/internal/activities"
+	"loan-origination-system/internal/creditbureau"
+	"loan-origination-system/internal/decisioning"
+	"loan-origination-system/internal/notify"
+	"loan-origination-system/internal/policy"
+	"loan-origination-system/internal/workflows"
+
+	"github.com/stretchr/testify/require"
+)
+
+func openTestStore(t *testing.T) *Store {
+	store, err := Open(filepath.Join(t.TempDir(), "loans.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func testState(id string, createdAt time.Time) workflows.LoanOriginationState {
+	return workflows.LoanOriginationState{
+		LoanApplication: workflows.LoanApplication{
+			ID:            id,
+			BorrowerName:  "Jane Doe",
+			BorrowerEmail: "jane@example.com",
+			LoanAmount:    250000,
+			CreatedBy:     "loan-officer",
+			CreatedAt:     createdAt,
+			WorkflowID:    "loan-origination-" + id,
+		},
+		Documents: []workflows.Document{},
+		DocumentChecklist: []workflows.ChecklistItem{
+			{DocumentType: policy.DocumentIncomeStatement, Status: "missing"},
+			{DocumentType: policy.DocumentBankStatement, Status: "missing"},
+		},
+		Policy:   policy.Snapshot{Version: "2024-06-01", Product: policy.Default().Products["mortgage"]},
+		Status:   "processing",
+		NextStep: "Waiting for customer documents: income_statement, bank_statement",
+	}
+}
+
+func TestSaveLoan_ReplacesPreviousSnapshot(t *testing.T) {
+	ctx := context.Background()
+	store := openTestStore(t)
+	now := time.Now().UTC().Truncate(time.Second)
+
+	state := testState("loan-1", now)
+	require.NoError(t, store.SaveLoan(ctx, state))
+
+	state.Documents = append(state.Documents,
+		workflows.Document{ID: "doc-1", DocumentType: "id_proof", VerificationStatus: "verified", VerificationDetails: map[string]interface{}{"confidence_score": 0.95}, UploadedBy: "customer", VerifiedBy: "loan-processor"},
+		workflows.Document{ID: "doc-2", DocumentType: "bank_statement", VerificationStatus: "pending"},
+	)
+	state.DocumentChecklist[0].Status = "verified"
+	state.DocumentChecklist[0].DocumentID = "doc-1"
+	state.DocumentChecklist = append(state.DocumentChecklist, workflows.ChecklistItem{DocumentType: policy.DocumentPayStub, Status: "missing", Reason: "Latest pay stub", RequestedBy: "underwriter"})
+	state.Appraisal = &workflows.Appraisal{ID: "appraisal-loan-1", PropertyValue: 300000, Status: "completed"}
+	state.CreditScore = &workflows.CreditScore{
+		ID:              "credit-score-loan-1",
+		Score:           720,
+		Status:          "completed",
+		Bureau:          "Simulated Bureau",
+		PullType:        creditbureau.PullHard,
+		ReportReference: "SIM-1",
+		Tradelines:      []creditbureau.Tradeline{{Creditor: "First National Bank", AccountType: "mortgage", MonthlyPayment: 1200}},
+	}
+	state.LienCheck = &workflows.LienCheck{ID: "lien-check-loan-1", Status: "completed", ExistingBalance: 150000, Liens: []activities.Lien{{Holder: "First National Bank", Position: 1, Balance: 150000}}}
+	dti := 0.35
+	state.Recommendation = &workflows.Recommendation{Result: decisioning.Result{
+		Recommendation: decisioning.Refer,
+		Reasons:        []decisioning.Reason{{Code: decisioning.ReasonLTVAboveThreshold, Outcome: decisioning.Refer}},
+		LTV:            0.85,
+		DTI:            &dti,
+		CreditTier:     decisioning.TierGood,
+	}}
+	state.UnderwritingDecision = &workflows.UnderwritingDecision{ID: "decision-loan-1", Decision: "needs_more_info"}
+	state.NextStep = "Waiting for document verification"
+	require.NoError(t, store.SaveLoan(ctx, state))
+
+	state.UnderwritingDecision.Decision = "approved"
+	state.Status = "withdrawn"
+	state.LoanApplication.Status = "withdrawn"
+	state.Closure = &workflows.Closure{Status: "withdrawn", ReasonCode: workflows.ClosureFoundOtherLender, PreviousStatus: "approved"}
+	state.Terms = workflows.LoanTerms{LoanAmount: 200000, InterestRate: 0.075, TermMonths: 240, MonthlyPayment: 1611.19}
+	state.CounterOffer = &workflows.CounterOffer{Terms: state.Terms, Status: "declined"}
+	state.LoanAgreement = &workflows.LoanAgreement{Version: 2, Terms: state.Terms, PDF: activities.StoredFile{FilePath: "loans/loan-1/agreements/v2.pdf", SHA256: "pdf-sha"}}
+	state.Signature = &workflows.Signature{AgreementVersion: 2, DocumentSHA256: "pdf-sha", Status: "declined", IPAddress: "203.0.113.7"}
+	state.Funding = &workflows.Funding{Attempt: 2, Amount: 200000, FundManagerID: "fund-manager", LedgerEntryID: "disbursement-loan-1-2"}
+	state.FundingFailures = []workflows.FundingFailure{{Funding: workflows.Funding{Attempt: 1}, Error: "ledger unavailable", RetriedBy: "admin"}}
+	state.Conditions = []workflows.Condition{{ID: "condition-1", Description: "Provide final pay stub", Status: "open"}}
+	state.AdverseActionNotice = &workflows.AdverseActionNotice{DocumentID: "adverse-action-notice-decision-loan-1", CreditScore: true}
+	state.Notifications = []workflows.Notification{{Event: notify.EventWithdrawn, Channel: notify.ChannelSMS, Recipient: "555-0100", Status: "sent", MessageID: "sms-1"}}
+	require.NoError(t, store.SaveLoan(ctx, state))
+
+	got, err := store.GetLoan(ctx, "loan-1")
+	require.NoError(t, err)
+	require.Equal(t, "withdrawn", got.Status)
+	require.Equal(t, workflows.ClosureFoundOtherLender, got.Closure.ReasonCode)
+	require.Equal(t, 240, got.Terms.TermMonths)
+	require.Equal(t, "declined", got.CounterOffer.Status)
+	require.Equal(t, 2, got.LoanAgreement.Version)
+	require.Equal(t, "pdf-sha", got.LoanAgreement.PDF.SHA256)
+	require.Equal(t, "203.0.113.7", got.Signature.IPAddress)
+	require.Equal(t, "disbursement-loan-1-2", got.Funding.LedgerEntryID)
+	require.Len(t, got.FundingFailures, 1)
+	require.Equal(t, "admin", got.FundingFailures[0].RetriedBy)
+	require.Len(t, got.Conditions, 1)
+	require.Equal(t, "open", got.Conditions[0].Status)
+	require.Equal(t, "adverse-action-notice-decision-loan-1", got.AdverseActionNotice.DocumentID)
+	require.Len(t, got.Notifications, 1)
+	require.Equal(t, "sms-1", got.Notifications[0].MessageID)
+	require.Len(t, got.Documents, 2)
+	require.Equal(t, "doc-1", got.Documents[0].ID)
+	require.Equal(t, 0.95, got.Documents[0].VerificationDetails["confidence_score"])
+	require.Equal(t, "customer", got.Documents[0].UploadedBy)
+	require.Len(t, got.DocumentChecklist, 3)
+	require.Equal(t, "verified", got.DocumentChecklist[0].Status)
+	require.Equal(t, "doc-1", got.DocumentChecklist[0].DocumentID)
+	require.Equal(t, policy.DocumentPayStub, got.DocumentChecklist[2].DocumentType)
+	require.Equal(t, "Latest pay stub", got.DocumentChecklist[2].Reason)
+	require.Equal(t, "loan-processor", got.Documents[0].VerifiedBy)
+	require.Equal(t, 300000.0, got.Appraisal.PropertyValue)
+	require.Equal(t, 720, got.CreditScore.Score)
+	require.Equal(t, creditbureau.PullHard, got.CreditScore.PullType)
+	require.Equal(t, "SIM-1", got.CreditScore.ReportReference)
+	require.Equal(t, 1200.0, got.CreditScore.Tradelines[0].MonthlyPayment)
+	require.Equal(t, "2024-06-01", got.Policy.Version)
+	require.Equal(t, 150000.0, got.LienCheck.Liens[0].Balance)
+	require.Equal(t, policy.Days(30), got.Policy.Product.SLA.Processing)
+	require.Equal(t, decisioning.Refer, got.Recommendation.Recommendation)
+	require.Equal(t, decisioning.ReasonLTVAboveThreshold, got.Recommendation.Reasons[0].Code)
+	require.Equal(t, 0.35, *got.Recommendation.DTI)
+	require.Equal(t, "approved", got.UnderwritingDecision.Decision)
+}
+
+func TestListLoans_NewestFirst(t *testing.T) {
+	ctx := context.Background()
+	store := openTestStore(t)
+	now := time.Now().UTC()
+
+	require.NoError(t, store.SaveLoan(ctx, testState("older", now.Add(-time.Hour))))
+	require.NoError(t, store.SaveLoan(ctx, testState("newer", now)))
+
+	page, err := store.ListLoans(ctx, LoanFilter{})
+	require.NoError(t, err)
+	require.Len(t, page.Loans, 2)
+	require.Equal(t, "newer", page.Loans[0].LoanApplication.ID)
+	require.Equal(t, "older", page.Loans[1].LoanApplication.ID)
+	require.Empty(t, page.NextPageToken)
+
+	_, err = store.GetLoan(ctx, "missing")
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestListLoans_FiltersAndPages(t *testing.T) {
+	ctx := context.Background()
+	store := openTestStore(t)
+	now := time.Now().UTC()
+
+	for i := 0; i < 5; i++ {
+		state := testState(fmt.Sprintf("loan-%d", i), now.Add(time.Duration(i)*time.Minute))
+		state.LoanApplication.LoanAmount = float64(100000 * (i + 1))
+		if i%2 == 1 {
+			state.Status = "approved"
+			state.NextStep = "Waiting for funding"
+		}
+		if i == 4 {
+			state.LoanApplication.Product = policy.ProductAuto
+		}
+		require.NoError(t, store.SaveLoan(ctx, state))
+	}
+
+	page, err := store.ListLoans(ctx, LoanFilter{Statuses: []string{"approved"}})
+	require.NoError(t, err)
+	require.Len(t, page.Loans, 2)
+
+	page, err = store.ListLoans(ctx, LoanFilter{Products: []string{"auto"}})
+	require.NoError(t, err)
+	require.Len(t, page.Loans, 1)
+	require.Equal(t, "loan-4", page.Loans[0].LoanApplication.ID)
+
+	page, err = store.ListLoans(ctx, LoanFilter{NextStep: "Waiting for customer"})
+	require.NoError(t, err)
+	require.Len(t, page.Loans, 3)
+
+	minAmount, maxAmount := 200000.0, 400000.0
+	page, err = store.ListLoans(ctx, LoanFilter{MinAmount: &minAmount, MaxAmount: &maxAmount, Sort: "loan_amount"})
+	require.NoError(t, err)
+	require.Len(t, page.Loans, 3)
+	require.Equal(t, "loan-1", page.Loans[0].LoanApplication.ID)
+
+	after := now.Add(3 * time.Minute)
+	page, err = store.ListLoans(ctx, LoanFilter{CreatedAfter: &after})
+	require.NoError(t, err)
+	require.Len(t, page.Loans, 2)
+
+	var ids []string
+	filter := LoanFilter{Sort: "-loan_amount", PageSize: 2}
+	for {
+		page, err = store.ListLoans(ctx, filter)
+		require.NoError(t, err)
+		for _, loan := range page.Loans {
+			ids = append(ids, loan.LoanApplication.ID)
+		}
+		if page.NextPageToken == "" {
+			break
+		}
+		filter.PageToken = page.NextPageToken
+	}
+	require.Equal(t, []string{"loan-4", "loan-3", "loan-2", "loan-1", "loan-0"}, ids)
+
+	page, err = store.ListLoans(ctx, LoanFilter{PageSize: 3})
+	require.NoError(t, err)
+	require.Equal(t, "loan-4", page.Loans[0].LoanApplication.ID)
+	page, err = store.ListLoans(ctx, LoanFilter{PageSize: 3, PageToken: page.NextPageToken})
+	require.NoError(t, err)
+	require.Len(t, page.Loans, 2)
+	require.Equal(t, "loan-1", page.Loans[0].LoanApplication.ID)
+
+	firstPage, err := store.ListLoans(ctx, LoanFilter{PageSize: 1})
+	require.NoError(t, err)
+	_, err = store.ListLoans(ctx, LoanFilter{Sort: "loan_amount", PageToken: firstPage.NextPageToken})
+	require.ErrorIs(t, err, ErrInvalidFilter)
+	_, err = store.ListLoans(ctx, LoanFilter{Sort: "borrower_name"})
+	require.ErrorIs(t, err, ErrInvalidFilter)
+}
+
+func TestListLoans_NextStepMatchesWildcardsLiterally(t *testing.T) {
+	ctx := context.Background()
+	store := openTestStore(t)
+	now := time.Now().UTC()
+
+	for i, nextStep := range []string{`Waiting for 100% of bank_statement`, `Waiting for 1000 of bankXstatement`, `Waiting for C:\docs`} {
+		state := testState(fmt.Sprintf("loan-%d", i), now.Add(time.Duration(i)*time.Minute))
+		state.NextStep = nextStep
+		require.NoError(t, store.SaveLoan(ctx, state))
+	}
+
+	page, err := store.ListLoans(ctx, LoanFilter{NextStep: "Waiting for 100% of bank_"})
+	require.NoError(t, err)
+	require.Len(t, page.Loans, 1)
+	require.Equal(t, "loan-0", page.Loans[0].LoanApplication.ID)
+
+	page, err = store.ListLoans(ctx, LoanFilter{NextStep: `Waiting for C:\`})
+	require.NoError(t, err)
+	require.Len(t, page.Loans, 1)
+	require.Equal(t, "loan-2", page.Loans[0].LoanApplication.ID)
+}
+
+func TestChangesSince_FollowsSaves(t *testing.T) {
+	ctx := context.Background()
+	store := openTestStore(t)
+	now := time.Now().UTC()
+
+	latest, err := store.LatestSequence(ctx)
+	require.NoError(t, err)
+	require.Zero(t, latest)
+
+	require.NoError(t, store.SaveLoan(ctx, testState("loan-1", now)))
+	require.NoError(t, store.SaveLoan(ctx, testState("loan-2", now)))
+	cursor, err := store.LatestSequence(ctx)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), cursor)
+
+	// A loan saved twice since the cursor is returned once, at its latest
+	// sequence
+	state := testState("loan-1", now)
+	state.Status = "approved"
+	require.NoError(t, store.SaveLoan(ctx, state))
+	require.NoError(t, store.SaveLoan(ctx, testState("loan-3", now)))
+	state.Status = "funded"
+	require.NoError(t, store.SaveLoan(ctx, state))
+
+	changes, err := store.ChangesSince(ctx, cursor, "", 10)
+	require.NoError(t, err)
+	require.Len(t, changes, 2)
+	require.Equal(t, "loan-3", changes[0].State.LoanApplication.ID)
+	require.Equal(t, int64(4), changes[0].Sequence)
+	require.Equal(t, "loan-1", changes[1].State.LoanApplication.ID)
+	require.Equal(t, int64(5), changes[1].Sequence)
+	require.Equal(t, "funded", changes[1].State.Status)
+
+	changes, err = store.ChangesSince(ctx, 0, "loan-2", 10)
+	require.NoError(t, err)
+	require.Len(t, changes, 1)
+	require.Equal(t, int64(2), changes[0].Sequence)
+}
@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+
+	"loan-origination-system/internal/config"
+)
+
+// ErrNotFound is returned when an object does not exist in the store.
+var ErrNotFound = errors.New("object not found")
+
+// BlobStore stores uploaded document contents by key.
+type BlobStore interface {
+	// Put stores the contents of r under key. size is -1 when unknown.
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get opens the object stored under key.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the object stored under key if it exists.
+	Delete(ctx context.Context, key string) error
+}
+
+// New returns the BlobStore configured by cfg.
+func New(ctx context.Context, cfg config.Storage) (BlobStore, error) {
+	switch cfg.Backend {
+	case "", "local":
+		return NewLocalStore(cfg.LocalDir)
+	case "s3":
+		return NewS3Store(ctx, cfg.S3)
+	default:
+		return nil, fmt.Errorf("unknown document storage backend %q", cfg.Backend)
+	}
+}
+
+// Object describes stored contents.
+type Object struct {
+	Key         string
+	ContentType string
+	Size        int64
+	SHA256      string
+}
+
+// PutObject stores r under key and returns its size and SHA-256 checksum,
+// computed from the bytes actually written.
+func PutObject(ctx context.Context, store BlobStore, key string, r io.Reader, size int64, contentType string) (Object, error) {
+	hash := sha256.New()
+	counter := &countingWriter{}
+	err := store.Put(ctx, key, io.TeeReader(r, io.MultiWriter(hash, counter)), size, contentType)
+	if err != nil {
+		return Object{}, err
+	}
+
+	return Object{
+		Key:         key,
+		ContentType: contentType,
+		Size:        counter.n,
+		SHA256:      hex.EncodeToString(hash.Sum(nil)),
+	}, nil
+}
+
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
@@ -0,0 +1,134 @@
+package workflows
+
+import (
+	"loan-origination-system/internal/activities"
+	"loan-origination-system/internal/decisioning"
+	"loan-origination-system/internal/policy"
+	"sort"
+	"time"
+
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+// maxAdverseActionReasons is how many principal reasons a notice lists.
+// Regulation B considers more than four unhelpful to the applicant.
+const maxAdverseActionReasons = 4
+
+// AdverseActionNotice records the notice generated for a declined loan.
+type AdverseActionNotice struct {
+	// DocumentID is the PDF notice in the loan's documents once generated
+	DocumentID  string                    `json:"document_id,omitempty"`
+	Reasons     []activities.NoticeReason `json:"reasons"`
+	CreditScore bool                      `json:"credit_score_disclosed"`
+	HTML        *activities.StoredFile    `json:"html,omitempty"`
+	PDF         *activities.StoredFile    `json:"pdf,omitempty"`
+	// Error is set when the notice could not be generated after retries
+	Error       string     `json:"error,omitempty"`
+	GeneratedAt *time.Time `json:"generated_at"`
+}
+
+// adverseActionReasons picks the principal reasons for declining the loan:
+// the policy findings that declined or referred it, most severe first, or
+// the underwriter's comments when the policy found nothing against it.
+func adverseActionReasons(state *LoanOriginationState) []activities.NoticeReason {
+	var findings []decisioning.Reason
+	if state.Recommendation != nil {
+		for _, reason := range state.Recommendation.Reasons {
+			if reason.Outcome != decisioning.Approve {
+				findings = append(findings, reason)
+			}
+		}
+	}
+	sort.SliceStable(findings, func(i, j int) bool {
+		return findings[i].Outcome == decisioning.Decline && findings[j].Outcome != decisioning.Decline
+	})
+
+	reasons := []activities.NoticeReason{}
+	for _, finding := range findings {
+		if len(reasons) == maxAdverseActionReasons {
+			break
+		}
+		reasons = append(reasons, activities.NoticeReason{Code: string(finding.Code), Description: finding.Message})
+	}
+	if len(reasons) == 0 {
+		description := "Application does not meet our underwriting criteria"
+		if state.UnderwritingDecision != nil && state.UnderwritingDecision.Comments != "" {
+			description = state.UnderwritingDecision.Comments
+		}
+		reasons = append(reasons, activities.NoticeReason{Code: "UNDERWRITER_DECISION", Description: description})
+	}
+	return reasons
+}
+
+// sendAdverseActionNotice generates the notice for a declined loan and adds
+// its PDF to the loan's documents. A notice that cannot be generated is
+// recorded with its error rather than failing the workflow.
+func sendAdverseActionNotice(ctx workflow.Context, state *LoanOriginationState) {
+	logger := workflow.GetLogger(ctx)
+
+	noticeCtx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: 30 * time.Second,
+		RetryPolicy: &temporal.RetryPolicy{
+			MaximumAttempts: 5,
+		},
+	})
+
+	app := state.LoanApplication
+	input := activities.GenerateAdverseActionNoticeInput{
+		LoanApplicationID: app.ID,
+		NoticeID:          "adverse-action-notice-" + app.ID,
+		BorrowerName:      app.BorrowerName,
+		BorrowerEmail:     app.BorrowerEmail,
+		Product:           string(app.Product),
+		LoanAmount:        app.LoanAmount,
+		DecisionDate:      workflow.Now(ctx),
+		Reasons:           adverseActionReasons(state),
+	}
+	if state.UnderwritingDecision != nil {
+		input.NoticeID = "adverse-action-notice-" + state.UnderwritingDecision.ID
+		input.DecisionDate = state.UnderwritingDecision.DecisionDate
+	}
+	if state.creditScoreCompleted() {
+		input.CreditScore = &activities.CreditScoreDisclosure{
+			Score:           state.CreditScore.Score,
+			ScoreModel:      state.CreditScore.ScoreModel,
+			Bureau:          state.CreditScore.Bureau,
+			ReportReference: state.CreditScore.ReportReference,
+			ScoreDate:       *state.CreditScore.CompletedAt,
+		}
+	}
+
+	state.AdverseActionNotice = &AdverseActionNotice{
+		Reasons:     input.Reasons,
+		CreditScore: input.CreditScore != nil,
+	}
+
+	var notices *activities.NoticeActivities
+	var result activities.GenerateAdverseActionNoticeResult
+	err := workflow.ExecuteActivity(noticeCtx, notices.GenerateAdverseActionNotice, input).Get(ctx, &result)
+	if err != nil {
+		logger.Error("Adverse action notice failed", "error", err)
+		state.AdverseActionNotice.Error = err.Error()
+		return
+	}
+
+	now := workflow.Now(ctx)
+	state.AdverseActionNotice.DocumentID = result.DocumentID
+	state.AdverseActionNotice.HTML = &result.HTML
+	state.AdverseActionNotice.PDF = &result.PDF
+	state.AdverseActionNotice.GeneratedAt = &now
+	state.Documents = append(state.Documents, Document{
+		ID:                 result.DocumentID,
+		DocumentType:       policy.DocumentAdverseActionNotice,
+		FileName:           result.DocumentID + ".pdf",
+		FilePath:           result.PDF.FilePath,
+		ContentType:        result.PDF.ContentType,
+		Size:               result.PDF.Size,
+		SHA256:             result.PDF.SHA256,
+		VerificationStatus: "generated",
+		UploadedBy:         "system",
+		UploadedAt:         now,
+	})
+	logger.Info("Adverse action notice generated", "documentID", result.DocumentID)
+}
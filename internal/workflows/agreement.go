@@ -0,0 +1,60 @@
+package workflows
+
+import (
+	"loan-origination-system/internal/activities"
+	"time"
+
+	"go.temporal.io/sdk/workflow"
+)
+
+// LoanAgreement records the agreement last generated for the loan's terms.
+type LoanAgreement struct {
+	Version     int                   `json:"version"`
+	Terms       LoanTerms             `json:"terms"`
+	HTML        activities.StoredFile `json:"html"`
+	PDF         activities.StoredFile `json:"pdf"`
+	GeneratedAt time.Time             `json:"generated_at"`
+}
+
+// generateLoanAgreement draws up the agreement for the current terms as the
+// next version. It runs when the workflow starts and again whenever the
+// terms change.
+func generateLoanAgreement(ctx workflow.Context, state *LoanOriginationState) error {
+	version := 1
+	if state.LoanAgreement != nil {
+		version = state.LoanAgreement.Version + 1
+	}
+
+	app := state.LoanApplication
+	input := activities.GenerateLoanAgreementInput{
+		LoanApplicationID: app.ID,
+		Version:           version,
+		BorrowerName:      app.BorrowerName,
+		BorrowerEmail:     app.BorrowerEmail,
+		Product:           string(app.Product),
+		LoanPurpose:       app.LoanPurpose,
+		PropertyAddress:   app.PropertyAddress,
+		Date:              workflow.Now(ctx),
+		LoanAmount:        state.Terms.LoanAmount,
+		InterestRate:      state.Terms.InterestRate,
+		TermMonths:        state.Terms.TermMonths,
+		MonthlyPayment:    state.Terms.MonthlyPayment,
+	}
+
+	var agreements *activities.AgreementActivities
+	var result activities.GenerateLoanAgreementResult
+	err := workflow.ExecuteActivity(ctx, agreements.GenerateLoanAgreement, input).Get(ctx, &result)
+	if err != nil {
+		return err
+	}
+
+	state.LoanAgreement = &LoanAgreement{
+		Version:     version,
+		Terms:       state.Terms,
+		HTML:        result.HTML,
+		PDF:         result.PDF,
+		GeneratedAt: input.Date,
+	}
+	workflow.GetLogger(ctx).Info("Loan agreement generated", "version", version, "sha256", result.PDF.SHA256)
+	return nil
+}
@@ -0,0 +1,159 @@
+package workflows
+
+import (
+	"loan-origination-system/internal/activities"
+	"loan-origination-system/internal/notify"
+	"time"
+
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+// ClosureReason is the reason code recorded when an application is
+// withdrawn or cancelled.
+type ClosureReason string
+
+const (
+	// Borrower withdrawals
+	ClosureNoLongerNeeded      ClosureReason = "NO_LONGER_NEEDED"
+	ClosureFoundOtherLender    ClosureReason = "FOUND_OTHER_LENDER"
+	ClosureTermsNotAcceptable  ClosureReason = "TERMS_NOT_ACCEPTABLE"
+	ClosurePurchaseFellThrough ClosureReason = "PURCHASE_FELL_THROUGH"
+
+	// Lender cancellations
+	ClosureDuplicateApplication ClosureReason = "DUPLICATE_APPLICATION"
+	ClosureBorrowerUnresponsive ClosureReason = "BORROWER_UNRESPONSIVE"
+	ClosureSuspectedFraud       ClosureReason = "SUSPECTED_FRAUD"
+	ClosureEnteredInError       ClosureReason = "ENTERED_IN_ERROR"
+
+	// ClosureOther needs comments explaining it
+	ClosureOther ClosureReason = "OTHER"
+)
+
+// closureReasons lists the reason codes that apply to each closed status.
+var closureReasons = map[string][]ClosureReason{
+	"withdrawn": {ClosureNoLongerNeeded, ClosureFoundOtherLender, ClosureTermsNotAcceptable, ClosurePurchaseFellThrough, ClosureOther},
+	"cancelled": {ClosureDuplicateApplication, ClosureBorrowerUnresponsive, ClosureSuspectedFraud, ClosureEnteredInError, ClosureOther},
+}
+
+// CloseApplicationSignal withdraws or cancels an application before it is
+// funded.
+type CloseApplicationSignal struct {
+	// Status is withdrawn when the borrower backs out and cancelled when the
+	// lender stops the application
+	Status      string        `json:"status"`
+	ReasonCode  ClosureReason `json:"reason_code"`
+	Comments    string        `json:"comments"`
+	RequestedBy string        `json:"requested_by"`
+}
+
+// Closure records why and when an application was withdrawn or cancelled,
+// and how its cleanup went.
+type Closure struct {
+	Status      string        `json:"status"`
+	ReasonCode  ClosureReason `json:"reason_code"`
+	Comments    string        `json:"comments"`
+	RequestedBy string        `json:"requested_by"`
+	// PreviousStatus is the status the application was closed from
+	PreviousStatus string    `json:"previous_status"`
+	ClosedAt       time.Time `json:"closed_at"`
+	// CleanupErrors lists the cleanup steps that failed after retries
+	CleanupErrors []string   `json:"cleanup_errors,omitempty"`
+	CleanedUpAt   *time.Time `json:"cleaned_up_at"`
+}
+
+func applyClosure(ctx workflow.Context, state *LoanOriginationState, signal CloseApplicationSignal) {
+	state.Closure = &Closure{
+		Status:         signal.Status,
+		ReasonCode:     signal.ReasonCode,
+		Comments:       signal.Comments,
+		RequestedBy:    signal.RequestedBy,
+		PreviousStatus: state.Status,
+		ClosedAt:       workflow.Now(ctx),
+	}
+	state.setStatus(signal.Status)
+	state.NextStep = "n/a"
+	if signal.Status == "withdrawn" {
+		state.notifyBorrower(notify.EventWithdrawn, notify.Data{})
+	} else {
+		state.notifyBorrower(notify.EventCancelled, notify.Data{})
+	}
+	workflow.GetLogger(ctx).Info("Application closed", "status", signal.Status, "reasonCode", signal.ReasonCode)
+}
+
+// receiveClosure applies a closure sent as a signal. Signals cannot be
+// rejected, so an invalid one is logged and dropped.
+func receiveClosure(ctx workflow.Context, c workflow.ReceiveChannel, state *LoanOriginationState) {
+	var signal CloseApplicationSignal
+	c.Receive(ctx, &signal)
+	if err := validateClosure(state, signal); err != nil {
+		workflow.GetLogger(ctx).Warn("Ignoring application-closed signal", "error", err)
+		return
+	}
+	applyClosure(ctx, state, signal)
+}
+
+func validateClosure(state *LoanOriginationState, signal CloseApplicationSignal) error {
+	switch state.Status {
+	case "processing", "counter_offered", "conditionally_approved", "awaiting_signature", "approved", "funding_failed":
+	default:
+		return rejectUpdate("loan is %s and can no longer be withdrawn or cancelled", state.Status)
+	}
+	reasons, ok := closureReasons[signal.Status]
+	if !ok {
+		return rejectUpdate("status must be withdrawn or cancelled, got %q", signal.Status)
+	}
+	if signal.ReasonCode == ClosureOther && signal.Comments == "" {
+		return rejectUpdate("comments are required with reason code %s", ClosureOther)
+	}
+	for _, reason := range reasons {
+		if signal.ReasonCode == reason {
+			return nil
+		}
+	}
+	return rejectUpdate("reason code %q does not apply to %s applications", signal.ReasonCode, signal.Status)
+}
+
+func (s *LoanOriginationState) closed() bool {
+	return s.Closure != nil
+}
+
+// runClosureCleanup undoes what was set up for a withdrawn or cancelled
+// application. The steps are independent and run together; one that still
+// fails after its retries is recorded on the closure rather than failing
+// the workflow.
+func runClosureCleanup(ctx workflow.Context, state *LoanOriginationState) {
+	logger := workflow.GetLogger(ctx)
+
+	cleanupCtx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: 30 * time.Second,
+		RetryPolicy: &temporal.RetryPolicy{
+			MaximumAttempts: 5,
+		},
+	})
+
+	app := state.LoanApplication
+	closure := state.Closure
+	steps := []struct {
+		name   string
+		future workflow.Future
+	}{
+		{"void loan agreement", workflow.ExecuteActivity(cleanupCtx, activities.VoidLoanAgreement, activities.VoidLoanAgreementInput{
+			LoanApplicationID: app.ID,
+			Reason:            string(closure.ReasonCode),
+		})},
+		{"release rate lock", workflow.ExecuteActivity(cleanupCtx, activities.ReleaseRateLock, activities.ReleaseRateLockInput{
+			LoanApplicationID: app.ID,
+		})},
+	}
+
+	for _, step := range steps {
+		if err := step.future.Get(ctx, nil); err != nil {
+			logger.Error("Closure cleanup step failed", "step", step.name, "error", err)
+			closure.CleanupErrors = append(closure.CleanupErrors, step.name+": "+err.Error())
+		}
+	}
+
+	now := workflow.Now(ctx)
+	closure.CleanedUpAt = &now
+}
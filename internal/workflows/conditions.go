@@ -0,0 +1,180 @@
+package workflows
+
+import (
+	"fmt"
+	"loan-origination-system/internal/notify"
+	"time"
+
+	"go.temporal.io/sdk/workflow"
+)
+
+// ConditionRequest is a condition the underwriter attaches to an approval.
+type ConditionRequest struct {
+	Description string `json:"description"`
+}
+
+// Condition must be cleared before a conditionally approved loan is funded.
+type Condition struct {
+	ID          string `json:"id"`
+	Description string `json:"description"`
+	// Status is open, satisfied or waived
+	Status    string     `json:"status"`
+	AddedBy   string     `json:"added_by"`
+	AddedAt   time.Time  `json:"added_at"`
+	ClearedBy string     `json:"cleared_by,omitempty"`
+	Comments  string     `json:"comments,omitempty"`
+	ClearedAt *time.Time `json:"cleared_at"`
+}
+
+// ClearConditionSignal marks a condition satisfied, or waives it.
+type ClearConditionSignal struct {
+	ConditionID string `json:"condition_id"`
+	// Status is satisfied or waived
+	Status    string `json:"status"`
+	Comments  string `json:"comments"`
+	ClearedBy string `json:"cleared_by"`
+}
+
+func applyConditions(ctx workflow.Context, state *LoanOriginationState, signal UnderwritingDecisionSignal) {
+	for _, request := range signal.Conditions {
+		state.Conditions = append(state.Conditions, Condition{
+			ID:          fmt.Sprintf("condition-%d", len(state.Conditions)+1),
+			Description: request.Description,
+			Status:      "open",
+			AddedBy:     signal.UnderwriterID,
+			AddedAt:     workflow.Now(ctx),
+		})
+	}
+	state.setStatus("conditionally_approved")
+	state.refreshConditionsStep()
+
+	var conditions []string
+	for _, condition := range state.Conditions {
+		if condition.Status == "open" {
+			conditions = append(conditions, condition.Description)
+		}
+	}
+	state.notifyBorrower(notify.EventConditionallyApproved, notify.Data{Conditions: conditions})
+}
+
+func applyConditionCleared(ctx workflow.Context, state *LoanOriginationState, signal ClearConditionSignal) {
+	now := workflow.Now(ctx)
+	for i := range state.Conditions {
+		condition := &state.Conditions[i]
+		if condition.ID != signal.ConditionID {
+			continue
+		}
+		condition.Status = signal.Status
+		condition.ClearedBy = signal.ClearedBy
+		condition.Comments = signal.Comments
+		condition.ClearedAt = &now
+	}
+	workflow.GetLogger(ctx).Info("Condition cleared", "conditionID", signal.ConditionID, "status", signal.Status)
+
+	if state.openConditions() == 0 {
+		state.setStatus("approved")
+		state.NextStep = signatureStep
+	} else {
+		state.refreshConditionsStep()
+	}
+}
+
+func validateConditionCleared(state *LoanOriginationState, signal ClearConditionSignal) error {
+	if state.Status != "conditionally_approved" {
+		return rejectUpdate("loan is %s and has no open conditions", state.Status)
+	}
+	if signal.Status != "satisfied" && signal.Status != "waived" {
+		return rejectUpdate("condition status must be satisfied or waived, got %q", signal.Status)
+	}
+	if signal.Status == "waived" && signal.Comments == "" {
+		return rejectUpdate("comments are required to waive a condition")
+	}
+	for _, condition := range state.Conditions {
+		if condition.ID == signal.ConditionID {
+			if condition.Status != "open" {
+				return rejectUpdate("condition %s is already %s", signal.ConditionID, condition.Status)
+			}
+			return nil
+		}
+	}
+	return rejectUpdate("condition %s not found", signal.ConditionID)
+}
+
+// validateConditionRequests checks the conditions attached to a decision.
+func validateConditionRequests(signal UnderwritingDecisionSignal) error {
+	if len(signal.Conditions) > 0 && signal.Decision != "approved" {
+		return rejectUpdate("conditions can only be attached to an approval")
+	}
+	for _, request := range signal.Conditions {
+		if request.Description == "" {
+			return rejectUpdate("every condition needs a description")
+		}
+	}
+	return nil
+}
+
+func (s *LoanOriginationState) openConditions() int {
+	open := 0
+	for _, condition := range s.Conditions {
+		if condition.Status == "open" {
+			open++
+		}
+	}
+	return open
+}
+
+func (s *LoanOriginationState) refreshConditionsStep() {
+	s.NextStep = fmt.Sprintf("Waiting for approval conditions: %d open", s.openConditions())
+}
+
+// waitForConditions waits until every condition of a conditional approval
+// is satisfied or waived. Conditions still open when the window closes end
+// the loan as conditions_timeout.
+func waitForConditions(ctx workflow.Context, state *LoanOriginationState, stateChanged workflow.ReceiveChannel) error {
+	logger := workflow.GetLogger(ctx)
+
+	conditionChannel := workflow.GetSignalChannel(ctx, "condition-cleared")
+	closeChannel := workflow.GetSignalChannel(ctx, "application-closed")
+
+	timerCtx, timerCancel := workflow.WithCancel(ctx)
+	timer := workflow.NewTimer(timerCtx, state.Policy.Product.SLA.ConditionsWindow())
+
+	for state.Status == "conditionally_approved" {
+		selector := workflow.NewSelector(ctx)
+
+		selector.AddReceive(conditionChannel, func(c workflow.ReceiveChannel, more bool) {
+			var signal ClearConditionSignal
+			c.Receive(ctx, &signal)
+			if err := validateConditionCleared(state, signal); err != nil {
+				logger.Warn("Ignoring condition-cleared signal", "error", err)
+				return
+			}
+			applyConditionCleared(ctx, state, signal)
+		})
+
+		selector.AddReceive(closeChannel, func(c workflow.ReceiveChannel, more bool) {
+			receiveClosure(ctx, c, state)
+		})
+
+		selector.AddReceive(stateChanged, func(c workflow.ReceiveChannel, more bool) {
+			c.Receive(ctx, nil)
+		})
+
+		err := publishState(ctx, state)
+		if err != nil {
+			return err
+		}
+
+		selector.AddFuture(timer, func(f workflow.Future) {
+			logger.Error("Timeout waiting for approval conditions", "open", state.openConditions())
+			state.setStatus("conditions_timeout")
+			state.NextStep = "n/a"
+		})
+
+		selector.Select(ctx)
+	}
+
+	timerCancel()
+
+	return nil
+}
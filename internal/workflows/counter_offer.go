@@ -0,0 +1,189 @@
+package workflows
+
+import (
+	"loan-origination-system/internal/decisioning"
+	"loan-origination-system/internal/notify"
+	"time"
+
+	"go.temporal.io/sdk/workflow"
+)
+
+// LoanTerms are the amount, annual rate and term the loan agreement is
+// drawn up for.
+type LoanTerms struct {
+	LoanAmount     float64 `json:"loan_amount"`
+	InterestRate   float64 `json:"interest_rate"`
+	TermMonths     int     `json:"term_months"`
+	MonthlyPayment float64 `json:"monthly_payment"`
+}
+
+func newLoanTerms(amount, rate float64, termMonths int) LoanTerms {
+	return LoanTerms{
+		LoanAmount:     amount,
+		InterestRate:   rate,
+		TermMonths:     termMonths,
+		MonthlyPayment: decisioning.MonthlyPayment(amount, rate, termMonths),
+	}
+}
+
+// CounterOffer is an offer of different terms from underwriting, waiting for
+// the borrower's answer or answered.
+type CounterOffer struct {
+	Terms LoanTerms `json:"terms"`
+	// OriginalTerms are the terms the borrower applied for
+	OriginalTerms LoanTerms `json:"original_terms"`
+	// Status is pending, accepted, declined or expired
+	Status      string     `json:"status"`
+	OfferedBy   string     `json:"offered_by"`
+	OfferedAt   time.Time  `json:"offered_at"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+	RespondedBy string     `json:"responded_by,omitempty"`
+	Comments    string     `json:"comments,omitempty"`
+	RespondedAt *time.Time `json:"responded_at"`
+}
+
+// CounterOfferResponseSignal is the borrower's answer to a counter-offer.
+type CounterOfferResponseSignal struct {
+	Accepted    bool   `json:"accepted"`
+	Comments    string `json:"comments"`
+	RespondedBy string `json:"responded_by"`
+}
+
+func applyCounterOffer(ctx workflow.Context, state *LoanOriginationState, signal UnderwritingDecisionSignal) {
+	now := workflow.Now(ctx)
+	offered := signal.CounterOffer
+	state.CounterOffer = &CounterOffer{
+		Terms:         newLoanTerms(offered.LoanAmount, offered.InterestRate, offered.TermMonths),
+		OriginalTerms: state.Terms,
+		Status:        "pending",
+		OfferedBy:     signal.UnderwriterID,
+		OfferedAt:     now,
+		ExpiresAt:     now.Add(state.Policy.Product.SLA.CounterOfferExpiry()),
+	}
+	state.setStatus("counter_offered")
+	state.NextStep = "Waiting for borrower to respond to counter-offer"
+	state.notifyBorrower(notify.EventCounterOffered, notify.Data{
+		LoanAmount: state.CounterOffer.Terms.LoanAmount,
+		ExpiresAt:  state.CounterOffer.ExpiresAt,
+	})
+}
+
+func applyCounterOfferResponse(ctx workflow.Context, state *LoanOriginationState, signal CounterOfferResponseSignal) {
+	now := workflow.Now(ctx)
+	offer := state.CounterOffer
+	offer.RespondedBy = signal.RespondedBy
+	offer.Comments = signal.Comments
+	offer.RespondedAt = &now
+
+	// An accepted offer stays counter_offered until the agreement has been
+	// drawn up for its terms, so funding cannot start before then
+	if signal.Accepted {
+		offer.Status = "accepted"
+		state.NextStep = "Generating loan agreement for the new terms"
+	} else {
+		offer.Status = "declined"
+		state.setStatus("counter_offer_declined")
+		state.NextStep = "n/a"
+	}
+	workflow.GetLogger(ctx).Info("Counter-offer answered", "status", offer.Status)
+}
+
+// receiveCounterOfferResponse applies a response sent as a signal. Signals
+// cannot be rejected, so one that arrives while no counter-offer is pending
+// is logged and dropped rather than left to answer a later offer.
+func receiveCounterOfferResponse(ctx workflow.Context, c workflow.ReceiveChannel, state *LoanOriginationState) {
+	var signal CounterOfferResponseSignal
+	c.Receive(ctx, &signal)
+	if err := validateCounterOfferResponse(state, signal); err != nil {
+		workflow.GetLogger(ctx).Warn("Ignoring counter-offer-response signal", "error", err)
+		return
+	}
+	applyCounterOfferResponse(ctx, state, signal)
+}
+
+func validateCounterOfferResponse(state *LoanOriginationState, signal CounterOfferResponseSignal) error {
+	if state.Status != "counter_offered" || state.CounterOffer == nil || state.CounterOffer.Status != "pending" {
+		return rejectUpdate("loan is %s and has no counter-offer waiting for a response", state.Status)
+	}
+	return nil
+}
+
+// validateCounterOfferTerms checks the terms of a counter_offer decision.
+func validateCounterOfferTerms(terms *LoanTerms) error {
+	switch {
+	case terms == nil:
+		return rejectUpdate("counter_offer requires the offered terms")
+	case terms.LoanAmount <= 0:
+		return rejectUpdate("counter-offer loan amount must be positive")
+	case terms.InterestRate <= 0 || terms.InterestRate >= 1:
+		return rejectUpdate("counter-offer interest rate must be a fraction between 0 and 1")
+	case terms.TermMonths <= 0:
+		return rejectUpdate("counter-offer term must be a positive number of months")
+	}
+	return nil
+}
+
+// waitForCounterOfferResponse waits for the borrower to accept or decline
+// the counter-offer until it expires. On acceptance the loan agreement is
+// generated again for the new terms and the loan is approved.
+func waitForCounterOfferResponse(ctx workflow.Context, state *LoanOriginationState, stateChanged workflow.ReceiveChannel) error {
+	logger := workflow.GetLogger(ctx)
+
+	responseChannel := workflow.GetSignalChannel(ctx, "counter-offer-response")
+	closeChannel := workflow.GetSignalChannel(ctx, "application-closed")
+
+	timerCtx, timerCancel := workflow.WithCancel(ctx)
+	timer := workflow.NewTimer(timerCtx, state.CounterOffer.ExpiresAt.Sub(workflow.Now(ctx)))
+
+	for state.CounterOffer.Status == "pending" && !state.closed() {
+		selector := workflow.NewSelector(ctx)
+
+		selector.AddReceive(responseChannel, func(c workflow.ReceiveChannel, more bool) {
+			receiveCounterOfferResponse(ctx, c, state)
+		})
+
+		selector.AddReceive(closeChannel, func(c workflow.ReceiveChannel, more bool) {
+			receiveClosure(ctx, c, state)
+		})
+
+		selector.AddReceive(stateChanged, func(c workflow.ReceiveChannel, more bool) {
+			c.Receive(ctx, nil)
+		})
+
+		err := publishState(ctx, state)
+		if err != nil {
+			return err
+		}
+
+		selector.AddFuture(timer, func(f workflow.Future) {
+			logger.Info("Counter-offer expired")
+			state.CounterOffer.Status = "expired"
+			state.setStatus("counter_offer_expired")
+			state.NextStep = "n/a"
+		})
+
+		selector.Select(ctx)
+	}
+
+	timerCancel()
+
+	if state.CounterOffer.Status != "accepted" || state.closed() {
+		return nil
+	}
+
+	err := publishState(ctx, state)
+	if err != nil {
+		return err
+	}
+
+	state.Terms = state.CounterOffer.Terms
+	state.LoanApplication.LoanAmount = state.Terms.LoanAmount
+	err = generateLoanAgreement(ctx, state)
+	if err != nil {
+		return err
+	}
+
+	state.setStatus("approved")
+	state.NextStep = signatureStep
+	return nil
+}
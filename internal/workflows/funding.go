@@ -0,0 +1,219 @@
+package workflows
+
+import (
+	"loan-origination-system/internal/activities"
+	"loan-origination-system/internal/notify"
+	"time"
+
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+// Funding records the fund manager releasing the loan and its disbursement
+// in the ledger.
+type Funding struct {
+	// Attempt counts the loan's funding attempts, starting at 1
+	Attempt       int       `json:"attempt"`
+	Amount        float64   `json:"amount"`
+	FundManagerID string    `json:"fund_manager_id"`
+	Notes         string    `json:"notes,omitempty"`
+	FundedAt      time.Time `json:"funded_at"`
+	// LedgerEntryID is set once the disbursement has been posted
+	LedgerEntryID string `json:"ledger_entry_id,omitempty"`
+}
+
+// FundingFailure records a funding attempt whose disbursement failed, how
+// it was compensated and who retried it.
+type FundingFailure struct {
+	Funding  Funding   `json:"funding"`
+	Error    string    `json:"error"`
+	FailedAt time.Time `json:"failed_at"`
+	// ReversalEntryID is set when the attempt had posted a disbursement
+	// that was reversed
+	ReversalEntryID string `json:"reversal_entry_id,omitempty"`
+	// CompensationErrors lists the compensating steps that failed after
+	// retries
+	CompensationErrors []string   `json:"compensation_errors,omitempty"`
+	CompensatedAt      *time.Time `json:"compensated_at"`
+	RetriedBy          string     `json:"retried_by,omitempty"`
+	RetryComments      string     `json:"retry_comments,omitempty"`
+	RetriedAt          *time.Time `json:"retried_at"`
+}
+
+// RetryFundingSignal returns a loan whose funding failed to the funding
+// steps.
+type RetryFundingSignal struct {
+	RequestedBy string `json:"requested_by"`
+	Comments    string `json:"comments"`
+}
+
+const fundingRetryStep = "Waiting for an administrator to retry funding"
+
+// disburseLoan posts the funded loan's disbursement to the ledger.
+func disburseLoan(ctx workflow.Context, state *LoanOriginationState) error {
+	ctx = workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: 30 * time.Second,
+		RetryPolicy: &temporal.RetryPolicy{
+			MaximumAttempts: 5,
+		},
+	})
+
+	var funding *activities.FundingActivities
+	var result activities.ProcessFundingResult
+	err := workflow.ExecuteActivity(ctx, funding.ProcessFunding, activities.ProcessFundingInput{
+		LoanApplicationID: state.LoanApplication.ID,
+		Attempt:           state.Funding.Attempt,
+		Amount:            state.Funding.Amount,
+		FundedAt:          state.Funding.FundedAt,
+	}).Get(ctx, &result)
+	if err != nil {
+		return err
+	}
+
+	state.Funding.LedgerEntryID = result.LedgerEntryID
+	workflow.GetLogger(ctx).Info("Loan disbursed", "ledgerEntryID", result.LedgerEntryID)
+	return nil
+}
+
+// compensateFunding undoes a funding attempt whose disbursement failed and
+// moves the loan to funding_failed. The steps run in order, reversing
+// whatever the attempt posted before the signed agreement is voided; one
+// that still fails after its retries is recorded on the failure rather than
+// failing the workflow.
+func compensateFunding(ctx workflow.Context, state *LoanOriginationState, cause error) {
+	logger := workflow.GetLogger(ctx)
+	logger.Error("Funding failed, compensating", "attempt", state.Funding.Attempt, "error", cause)
+
+	failure := FundingFailure{
+		Funding:  *state.Funding,
+		Error:    cause.Error(),
+		FailedAt: workflow.Now(ctx),
+	}
+	state.Funding = nil
+	state.setStatus("funding_failed")
+	state.NextStep = "Compensating failed funding"
+
+	compensationCtx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: 30 * time.Second,
+		RetryPolicy: &temporal.RetryPolicy{
+			MaximumAttempts: 5,
+		},
+	})
+
+	app := state.LoanApplication
+	var funding *activities.FundingActivities
+	steps := []struct {
+		name string
+		run  func() error
+	}{
+		{"reverse ledger entries", func() error {
+			var result activities.ReverseFundingResult
+			err := workflow.ExecuteActivity(compensationCtx, funding.ReverseFunding, activities.ReverseFundingInput{
+				LoanApplicationID: app.ID,
+				Attempt:           failure.Funding.Attempt,
+				ReversedAt:        failure.FailedAt,
+			}).Get(ctx, &result)
+			failure.ReversalEntryID = result.LedgerEntryID
+			return err
+		}},
+		{"void loan agreement", func() error {
+			return workflow.ExecuteActivity(compensationCtx, activities.VoidLoanAgreement, activities.VoidLoanAgreementInput{
+				LoanApplicationID: app.ID,
+				Reason:            "FUNDING_FAILED",
+			}).Get(ctx, nil)
+		}},
+		{"notify fund manager", func() error {
+			return workflow.ExecuteActivity(compensationCtx, activities.SendFundingFailureNotification, activities.SendFundingFailureNotificationInput{
+				LoanApplicationID: app.ID,
+				FundManagerID:     failure.Funding.FundManagerID,
+				Attempt:           failure.Funding.Attempt,
+				Amount:            failure.Funding.Amount,
+				Error:             failure.Error,
+			}).Get(ctx, nil)
+		}},
+	}
+
+	for _, step := range steps {
+		if err := step.run(); err != nil {
+			logger.Error("Funding compensation step failed", "step", step.name, "error", err)
+			failure.CompensationErrors = append(failure.CompensationErrors, step.name+": "+err.Error())
+		}
+	}
+
+	// The signed agreement is void, so funding needs a new signature
+	if state.Signature != nil {
+		state.Signature.Status = "voided"
+	}
+
+	now := workflow.Now(ctx)
+	failure.CompensatedAt = &now
+	state.FundingFailures = append(state.FundingFailures, failure)
+	state.notifyBorrower(notify.EventFundingFailed, notify.Data{})
+}
+
+// waitForFundingRetry waits for an administrator to retry a loan whose
+// funding failed, until the loan is withdrawn or cancelled or the funding
+// SLA runs out, which abandons it.
+func waitForFundingRetry(ctx workflow.Context, state *LoanOriginationState, stateChanged workflow.ReceiveChannel) error {
+	logger := workflow.GetLogger(ctx)
+
+	closeChannel := workflow.GetSignalChannel(ctx, "application-closed")
+
+	timerCtx, timerCancel := workflow.WithCancel(ctx)
+	timer := workflow.NewTimer(timerCtx, time.Duration(state.Policy.Product.SLA.Funding))
+
+	timedOut := false
+	for state.Status == "funding_failed" && !timedOut {
+		state.NextStep = fundingRetryStep
+
+		selector := workflow.NewSelector(ctx)
+
+		selector.AddReceive(closeChannel, func(c workflow.ReceiveChannel, more bool) {
+			receiveClosure(ctx, c, state)
+		})
+
+		selector.AddReceive(stateChanged, func(c workflow.ReceiveChannel, more bool) {
+			c.Receive(ctx, nil)
+		})
+
+		err := publishState(ctx, state)
+		if err != nil {
+			return err
+		}
+
+		selector.AddFuture(timer, func(f workflow.Future) {
+			logger.Error("Timeout waiting for funding retry")
+			state.setStatus("funding_abandoned")
+			state.NextStep = "n/a"
+			timedOut = true
+		})
+
+		selector.Select(ctx)
+	}
+
+	timerCancel()
+
+	return nil
+}
+
+func applyFundingRetry(ctx workflow.Context, state *LoanOriginationState, signal RetryFundingSignal) {
+	now := workflow.Now(ctx)
+	failure := &state.FundingFailures[len(state.FundingFailures)-1]
+	failure.RetriedBy = signal.RequestedBy
+	failure.RetryComments = signal.Comments
+	failure.RetriedAt = &now
+
+	state.setStatus("approved")
+	state.NextStep = "Generating a new loan agreement"
+	workflow.GetLogger(ctx).Info("Funding retried", "requestedBy", signal.RequestedBy)
+}
+
+func validateFundingRetry(state *LoanOriginationState, signal RetryFundingSignal) error {
+	if state.Status != "funding_failed" || state.NextStep != fundingRetryStep {
+		return rejectUpdate("loan is %s and has no failed funding to retry", state.Status)
+	}
+	if signal.RequestedBy == "" {
+		return rejectUpdate("the administrator retrying funding is required")
+	}
+	return nil
+}
@@ -3,6 +3,12 @@ package workflows
 import (
 	"fmt"
 	"loan-origination-system/internal/activities"
+	"loan-origination-system/internal/creditbureau"
+	"loan-origination-system/internal/decisioning"
+	"loan-origination-system/internal/notify"
+	"loan-origination-system/internal/policy"
+	"math"
+	"strings"
 	"time"
 
 	"go.temporal.io/sdk/temporal"
@@ -11,20 +17,41 @@ import (
 
 // Workflow signals
 type DocumentUploadedSignal struct {
-	DocumentID   string `json:"document_id"`
-	DocumentType string `json:"document_type"`
+	DocumentID   string              `json:"document_id"`
+	DocumentType policy.DocumentType `json:"document_type"`
+	FileName     string              `json:"file_name"`
+	FilePath     string              `json:"file_path"`
+	ContentType  string              `json:"content_type"`
+	Size         int64               `json:"size"`
+	SHA256       string              `json:"sha256"`
+	UploadedBy   string              `json:"uploaded_by"`
 }
 
 type DocumentVerificationSignal struct {
 	DocumentID          string                 `json:"document_id"`
 	VerificationStatus  string                 `json:"verification_status"`
 	VerificationDetails map[string]interface{} `json:"verification_details"`
+	VerifiedBy          string                 `json:"verified_by"`
 }
 
 type UnderwritingDecisionSignal struct {
 	Decision      string `json:"decision"`
 	Comments      string `json:"comments"`
 	UnderwriterID string `json:"underwriter_id"`
+	// RequestedDocuments are added to the checklist when the decision is
+	// needs_more_info
+	RequestedDocuments []DocumentRequest `json:"requested_documents,omitempty"`
+	// CounterOffer holds the terms offered instead when the decision is
+	// counter_offer
+	CounterOffer *LoanTerms `json:"counter_offer,omitempty"`
+	// Conditions must be cleared before an approval is funded
+	Conditions []ConditionRequest `json:"conditions,omitempty"`
+}
+
+// DocumentRequest asks the borrower for another document
+type DocumentRequest struct {
+	DocumentType policy.DocumentType `json:"document_type"`
+	Reason       string              `json:"reason"`
 }
 
 type AppraisalCompletedSignal struct {
@@ -42,36 +69,78 @@ type FundingCompletedSignal struct {
 // Workflow input
 type LoanOriginationWorkflowInput struct {
 	LoanApplication LoanApplication `json:"loan_application"`
+	// Policy is the underwriting policy in force when the loan was started.
+	// It travels in the input so replays apply the same rules after the
+	// policy file changes.
+	Policy *policy.Snapshot `json:"policy"`
 }
 
 // Loan application data structure
 type LoanApplication struct {
-	ID            string    `json:"id"`
-	BorrowerName  string    `json:"borrower_name"`
-	BorrowerEmail string    `json:"borrower_email"`
-	BorrowerPhone string    `json:"borrower_phone"`
-	LoanAmount    float64   `json:"loan_amount"`
-	LoanPurpose   string    `json:"loan_purpose"`
-	Status        string    `json:"status"`
-	NextStep      string    `json:"next_step"`
-	CreatedBy     string    `json:"created_by"`
-	CreatedAt     time.Time `json:"created_at"`
-	UpdatedAt     time.Time `json:"updated_at"`
-	WorkflowID    string    `json:"workflow_id"`
+	ID            string `json:"id"`
+	BorrowerName  string `json:"borrower_name"`
+	BorrowerEmail string `json:"borrower_email"`
+	// BorrowerEmailHash is the keyed hash of BorrowerEmail that is indexed
+	// for search, empty when no search key is configured
+	BorrowerEmailHash string    `json:"borrower_email_hash,omitempty"`
+	BorrowerPhone     string    `json:"borrower_phone"`
+	LoanAmount        float64   `json:"loan_amount"`
+	LoanPurpose       string    `json:"loan_purpose"`
+	MonthlyIncome     float64   `json:"monthly_income"`
+	Status            string    `json:"status"`
+	NextStep          string    `json:"next_step"`
+	CreatedBy         string    `json:"created_by"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+	WorkflowID        string    `json:"workflow_id"`
+
+	// Product decides the stages the loan goes through. Vehicle describes
+	// the collateral of auto loans.
+	Product         policy.LoanProduct `json:"product"`
+	PropertyAddress string             `json:"property_address"`
+	Vehicle         *Vehicle           `json:"vehicle,omitempty"`
+}
+
+// Vehicle securing an auto loan
+type Vehicle struct {
+	VIN     string `json:"vin"`
+	Year    int    `json:"year"`
+	Make    string `json:"make"`
+	Model   string `json:"model"`
+	Mileage int    `json:"mileage"`
 }
 
 // Document data structure
 type Document struct {
 	ID                  string                 `json:"id"`
-	DocumentType        string                 `json:"document_type"`
+	DocumentType        policy.DocumentType    `json:"document_type"`
 	FileName            string                 `json:"file_name"`
 	FilePath            string                 `json:"file_path"`
+	ContentType         string                 `json:"content_type"`
+	Size                int64                  `json:"size"`
+	SHA256              string                 `json:"sha256"`
 	VerificationStatus  string                 `json:"verification_status"`
 	VerificationDetails map[string]interface{} `json:"verification_details"`
+	UploadedBy          string                 `json:"uploaded_by"`
+	VerifiedBy          string                 `json:"verified_by"`
 	UploadedAt          time.Time              `json:"uploaded_at"`
 	VerifiedAt          *time.Time             `json:"verified_at"`
 }
 
+// ChecklistItem is a document the borrower must provide, required by the
+// product's policy or requested by the underwriter
+type ChecklistItem struct {
+	DocumentType policy.DocumentType `json:"document_type"`
+	// Status is missing, uploaded, verified or rejected. A rejected item is
+	// satisfied again by the next upload of its type.
+	Status string `json:"status"`
+	// DocumentID is the latest document uploaded for the item
+	DocumentID  string    `json:"document_id,omitempty"`
+	Reason      string    `json:"reason,omitempty"`
+	RequestedBy string    `json:"requested_by,omitempty"`
+	RequestedAt time.Time `json:"requested_at"`
+}
+
 // Appraisal data structure
 type Appraisal struct {
 	ID             string     `json:"id"`
@@ -83,22 +152,63 @@ type Appraisal struct {
 	CreatedAt      time.Time  `json:"created_at"`
 }
 
+// Vehicle valuation data structure, the appraisal of auto loans
+type VehicleValuation struct {
+	ID          string     `json:"id"`
+	Value       float64    `json:"value"`
+	Source      string     `json:"source"`
+	Reference   string     `json:"reference"`
+	Status      string     `json:"status"`
+	Error       string     `json:"error,omitempty"`
+	CompletedAt *time.Time `json:"completed_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// Lien check data structure, for products behind an existing mortgage
+type LienCheck struct {
+	ID              string            `json:"id"`
+	Liens           []activities.Lien `json:"liens"`
+	ExistingBalance float64           `json:"existing_balance"`
+	Reference       string            `json:"reference"`
+	Status          string            `json:"status"`
+	Error           string            `json:"error,omitempty"`
+	CompletedAt     *time.Time        `json:"completed_at"`
+	CreatedAt       time.Time         `json:"created_at"`
+}
+
 // Credit score data structure
 type CreditScore struct {
-	ID           string     `json:"id"`
-	Score        int        `json:"score"`
-	Status       string     `json:"status"`
-	CompletedAt  *time.Time `json:"completed_at"`
-	CreatedAt    time.Time  `json:"created_at"`
+	ID              string                   `json:"id"`
+	Score           int                      `json:"score"`
+	Status          string                   `json:"status"`
+	Bureau          string                   `json:"bureau"`
+	PullType        creditbureau.PullType    `json:"pull_type"`
+	ReportReference string                   `json:"report_reference"`
+	ScoreModel      string                   `json:"score_model"`
+	Tradelines      []creditbureau.Tradeline `json:"tradelines"`
+	Inquiries       int                      `json:"inquiries"`
+	Error           string                   `json:"error,omitempty"`
+	CompletedAt     *time.Time               `json:"completed_at"`
+	CreatedAt       time.Time                `json:"created_at"`
+}
+
+// Recommendation is the automated decisioning result shown to the
+// underwriter
+type Recommendation struct {
+	decisioning.Result
+	EvaluatedAt time.Time `json:"evaluated_at"`
 }
 
 // Underwriting decision data structure
 type UnderwritingDecision struct {
-	ID            string    `json:"id"`
-	Decision      string    `json:"decision"`
-	Comments      string    `json:"comments"`
-	UnderwriterID string    `json:"underwriter_id"`
-	DecisionDate  time.Time `json:"decision_date"`
+	ID            string `json:"id"`
+	Decision      string `json:"decision"`
+	Comments      string `json:"comments"`
+	UnderwriterID string `json:"underwriter_id"`
+	// Recommendation is the automated recommendation the decision was made
+	// against
+	Recommendation decisioning.Recommendation `json:"recommendation"`
+	DecisionDate   time.Time                  `json:"decision_date"`
 }
 
 // Workflow state
@@ -106,10 +216,74 @@ type LoanOriginationState struct {
 	LoanApplication      LoanApplication       `json:"loan_application"`
 	Documents            []Document            `json:"documents"`
 	Appraisal            *Appraisal            `json:"appraisal"`
+	VehicleValuation     *VehicleValuation     `json:"vehicle_valuation"`
+	LienCheck            *LienCheck            `json:"lien_check"`
 	CreditScore          *CreditScore          `json:"credit_score"`
+	Recommendation       *Recommendation       `json:"recommendation"`
 	UnderwritingDecision *UnderwritingDecision `json:"underwriting_decision"`
+	DocumentChecklist    []ChecklistItem       `json:"document_checklist"`
+	Closure              *Closure              `json:"closure"`
+	Terms                LoanTerms             `json:"terms"`
+	CounterOffer         *CounterOffer         `json:"counter_offer"`
+	LoanAgreement        *LoanAgreement        `json:"loan_agreement"`
+	Signature            *Signature            `json:"signature"`
+	Funding              *Funding              `json:"funding"`
+	FundingFailures      []FundingFailure      `json:"funding_failures,omitempty"`
+	Conditions           []Condition           `json:"conditions"`
+	AdverseActionNotice  *AdverseActionNotice  `json:"adverse_action_notice"`
+	Notifications        []Notification        `json:"notifications"`
+	Policy               policy.Snapshot       `json:"policy"`
 	Status               string                `json:"status"`
 	NextStep             string                `json:"next_step"`
+
+	// searchAttributes holds the values last upserted to visibility
+	searchAttributes map[string]interface{}
+	// pendingNotifications are the messages queued for the borrower since
+	// the state was last published
+	pendingNotifications []pendingNotification
+}
+
+// UpdateRejectedErrorType is the application error type returned by update
+// validators when the workflow is not in a stage that accepts the update.
+const UpdateRejectedErrorType = "UpdateRejected"
+
+// ProjectLoanStateActivity is the activity that writes each state change to
+// the read-model projection. It is registered by the worker from the
+// projection package, which depends on this package's types, so the workflow
+// refers to it by name.
+const ProjectLoanStateActivity = "ProjectLoanState"
+
+// legacyPolicy is the policy of an input without one, which is how inputs
+// were shaped before the policy was part of them. It holds the rules they
+// ran with and must not change, so the values are spelled out rather than
+// read from the current defaults.
+func legacyPolicy() policy.Snapshot {
+	return policy.Snapshot{
+		Version: "legacy",
+		Product: policy.Product{
+			Name:              "mortgage",
+			RequiredDocuments: []policy.DocumentType{policy.DocumentIncomeStatement, policy.DocumentBankStatement},
+			RequireAppraisal:  true,
+			Decisioning: decisioning.Policy{
+				MinCreditScore:    580,
+				ReferCreditScore:  660,
+				MaxLTV:            0.97,
+				ReferLTV:          0.80,
+				MaxDTI:            0.50,
+				ReferDTI:          0.43,
+				AssumedRate:       0.07,
+				AssumedTermMonths: 360,
+			},
+			SLA: policy.SLA{
+				Processing: policy.Days(30),
+				Funding:    policy.Days(7),
+			},
+		},
+	}
+}
+
+func rejectUpdate(format string, args ...interface{}) error {
+	return temporal.NewApplicationError(fmt.Sprintf(format, args...), UpdateRejectedErrorType)
 }
 
 func LoanOriginationWorkflow(ctx workflow.Context, input LoanOriginationWorkflowInput) error {
@@ -122,16 +296,33 @@ func LoanOriginationWorkflow(ctx workflow.Context, input LoanOriginationWorkflow
 	}
 	ctx = workflow.WithActivityOptions(ctx, activityOptions)
 
+	rules := legacyPolicy()
+	if input.Policy != nil {
+		rules = *input.Policy
+	}
+	if input.LoanApplication.Product == "" {
+		input.LoanApplication.Product = rules.Product.Name
+	}
+
 	// Initialize workflow state with loan application data
 	state := &LoanOriginationState{
 		LoanApplication: input.LoanApplication,
 		Documents:       []Document{},
-		Status:          "processing",
+		Policy:          rules,
+		Terms: newLoanTerms(input.LoanApplication.LoanAmount,
+			rules.Product.Decisioning.AssumedRate, rules.Product.Decisioning.AssumedTermMonths),
+	}
+	for _, documentType := range rules.Product.RequiredDocuments {
+		state.DocumentChecklist = append(state.DocumentChecklist, ChecklistItem{
+			DocumentType: documentType,
+			Status:       "missing",
+			RequestedAt:  workflow.Now(ctx),
+		})
 	}
 
 	// Update loan status to processing
-	state.LoanApplication.Status = "processing"
-	state.Status = "processing"
+	state.setStatus("processing")
+	state.notifyBorrower(notify.EventApplicationReceived, notify.Data{})
 
 	// Set up query handlers
 	err := workflow.SetQueryHandler(ctx, "getLoanApplication", func() (LoanOriginationState, error) {
@@ -141,79 +332,351 @@ func LoanOriginationWorkflow(ctx workflow.Context, input LoanOriginationWorkflow
 		return err
 	}
 
-	// Generate loan agreement
-	workflow.ExecuteActivity(ctx, activities.GenerateLoanAgreement, activities.GenerateLoanAgreementInput{
-		LoanApplicationID: state.LoanApplication.ID,
-	})
+	// Update handlers change state outside the main loop, so they notify it
+	// through this channel to re-evaluate the current step.
+	stateChanged := workflow.NewBufferedChannel(ctx, 1)
+	err = setUpdateHandlers(ctx, state, stateChanged)
+	if err != nil {
+		return err
+	}
 
-	err = runWorkflowSteps(ctx, state)
+	err = generateLoanAgreement(ctx, state)
 	if err != nil {
 		return err
 	}
 
-	// Process based on underwriting decision
-	if state.UnderwritingDecision != nil {
-		if state.UnderwritingDecision.Decision == "approved" {
-			state.LoanApplication.Status = "approved"
-			state.Status = "approved"
+	err = runWorkflowSteps(ctx, state, stateChanged)
+	if err != nil {
+		return err
+	}
 
-			// Wait for funding completion
-			err = waitForFunding(ctx, state)
+	// Process based on underwriting decision
+	switch {
+	case state.closed():
+		// Withdrawn or cancelled while processing
+	case state.UnderwritingDecision == nil:
+		state.setStatus("incomplete")
+		state.notifyBorrower(notify.EventIncomplete, notify.Data{})
+	case state.UnderwritingDecision.Decision == "approved":
+		err = fundLoan(ctx, state, stateChanged)
+		if err != nil {
+			return err
+		}
+	case state.UnderwritingDecision.Decision == "counter_offer":
+		err = waitForCounterOfferResponse(ctx, state, stateChanged)
+		if err != nil {
+			return err
+		}
+		if state.Status == "approved" {
+			err = fundLoan(ctx, state, stateChanged)
 			if err != nil {
 				return err
 			}
+		}
+	default:
+		state.setStatus("rejected")
+		state.NextStep = "Generating adverse action notice"
+		err = publishState(ctx, state)
+		if err != nil {
+			return err
+		}
+		sendAdverseActionNotice(ctx, state)
+		state.notifyBorrower(notify.EventRejected, notify.Data{})
+	}
 
-			// Release funds
-			workflow.ExecuteActivity(ctx, activities.ProcessFunding, activities.ProcessFundingInput{
-				LoanApplicationID: state.LoanApplication.ID,
-			}).Get(ctx, nil)
-		} else {
-			state.LoanApplication.Status = "rejected"
-			state.Status = "rejected"
+	if state.closed() {
+		state.NextStep = "Cleaning up closed application"
+		err = publishState(ctx, state)
+		if err != nil {
+			return err
 		}
-	} else {
-		state.LoanApplication.Status = "incomplete"
-		state.Status = "incomplete"
+		runClosureCleanup(ctx, state)
 	}
 	state.NextStep = "n/a"
+	err = publishState(ctx, state)
+	if err != nil {
+		return err
+	}
 
 	logger.Info("Loan origination workflow completed", "loanApplicationID", input.LoanApplication.ID, "status", state.Status)
 	return nil
 }
 
-func waitForFunding(ctx workflow.Context, state *LoanOriginationState) error {
-	logger := workflow.GetLogger(ctx)
+// setUpdateHandlers registers an update for every human-in-the-loop action.
+// Each update is validated against the current stage, applied to the state
+// and answered with the resulting state.
+func setUpdateHandlers(ctx workflow.Context, state *LoanOriginationState, stateChanged workflow.Channel) error {
+	updated := func() (LoanOriginationState, error) {
+		if state.Status == "processing" {
+			state.refreshNextStep()
+		}
+		stateChanged.SendAsync(true)
+		return *state, nil
+	}
+
+	err := workflow.SetUpdateHandlerWithOptions(ctx, "uploadDocument",
+		func(ctx workflow.Context, signal DocumentUploadedSignal) (LoanOriginationState, error) {
+			applyDocumentUpload(ctx, state, signal)
+			return updated()
+		},
+		workflow.UpdateHandlerOptions{
+			Validator: func(signal DocumentUploadedSignal) error {
+				return validateDocumentUpload(state, signal)
+			},
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	err = workflow.SetUpdateHandlerWithOptions(ctx, "verifyDocument",
+		func(ctx workflow.Context, signal DocumentVerificationSignal) (LoanOriginationState, error) {
+			applyDocumentVerification(ctx, state, signal)
+			return updated()
+		},
+		workflow.UpdateHandlerOptions{
+			Validator: func(signal DocumentVerificationSignal) error {
+				return validateDocumentVerification(state, signal)
+			},
+		},
+	)
+	if err != nil {
+		return err
+	}
 
-	state.NextStep = "Waiting for funding"
+	err = workflow.SetUpdateHandlerWithOptions(ctx, "completeAppraisal",
+		func(ctx workflow.Context, signal AppraisalCompletedSignal) (LoanOriginationState, error) {
+			applyAppraisal(ctx, state, signal)
+			return updated()
+		},
+		workflow.UpdateHandlerOptions{
+			Validator: func(signal AppraisalCompletedSignal) error {
+				return validateAppraisal(state, signal)
+			},
+		},
+	)
+	if err != nil {
+		return err
+	}
 
-	// Set up signal channel for funding completion
+	err = workflow.SetUpdateHandlerWithOptions(ctx, "makeUnderwritingDecision",
+		func(ctx workflow.Context, signal UnderwritingDecisionSignal) (LoanOriginationState, error) {
+			applyUnderwritingDecision(ctx, state, signal)
+			return updated()
+		},
+		workflow.UpdateHandlerOptions{
+			Validator: func(signal UnderwritingDecisionSignal) error {
+				return validateUnderwritingDecision(state, signal)
+			},
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	err = workflow.SetUpdateHandlerWithOptions(ctx, "respondToCounterOffer",
+		func(ctx workflow.Context, signal CounterOfferResponseSignal) (LoanOriginationState, error) {
+			applyCounterOfferResponse(ctx, state, signal)
+			return updated()
+		},
+		workflow.UpdateHandlerOptions{
+			Validator: func(signal CounterOfferResponseSignal) error {
+				return validateCounterOfferResponse(state, signal)
+			},
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	err = workflow.SetUpdateHandlerWithOptions(ctx, "clearCondition",
+		func(ctx workflow.Context, signal ClearConditionSignal) (LoanOriginationState, error) {
+			applyConditionCleared(ctx, state, signal)
+			return updated()
+		},
+		workflow.UpdateHandlerOptions{
+			Validator: func(signal ClearConditionSignal) error {
+				return validateConditionCleared(state, signal)
+			},
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	err = workflow.SetUpdateHandlerWithOptions(ctx, "signAgreement",
+		func(ctx workflow.Context, signal AgreementSignatureSignal) (LoanOriginationState, error) {
+			applyAgreementSignature(ctx, state, signal)
+			return updated()
+		},
+		workflow.UpdateHandlerOptions{
+			Validator: func(signal AgreementSignatureSignal) error {
+				return validateAgreementSignature(state, signal)
+			},
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	err = workflow.SetUpdateHandlerWithOptions(ctx, "closeApplication",
+		func(ctx workflow.Context, signal CloseApplicationSignal) (LoanOriginationState, error) {
+			applyClosure(ctx, state, signal)
+			return updated()
+		},
+		workflow.UpdateHandlerOptions{
+			Validator: func(signal CloseApplicationSignal) error {
+				return validateClosure(state, signal)
+			},
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	err = workflow.SetUpdateHandlerWithOptions(ctx, "completeFunding",
+		func(ctx workflow.Context, signal FundingCompletedSignal) (LoanOriginationState, error) {
+			applyFunding(ctx, state, signal)
+			return updated()
+		},
+		workflow.UpdateHandlerOptions{
+			Validator: func(signal FundingCompletedSignal) error {
+				return validateFunding(state, signal)
+			},
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	return workflow.SetUpdateHandlerWithOptions(ctx, "retryFunding",
+		func(ctx workflow.Context, signal RetryFundingSignal) (LoanOriginationState, error) {
+			applyFundingRetry(ctx, state, signal)
+			return updated()
+		},
+		workflow.UpdateHandlerOptions{
+			Validator: func(signal RetryFundingSignal) error {
+				return validateFundingRetry(state, signal)
+			},
+		},
+	)
+}
+
+// fundLoan waits for the conditions of the approval to be cleared, then
+// for the borrower to sign the loan agreement, then for the fund manager,
+// and releases the funds, unless the loan is withdrawn or cancelled first.
+// A disbursement that fails is compensated, and a retried loan starts over
+// from signing a new agreement.
+func fundLoan(ctx workflow.Context, state *LoanOriginationState, stateChanged workflow.ReceiveChannel) error {
+	if state.Status == "conditionally_approved" {
+		err := waitForConditions(ctx, state, stateChanged)
+		if err != nil {
+			return err
+		}
+		if state.Status != "approved" {
+			return nil
+		}
+	}
+
+	for {
+		err := waitForSignature(ctx, state, stateChanged)
+		if err != nil {
+			return err
+		}
+		if state.Status != "approved" {
+			return nil
+		}
+
+		err = waitForFunding(ctx, state, stateChanged)
+		if err != nil {
+			return err
+		}
+		if state.Status != "funded" {
+			return nil
+		}
+
+		err = disburseLoan(ctx, state)
+		if err == nil {
+			state.notifyBorrower(notify.EventFunded, notify.Data{LoanAmount: state.Funding.Amount})
+			return nil
+		}
+		compensateFunding(ctx, state, err)
+
+		err = waitForFundingRetry(ctx, state, stateChanged)
+		if err != nil {
+			return err
+		}
+		if state.Status != "approved" {
+			return nil
+		}
+
+		// The failed attempt voided the signed agreement, so the retry is
+		// signed and funded under a new version
+		err = publishState(ctx, state)
+		if err != nil {
+			return err
+		}
+		err = generateLoanAgreement(ctx, state)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func waitForFunding(ctx workflow.Context, state *LoanOriginationState, stateChanged workflow.ReceiveChannel) error {
+	logger := workflow.GetLogger(ctx)
+
+	// Set up signal channels for funding completion and closure
 	fundingChannel := workflow.GetSignalChannel(ctx, "funding-completed")
+	closeChannel := workflow.GetSignalChannel(ctx, "application-closed")
 
-	selector := workflow.NewSelector(ctx)
+	// Add timeout for funding
+	timerCtx, timerCancel := workflow.WithCancel(ctx)
+	timer := workflow.NewTimer(timerCtx, time.Duration(state.Policy.Product.SLA.Funding))
 
-	// Listen for funding completion signal
-	selector.AddReceive(fundingChannel, func(c workflow.ReceiveChannel, more bool) {
-		var signal FundingCompletedSignal
-		c.Receive(ctx, &signal)
+	for state.Status == "approved" {
+		state.NextStep = "Waiting for funding"
 
-		state.LoanApplication.Status = "funded"
-		state.Status = "funded"
-		logger.Info("Funding completed", "fundManagerID", signal.FundManagerID, "amount", signal.FundingAmount)
-	})
+		selector := workflow.NewSelector(ctx)
 
-	// Add timeout for funding (7 days)
-	selector.AddFuture(workflow.NewTimer(ctx, 7*24*time.Hour), func(f workflow.Future) {
-		logger.Error("Timeout waiting for funding completion")
-		state.LoanApplication.Status = "funding_timeout"
-		state.Status = "funding_timeout"
-	})
+		// Listen for funding completion signal
+		selector.AddReceive(fundingChannel, func(c workflow.ReceiveChannel, more bool) {
+			var signal FundingCompletedSignal
+			c.Receive(ctx, &signal)
+			if err := validateFunding(state, signal); err != nil {
+				logger.Warn("Ignoring funding-completed signal", "error", err)
+				return
+			}
+			applyFunding(ctx, state, signal)
+		})
 
-	selector.Select(ctx)
+		selector.AddReceive(closeChannel, func(c workflow.ReceiveChannel, more bool) {
+			receiveClosure(ctx, c, state)
+		})
+
+		selector.AddReceive(stateChanged, func(c workflow.ReceiveChannel, more bool) {
+			c.Receive(ctx, nil)
+		})
+
+		err := publishState(ctx, state)
+		if err != nil {
+			return err
+		}
+
+		selector.AddFuture(timer, func(f workflow.Future) {
+			logger.Error("Timeout waiting for funding completion")
+			state.setStatus("funding_timeout")
+		})
+
+		selector.Select(ctx)
+	}
+
+	timerCancel()
 
 	return nil
 }
 
-func runWorkflowSteps(ctx workflow.Context, state *LoanOriginationState) error {
+func runWorkflowSteps(ctx workflow.Context, state *LoanOriginationState, stateChanged workflow.ReceiveChannel) error {
 	logger := workflow.GetLogger(ctx)
 
 	// Set up signal channels
@@ -221,190 +684,103 @@ func runWorkflowSteps(ctx workflow.Context, state *LoanOriginationState) error {
 	verificationChannel := workflow.GetSignalChannel(ctx, "document-verified")
 	appraisalChannel := workflow.GetSignalChannel(ctx, "appraisal-completed")
 	underwritingChannel := workflow.GetSignalChannel(ctx, "underwriting-decision")
+	responseChannel := workflow.GetSignalChannel(ctx, "counter-offer-response")
+	closeChannel := workflow.GetSignalChannel(ctx, "application-closed")
 
-	// Track completion status
-	requiredDocuments := 2
-	appraisalCompleted := state.Appraisal != nil
-	underwritingCompleted := false
-
+	timedOut := false
 	timerCtx, timerCancel := workflow.WithCancel(ctx)
-	timer := workflow.NewTimer(timerCtx, 30*24*time.Hour)
+	timer := workflow.NewTimer(timerCtx, time.Duration(state.Policy.Product.SLA.Processing))
 
 	// Main workflow loop - listen for all signals
-	for !underwritingCompleted {
-		verifiedDocCount := 0
-		// Count already verified documents
-		for _, doc := range state.Documents {
-			if doc.VerificationStatus == "verified" {
-				verifiedDocCount++
-			}
-		}
-
-		// Count rejected documents
-		rejectedDocCount := 0
-		for _, doc := range state.Documents {
-			if doc.VerificationStatus == "rejected" {
-				rejectedDocCount++
-			}
-		}
-
-		moreDocsRequired := (len(state.Documents) - rejectedDocCount) < requiredDocuments
+	for !state.underwritingCompleted() && !state.closed() && !timedOut {
+		state.refreshNextStep()
 
 		selector := workflow.NewSelector(ctx)
 
 		switch {
 		// Listen for document verification (only if we have uploaded documents)
-		case len(state.Documents) > 0 && verifiedDocCount+rejectedDocCount < len(state.Documents):
-			state.NextStep = "Waiting for document verification"
-
+		case state.pendingVerification():
 			selector.AddReceive(verificationChannel, func(c workflow.ReceiveChannel, more bool) {
 				var signal DocumentVerificationSignal
 				c.Receive(ctx, &signal)
-
-				// Update document verification status
-				for i, doc := range state.Documents {
-					if doc.ID == signal.DocumentID {
-						now := workflow.Now(ctx)
-						state.Documents[i].VerificationStatus = signal.VerificationStatus
-						state.Documents[i].VerificationDetails = signal.VerificationDetails
-						state.Documents[i].VerifiedAt = &now
-
-						switch signal.VerificationStatus {
-						case "verified":
-							verifiedDocCount++
-						case "rejected":
-							rejectedDocCount++
-						}
-
-						logger.Info("Document", signal.VerificationStatus, "documentID", signal.DocumentID, "status", signal.VerificationStatus, "verified", verifiedDocCount, "rejected", rejectedDocCount)
-						break
-					}
-				}
+				applyDocumentVerification(ctx, state, signal)
 			})
 
 			fallthrough
 
 		// Listen for document uploads
-		case moreDocsRequired:
-			if moreDocsRequired {
-				state.NextStep = fmt.Sprintf("Waiting for customer documents: %d more required", requiredDocuments-len(state.Documents)+rejectedDocCount)
-			}
-
+		case state.moreDocumentsRequired():
 			selector.AddReceive(documentUploadChannel, func(c workflow.ReceiveChannel, more bool) {
 				var signal DocumentUploadedSignal
 				c.Receive(ctx, &signal)
-
-				// Create document record
-				doc := Document{
-					ID:                 signal.DocumentID,
-					DocumentType:       signal.DocumentType,
-					FileName:           signal.DocumentType + "_document.pdf", // Simplified
-					FilePath:           "/uploads/" + signal.DocumentID,
-					VerificationStatus: "pending",
-					UploadedAt:         workflow.Now(ctx),
-				}
-				state.Documents = append(state.Documents, doc)
-				logger.Info("Document uploaded", "documentID", signal.DocumentID, "type", signal.DocumentType, "count", len(state.Documents))
+				applyDocumentUpload(ctx, state, signal)
 			})
 
 		// Listen for appraisal completion
-		case !appraisalCompleted:
-
-			state.NextStep = "Waiting for appraisal"
-
+		case state.appraisalPending():
 			selector.AddReceive(appraisalChannel, func(c workflow.ReceiveChannel, more bool) {
 				var signal AppraisalCompletedSignal
 				c.Receive(ctx, &signal)
-
-				now := workflow.Now(ctx)
-				state.Appraisal = &Appraisal{
-					ID:             "appraisal-" + state.LoanApplication.ID,
-					PropertyValue:  signal.PropertyValue,
-					AppraisalNotes: signal.AppraisalNotes,
-					AppraiserID:    signal.AppraiserID,
-					Status:         "completed",
-					CompletedAt:    &now,
-					CreatedAt:      now,
-				}
-				appraisalCompleted = true
-				logger.Info("Appraisal completed", "propertyValue", signal.PropertyValue)
+				applyAppraisal(ctx, state, signal)
 			})
 
 		// Perform credit score check after appraisal is completed
-		case len(state.Documents) >= requiredDocuments && appraisalCompleted:
-			state.NextStep = "Performing credit score check"
-
-			// Initialize credit score record if not exists
-			if state.CreditScore == nil {
-				state.CreditScore = &CreditScore{
-					ID:           "credit-score-" + state.LoanApplication.ID,
-					Status:       "in_progress",
-					CreatedAt:    workflow.Now(ctx),
-				}
+		default:
+			// The application may be closed while each automated check runs,
+			// so the next one only starts if it is still open
+			if state.vehicleValuationPending() {
+				runVehicleValuation(ctx, state)
+				state.refreshNextStep()
 			}
-
-			// Retry credit score check with exponential backoff
-			retryOptions := workflow.ActivityOptions{
-				StartToCloseTimeout: 30 * time.Second,
-				RetryPolicy: &temporal.RetryPolicy{
-					InitialInterval:        1 * time.Second,
-					BackoffCoefficient:     2.0,
-					MaximumInterval:        10 * time.Second,
-					MaximumAttempts:        3,
-					NonRetryableErrorTypes: []string{},
-				},
+			if state.lienCheckPending() && !state.closed() {
+				runLienCheck(ctx, state)
+				state.refreshNextStep()
 			}
-			retryCtx := workflow.WithActivityOptions(ctx, retryOptions)
-
-			var creditScoreResult *activities.CreditScoreCheckResult
-			err := workflow.ExecuteActivity(retryCtx, activities.CreditScoreCheck, activities.CreditScoreCheckInput{
-				LoanApplicationID: state.LoanApplication.ID,
-				BorrowerName:      state.LoanApplication.BorrowerName,
-			}).Get(ctx, &creditScoreResult)
-
-			if err != nil {
-				logger.Error("Credit score check failed", "error", err)
-				// Will retry automatically due to retry policy
+			if !state.creditCheckConcluded() && !state.closed() {
+				runCreditCheck(ctx, state)
+				state.refreshNextStep()
+			}
+			if state.Recommendation == nil && !state.closed() {
+				runDecisioning(ctx, state)
+				state.refreshNextStep()
 			}
-
-			// Credit score check succeeded
-			now := workflow.Now(ctx)
-			state.CreditScore.Score = creditScoreResult.CreditScore
-			state.CreditScore.Status = "completed"
-			state.CreditScore.CompletedAt = &now
-
-			logger.Info("Credit score check completed", "score", creditScoreResult.CreditScore)
 
 			// Listen for underwriting decision (only if we have enough documents, appraisal is done, and credit score is obtained)
 			// Allow underwriting even if some documents are rejected - underwriter can decide
-
-			state.NextStep = "Waiting for underwriting decision"
-
 			selector.AddReceive(underwritingChannel, func(c workflow.ReceiveChannel, more bool) {
 				var signal UnderwritingDecisionSignal
 				c.Receive(ctx, &signal)
-
-				state.UnderwritingDecision = &UnderwritingDecision{
-					ID:            "decision-" + state.LoanApplication.ID,
-					Decision:      signal.Decision,
-					Comments:      signal.Comments,
-					UnderwriterID: signal.UnderwriterID,
-					DecisionDate:  workflow.Now(ctx),
-				}
-				if signal.Decision != "needs_more_info" {
-					underwritingCompleted = true
-				} else {
-					requiredDocuments++
+				if err := validateUnderwritingDecision(state, signal); err != nil {
+					logger.Warn("Ignoring underwriting-decision signal", "error", err)
+					return
 				}
-				logger.Info("Underwriting decision received", "decision", signal.Decision)
+				applyUnderwritingDecision(ctx, state, signal)
 			})
+		}
 
+		selector.AddReceive(closeChannel, func(c workflow.ReceiveChannel, more bool) {
+			receiveClosure(ctx, c, state)
+		})
+
+		// Drop counter-offer responses sent before any offer was made
+		selector.AddReceive(responseChannel, func(c workflow.ReceiveChannel, more bool) {
+			receiveCounterOfferResponse(ctx, c, state)
+		})
+
+		// Wake up after an update has changed the state
+		selector.AddReceive(stateChanged, func(c workflow.ReceiveChannel, more bool) {
+			c.Receive(ctx, nil)
+		})
+
+		err := publishState(ctx, state)
+		if err != nil {
+			return err
 		}
 
 		// Add timeout to prevent infinite waiting
 		selector.AddFuture(timer, func(f workflow.Future) {
 			logger.Error("Workflow timeout - completing with current state")
-			underwritingCompleted = true
+			timedOut = true
 		})
 
 		selector.Select(ctx)
@@ -414,3 +790,610 @@ func runWorkflowSteps(ctx workflow.Context, state *LoanOriginationState) error {
 
 	return nil
 }
+
+// runCreditCheck pulls the borrower's credit report with a hard inquiry and
+// records it on the state. Transient bureau failures are retried; if the
+// check still fails the credit score is marked failed and left to the
+// underwriter.
+func runCreditCheck(ctx workflow.Context, state *LoanOriginationState) {
+	logger := workflow.GetLogger(ctx)
+
+	// Initialize credit score record if not exists
+	if state.CreditScore == nil {
+		state.CreditScore = &CreditScore{
+			ID:        "credit-score-" + state.LoanApplication.ID,
+			Status:    "in_progress",
+			CreatedAt: workflow.Now(ctx),
+		}
+	}
+
+	// Retry credit score check with exponential backoff
+	retryOptions := workflow.ActivityOptions{
+		StartToCloseTimeout: 30 * time.Second,
+		RetryPolicy: &temporal.RetryPolicy{
+			InitialInterval:        1 * time.Second,
+			BackoffCoefficient:     2.0,
+			MaximumInterval:        10 * time.Second,
+			MaximumAttempts:        5,
+			NonRetryableErrorTypes: []string{activities.CreditBureauRejectedErrorType},
+		},
+	}
+	retryCtx := workflow.WithActivityOptions(ctx, retryOptions)
+
+	var credit *activities.CreditActivities
+	var result activities.CreditScoreCheckResult
+	err := workflow.ExecuteActivity(retryCtx, credit.CreditScoreCheck, activities.CreditScoreCheckInput{
+		LoanApplicationID: state.LoanApplication.ID,
+		BorrowerName:      state.LoanApplication.BorrowerName,
+		BorrowerEmail:     state.LoanApplication.BorrowerEmail,
+		PullType:          creditbureau.PullHard,
+	}).Get(ctx, &result)
+
+	now := workflow.Now(ctx)
+	state.CreditScore.CompletedAt = &now
+	state.CreditScore.PullType = creditbureau.PullHard
+	if err != nil {
+		logger.Error("Credit score check failed", "error", err)
+		state.CreditScore.Status = "failed"
+		state.CreditScore.Error = err.Error()
+		return
+	}
+
+	// Credit score check succeeded
+	report := result.Report
+	state.CreditScore.Score = report.Score
+	state.CreditScore.Status = "completed"
+	state.CreditScore.Bureau = report.Bureau
+	state.CreditScore.PullType = report.PullType
+	state.CreditScore.ReportReference = report.Reference
+	state.CreditScore.ScoreModel = report.ScoreModel
+	state.CreditScore.Tradelines = report.Tradelines
+	state.CreditScore.Inquiries = report.Inquiries
+	state.CreditScore.Error = ""
+
+	logger.Info("Credit score check completed", "score", report.Score, "bureau", report.Bureau, "reference", report.Reference)
+}
+
+// runVehicleValuation values the vehicle securing an auto loan. If it cannot
+// be valued the valuation is marked failed and decisioning refers the loan.
+func runVehicleValuation(ctx workflow.Context, state *LoanOriginationState) {
+	logger := workflow.GetLogger(ctx)
+
+	now := workflow.Now(ctx)
+	state.VehicleValuation = &VehicleValuation{
+		ID:          "vehicle-valuation-" + state.LoanApplication.ID,
+		Status:      "failed",
+		CompletedAt: &now,
+		CreatedAt:   now,
+	}
+	vehicle := state.LoanApplication.Vehicle
+	if vehicle == nil {
+		state.VehicleValuation.Error = "no vehicle details on the application"
+		logger.Error("Vehicle valuation skipped", "error", state.VehicleValuation.Error)
+		return
+	}
+
+	valuationCtx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: 30 * time.Second,
+		RetryPolicy: &temporal.RetryPolicy{
+			MaximumAttempts:        5,
+			NonRetryableErrorTypes: []string{activities.CollateralRejectedErrorType},
+		},
+	})
+
+	var result activities.VehicleValuationResult
+	err := workflow.ExecuteActivity(valuationCtx, activities.ValueVehicle, activities.VehicleValuationInput{
+		LoanApplicationID: state.LoanApplication.ID,
+		VIN:               vehicle.VIN,
+		Year:              vehicle.Year,
+		Make:              vehicle.Make,
+		Model:             vehicle.Model,
+		Mileage:           vehicle.Mileage,
+	}).Get(ctx, &result)
+
+	completedAt := workflow.Now(ctx)
+	state.VehicleValuation.CompletedAt = &completedAt
+	if err != nil {
+		logger.Error("Vehicle valuation failed", "error", err)
+		state.VehicleValuation.Error = err.Error()
+		return
+	}
+
+	state.VehicleValuation.Status = "completed"
+	state.VehicleValuation.Value = result.Value
+	state.VehicleValuation.Source = result.Source
+	state.VehicleValuation.Reference = result.Reference
+	logger.Info("Vehicle valuation completed", "value", result.Value)
+}
+
+// runLienCheck finds the liens already recorded against the property. If the
+// search fails the check is marked failed and decisioning refers the loan.
+func runLienCheck(ctx workflow.Context, state *LoanOriginationState) {
+	logger := workflow.GetLogger(ctx)
+
+	now := workflow.Now(ctx)
+	state.LienCheck = &LienCheck{
+		ID:        "lien-check-" + state.LoanApplication.ID,
+		Liens:     []activities.Lien{},
+		Status:    "failed",
+		CreatedAt: now,
+	}
+
+	input := activities.LienCheckInput{
+		LoanApplicationID: state.LoanApplication.ID,
+		BorrowerName:      state.LoanApplication.BorrowerName,
+		PropertyAddress:   state.LoanApplication.PropertyAddress,
+	}
+	if state.Appraisal != nil {
+		input.PropertyValue = state.Appraisal.PropertyValue
+	}
+
+	lienCtx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: 30 * time.Second,
+		RetryPolicy: &temporal.RetryPolicy{
+			MaximumAttempts:        5,
+			NonRetryableErrorTypes: []string{activities.CollateralRejectedErrorType},
+		},
+	})
+
+	var result activities.LienCheckResult
+	err := workflow.ExecuteActivity(lienCtx, activities.CheckLiens, input).Get(ctx, &result)
+
+	completedAt := workflow.Now(ctx)
+	state.LienCheck.CompletedAt = &completedAt
+	if err != nil {
+		logger.Error("Lien check failed", "error", err)
+		state.LienCheck.Error = err.Error()
+		return
+	}
+
+	state.LienCheck.Status = "completed"
+	state.LienCheck.Liens = result.Liens
+	state.LienCheck.Reference = result.Reference
+	for _, lien := range result.Liens {
+		state.LienCheck.ExistingBalance += lien.Balance
+	}
+	logger.Info("Lien check completed", "liens", len(result.Liens), "existingBalance", state.LienCheck.ExistingBalance)
+}
+
+// runDecisioning evaluates the loan against the underwriting policy and
+// records the recommendation for the underwriter. If the evaluation fails
+// the loan is referred for manual review.
+func runDecisioning(ctx workflow.Context, state *LoanOriginationState) {
+	logger := workflow.GetLogger(ctx)
+
+	application := decisioning.Application{
+		LoanAmount:    state.LoanApplication.LoanAmount,
+		MonthlyIncome: state.LoanApplication.MonthlyIncome,
+	}
+	switch {
+	case state.Appraisal != nil:
+		application.CollateralValue = state.Appraisal.PropertyValue
+	case state.VehicleValuation != nil && state.VehicleValuation.Status == "completed":
+		application.CollateralValue = state.VehicleValuation.Value
+	}
+	if state.LienCheck != nil {
+		application.ExistingLienBalance = state.LienCheck.ExistingBalance
+		application.LiensUnknown = state.LienCheck.Status != "completed"
+	}
+	if state.creditScoreCompleted() {
+		application.CreditReportAvailable = true
+		application.CreditScore = state.CreditScore.Score
+		application.MonthlyDebtPayments = creditbureau.Report{Tradelines: state.CreditScore.Tradelines}.MonthlyDebtPayments()
+	}
+
+	decisionCtx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: 10 * time.Second,
+		RetryPolicy: &temporal.RetryPolicy{
+			MaximumAttempts: 3,
+		},
+	})
+
+	var result decisioning.Result
+	err := workflow.ExecuteActivity(decisionCtx, activities.EvaluateLoan, activities.EvaluateLoanInput{
+		LoanApplicationID: state.LoanApplication.ID,
+		Application:       application,
+		Policy:            state.Policy.Product.Decisioning,
+	}).Get(ctx, &result)
+	if err != nil {
+		logger.Error("Automated decisioning failed", "error", err)
+		result = decisioning.Result{
+			Recommendation: decisioning.Refer,
+			Reasons: []decisioning.Reason{{
+				Code:    decisioning.ReasonDecisioningFailed,
+				Outcome: decisioning.Refer,
+				Message: err.Error(),
+			}},
+		}
+	}
+
+	state.Recommendation = &Recommendation{Result: result, EvaluatedAt: workflow.Now(ctx)}
+	logger.Info("Automated decisioning completed", "recommendation", result.Recommendation, "reasons", len(result.Reasons))
+}
+
+// publishState exposes the current state outside the workflow: it sends the
+// borrower the messages queued since it last ran, upserts the visibility
+// search attributes and writes the read-model projection.
+func publishState(ctx workflow.Context, state *LoanOriginationState) error {
+	sendNotifications(ctx, state)
+	state.LoanApplication.UpdatedAt = workflow.Now(ctx)
+
+	err := upsertSearchAttributes(ctx, state)
+	if err != nil {
+		return err
+	}
+
+	projectState(ctx, state)
+	return nil
+}
+
+// projectState writes the current state to the read-model projection. The
+// workflow remains the source of truth, so a failed projection is logged and
+// corrected by the next state change.
+func projectState(ctx workflow.Context, state *LoanOriginationState) {
+	projectionCtx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: 10 * time.Second,
+		RetryPolicy: &temporal.RetryPolicy{
+			MaximumAttempts: 5,
+		},
+	})
+	err := workflow.ExecuteActivity(projectionCtx, ProjectLoanStateActivity, *state).Get(ctx, nil)
+	if err != nil {
+		workflow.GetLogger(ctx).Error("Failed to project loan state", "error", err)
+	}
+}
+
+func applyDocumentUpload(ctx workflow.Context, state *LoanOriginationState, signal DocumentUploadedSignal) {
+	// Signals sent without file details fall back to placeholder names
+	fileName := signal.FileName
+	if fileName == "" {
+		fileName = string(signal.DocumentType) + "_document.pdf"
+	}
+	filePath := signal.FilePath
+	if filePath == "" {
+		filePath = "/uploads/" + signal.DocumentID
+	}
+
+	// Create document record
+	doc := Document{
+		ID:                 signal.DocumentID,
+		DocumentType:       signal.DocumentType,
+		FileName:           fileName,
+		FilePath:           filePath,
+		ContentType:        signal.ContentType,
+		Size:               signal.Size,
+		SHA256:             signal.SHA256,
+		VerificationStatus: "pending",
+		UploadedBy:         signal.UploadedBy,
+		UploadedAt:         workflow.Now(ctx),
+	}
+	state.Documents = append(state.Documents, doc)
+
+	// The upload satisfies the first outstanding item of its type
+	for i, item := range state.DocumentChecklist {
+		if item.DocumentType == doc.DocumentType && item.outstanding() {
+			state.DocumentChecklist[i].Status = "uploaded"
+			state.DocumentChecklist[i].DocumentID = doc.ID
+			break
+		}
+	}
+	workflow.GetLogger(ctx).Info("Document uploaded", "documentID", signal.DocumentID, "type", signal.DocumentType, "count", len(state.Documents))
+}
+
+func applyDocumentVerification(ctx workflow.Context, state *LoanOriginationState, signal DocumentVerificationSignal) {
+	// Update document verification status
+	for i, doc := range state.Documents {
+		if doc.ID == signal.DocumentID {
+			now := workflow.Now(ctx)
+			state.Documents[i].VerificationStatus = signal.VerificationStatus
+			state.Documents[i].VerificationDetails = signal.VerificationDetails
+			state.Documents[i].VerifiedBy = signal.VerifiedBy
+			state.Documents[i].VerifiedAt = &now
+			for j, item := range state.DocumentChecklist {
+				if item.DocumentID == doc.ID {
+					state.DocumentChecklist[j].Status = signal.VerificationStatus
+				}
+			}
+			if signal.VerificationStatus == "rejected" {
+				reason, _ := signal.VerificationDetails["reason"].(string)
+				state.notifyBorrower(notify.EventDocumentRejected, notify.Data{
+					DocumentType: documentName(doc.DocumentType),
+					Reason:       reason,
+				})
+			}
+
+			workflow.GetLogger(ctx).Info("Document verification received", "documentID", signal.DocumentID, "status", signal.VerificationStatus)
+			return
+		}
+	}
+}
+
+func applyAppraisal(ctx workflow.Context, state *LoanOriginationState, signal AppraisalCompletedSignal) {
+	now := workflow.Now(ctx)
+	state.Appraisal = &Appraisal{
+		ID:             "appraisal-" + state.LoanApplication.ID,
+		PropertyValue:  signal.PropertyValue,
+		AppraisalNotes: signal.AppraisalNotes,
+		AppraiserID:    signal.AppraiserID,
+		Status:         "completed",
+		CompletedAt:    &now,
+		CreatedAt:      now,
+	}
+	workflow.GetLogger(ctx).Info("Appraisal completed", "propertyValue", signal.PropertyValue)
+}
+
+func applyUnderwritingDecision(ctx workflow.Context, state *LoanOriginationState, signal UnderwritingDecisionSignal) {
+	state.UnderwritingDecision = &UnderwritingDecision{
+		ID:            "decision-" + state.LoanApplication.ID,
+		Decision:      signal.Decision,
+		Comments:      signal.Comments,
+		UnderwriterID: signal.UnderwriterID,
+		DecisionDate:  workflow.Now(ctx),
+	}
+	if state.Recommendation != nil {
+		state.UnderwritingDecision.Recommendation = state.Recommendation.Recommendation
+	}
+
+	switch signal.Decision {
+	case "needs_more_info":
+		var requested []string
+		for _, request := range signal.RequestedDocuments {
+			state.DocumentChecklist = append(state.DocumentChecklist, ChecklistItem{
+				DocumentType: request.DocumentType,
+				Status:       "missing",
+				Reason:       request.Reason,
+				RequestedBy:  signal.UnderwriterID,
+				RequestedAt:  workflow.Now(ctx),
+			})
+			requested = append(requested, documentName(request.DocumentType))
+		}
+		state.notifyBorrower(notify.EventDocumentsRequested, notify.Data{Documents: requested})
+	case "approved":
+		if len(signal.Conditions) > 0 {
+			applyConditions(ctx, state, signal)
+			break
+		}
+		state.setStatus("approved")
+		state.NextStep = signatureStep
+	case "counter_offer":
+		applyCounterOffer(ctx, state, signal)
+	default:
+		state.setStatus("rejected")
+		state.NextStep = "n/a"
+	}
+	workflow.GetLogger(ctx).Info("Underwriting decision received", "decision", signal.Decision)
+}
+
+func applyFunding(ctx workflow.Context, state *LoanOriginationState, signal FundingCompletedSignal) {
+	state.Funding = &Funding{
+		Attempt:       len(state.FundingFailures) + 1,
+		Amount:        signal.FundingAmount,
+		FundManagerID: signal.FundManagerID,
+		Notes:         signal.FundingNotes,
+		FundedAt:      workflow.Now(ctx),
+	}
+	state.setStatus("funded")
+	state.NextStep = "n/a"
+	workflow.GetLogger(ctx).Info("Funding completed", "fundManagerID", signal.FundManagerID, "amount", signal.FundingAmount)
+}
+
+func validateDocumentUpload(state *LoanOriginationState, signal DocumentUploadedSignal) error {
+	if state.Status != "processing" || state.underwritingCompleted() {
+		return rejectUpdate("loan is %s and no longer accepts documents", state.Status)
+	}
+	if signal.DocumentID == "" || signal.DocumentType == "" {
+		return rejectUpdate("document id and type are required")
+	}
+	if !signal.DocumentType.Valid() {
+		return rejectUpdate("unknown document type %q", signal.DocumentType)
+	}
+	for _, doc := range state.Documents {
+		if doc.ID == signal.DocumentID {
+			return rejectUpdate("document %s has already been uploaded", signal.DocumentID)
+		}
+	}
+	return nil
+}
+
+func validateDocumentVerification(state *LoanOriginationState, signal DocumentVerificationSignal) error {
+	if state.Status != "processing" {
+		return rejectUpdate("loan is %s and no longer accepts document verification", state.Status)
+	}
+	if signal.VerificationStatus != "verified" && signal.VerificationStatus != "rejected" {
+		return rejectUpdate("verification status must be verified or rejected, got %q", signal.VerificationStatus)
+	}
+	for _, doc := range state.Documents {
+		if doc.ID == signal.DocumentID {
+			if doc.VerificationStatus != "pending" {
+				return rejectUpdate("document %s is already %s", signal.DocumentID, doc.VerificationStatus)
+			}
+			return nil
+		}
+	}
+	return rejectUpdate("document %s not found", signal.DocumentID)
+}
+
+func validateAppraisal(state *LoanOriginationState, signal AppraisalCompletedSignal) error {
+	if state.Status != "processing" {
+		return rejectUpdate("loan is %s and no longer accepts an appraisal", state.Status)
+	}
+	if state.product().Collateral() != policy.CollateralProperty || !state.Policy.Product.RequireAppraisal {
+		return rejectUpdate("%s loans do not require a property appraisal", state.product())
+	}
+	if state.Appraisal != nil {
+		return rejectUpdate("appraisal has already been completed")
+	}
+	if signal.PropertyValue <= 0 {
+		return rejectUpdate("property value must be positive")
+	}
+	return nil
+}
+
+func validateUnderwritingDecision(state *LoanOriginationState, signal UnderwritingDecisionSignal) error {
+	if !state.awaitingUnderwriting() {
+		return rejectUpdate("loan is not waiting for an underwriting decision (next step: %s)", state.NextStep)
+	}
+	if err := validateConditionRequests(signal); err != nil {
+		return err
+	}
+	switch signal.Decision {
+	case "approved", "rejected":
+		return nil
+	case "counter_offer":
+		return validateCounterOfferTerms(signal.CounterOffer)
+	case "needs_more_info":
+		if len(signal.RequestedDocuments) == 0 {
+			return rejectUpdate("needs_more_info must request at least one document")
+		}
+		for _, request := range signal.RequestedDocuments {
+			if !request.DocumentType.Requirable() {
+				return rejectUpdate("cannot request document type %q", request.DocumentType)
+			}
+			if request.Reason == "" {
+				return rejectUpdate("a reason is required for the requested %s", request.DocumentType)
+			}
+		}
+		return nil
+	default:
+		return rejectUpdate("unknown underwriting decision %q", signal.Decision)
+	}
+}
+
+func validateFunding(state *LoanOriginationState, signal FundingCompletedSignal) error {
+	if state.Status != "approved" {
+		return rejectUpdate("loan is %s and not waiting for funding", state.Status)
+	}
+	if !state.signed() {
+		return rejectUpdate("loan agreement has not been signed")
+	}
+	// Compared in cents, the unit the ledger posts
+	if math.Round(signal.FundingAmount*100) != math.Round(state.Terms.LoanAmount*100) {
+		return rejectUpdate("funding amount %.2f does not match the approved amount %.2f", signal.FundingAmount, state.Terms.LoanAmount)
+	}
+	return nil
+}
+
+func (s *LoanOriginationState) setStatus(status string) {
+	s.LoanApplication.Status = status
+	s.Status = status
+}
+
+// documentCounts returns the number of verified and rejected documents.
+func (s *LoanOriginationState) documentCounts() (verified, rejected int) {
+	for _, doc := range s.Documents {
+		switch doc.VerificationStatus {
+		case "verified":
+			verified++
+		case "rejected":
+			rejected++
+		}
+	}
+	return verified, rejected
+}
+
+// outstanding reports whether the item still needs a document uploaded.
+func (i ChecklistItem) outstanding() bool {
+	return i.Status == "missing" || i.Status == "rejected"
+}
+
+// missingDocuments returns the document types of the outstanding checklist
+// items.
+func (s *LoanOriginationState) missingDocuments() []string {
+	var missing []string
+	for _, item := range s.DocumentChecklist {
+		if item.outstanding() {
+			missing = append(missing, string(item.DocumentType))
+		}
+	}
+	return missing
+}
+
+func (s *LoanOriginationState) moreDocumentsRequired() bool {
+	return len(s.missingDocuments()) > 0
+}
+
+func (s *LoanOriginationState) pendingVerification() bool {
+	verified, rejected := s.documentCounts()
+	return verified+rejected < len(s.Documents)
+}
+
+// product is the loan product whose stages the workflow runs.
+func (s *LoanOriginationState) product() policy.LoanProduct {
+	return s.Policy.Product.Name
+}
+
+// appraisalPending reports whether the product requires a property
+// appraisal that has not been completed yet.
+func (s *LoanOriginationState) appraisalPending() bool {
+	return s.product().Collateral() == policy.CollateralProperty &&
+		s.Policy.Product.RequireAppraisal &&
+		s.Appraisal == nil
+}
+
+// vehicleValuationPending reports whether the vehicle securing the loan has
+// yet to be valued.
+func (s *LoanOriginationState) vehicleValuationPending() bool {
+	return s.product().Collateral() == policy.CollateralVehicle &&
+		s.Policy.Product.RequireAppraisal &&
+		s.VehicleValuation == nil
+}
+
+// lienCheckPending reports whether existing liens have yet to be checked.
+func (s *LoanOriginationState) lienCheckPending() bool {
+	return s.product().RequiresLienCheck() && s.LienCheck == nil
+}
+
+func (s *LoanOriginationState) creditScoreCompleted() bool {
+	return s.CreditScore != nil && s.CreditScore.Status == "completed"
+}
+
+// creditCheckConcluded reports whether the credit check has completed or
+// failed for good, after which underwriting can go ahead.
+func (s *LoanOriginationState) creditCheckConcluded() bool {
+	return s.creditScoreCompleted() || (s.CreditScore != nil && s.CreditScore.Status == "failed")
+}
+
+func (s *LoanOriginationState) awaitingUnderwriting() bool {
+	return s.Status == "processing" &&
+		!s.underwritingCompleted() &&
+		!s.moreDocumentsRequired() &&
+		!s.pendingVerification() &&
+		!s.appraisalPending() &&
+		!s.vehicleValuationPending() &&
+		!s.lienCheckPending() &&
+		s.creditCheckConcluded() &&
+		s.Recommendation != nil
+}
+
+func (s *LoanOriginationState) underwritingCompleted() bool {
+	return s.UnderwritingDecision != nil && s.UnderwritingDecision.Decision != "needs_more_info"
+}
+
+// refreshNextStep derives NextStep from the documents, appraisal, credit
+// score and recommendation collected so far while the application is
+// processing. A closed application keeps the NextStep it was closed with.
+func (s *LoanOriginationState) refreshNextStep() {
+	if s.closed() {
+		return
+	}
+	switch {
+	case s.moreDocumentsRequired():
+		s.NextStep = "Waiting for customer documents: " + strings.Join(s.missingDocuments(), ", ")
+	case s.pendingVerification():
+		s.NextStep = "Waiting for document verification"
+	case s.appraisalPending():
+		s.NextStep = "Waiting for appraisal"
+	case s.vehicleValuationPending():
+		s.NextStep = "Valuing vehicle"
+	case s.lienCheckPending():
+		s.NextStep = "Checking existing liens"
+	case !s.creditCheckConcluded():
+		s.NextStep = "Performing credit score check"
+	case s.Recommendation == nil:
+		s.NextStep = "Running automated decisioning"
+	case !s.creditScoreCompleted():
+		s.NextStep = "Waiting for underwriting decision: credit check failed"
+	default:
+		s.NextStep = "Waiting for underwriting decision"
+	}
+}
@@ -0,0 +1,1543 @@
+package workflows
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"loan-origination-system/internal/activities"
+	"loan-origination-system/internal/creditbureau"
+	"loan-origination-system/internal/decisioning"
+	"loan-origination-system/internal/notify"
+	"loan-origination-system/internal/policy"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/testsuite"
+)
+
+type LoanOriginationWorkflowTestSuite struct {
+	suite.Suite
+	testsuite.WorkflowTestSuite
+
+	env *testsuite.TestWorkflowEnvironment
+	// creditErr, when set, fails every credit score check
+	creditErr error
+	// notices records the adverse action notices generated, and noticeErr,
+	// when set, fails them
+	notices   []activities.GenerateAdverseActionNoticeInput
+	noticeErr error
+	// agreements records the loan agreements generated
+	agreements []activities.GenerateLoanAgreementInput
+	// disbursements records the disbursements posted, and disbursementErrs
+	// fail them in turn
+	disbursements    []activities.ProcessFundingInput
+	disbursementErrs []error
+	// notifications records the messages sent to the borrower, and emailErr,
+	// when set, fails every email
+	notifications []activities.SendNotificationInput
+	emailErr      error
+	// projections records every state written to the read model
+	projections []LoanOriginationState
+}
+
+func TestLoanOriginationWorkflowTestSuite(t *testing.T) {
+	suite.Run(t, new(LoanOriginationWorkflowTestSuite))
+}
+
+func (s *LoanOriginationWorkflowTestSuite) SetupTest() {
+	s.env = s.NewTestWorkflowEnvironment()
+	s.creditErr = nil
+	s.notices = nil
+	s.noticeErr = nil
+	s.agreements = nil
+	s.disbursements = nil
+	s.disbursementErrs = nil
+	s.notifications = nil
+	s.emailErr = nil
+	s.projections = nil
+	s.env.RegisterActivity(&activities.AgreementActivities{})
+	s.env.RegisterActivity(&activities.FundingActivities{})
+	s.env.RegisterActivity(&activities.CreditActivities{})
+	s.env.RegisterActivity(activities.EvaluateLoan)
+	s.env.RegisterActivity(activities.ValueVehicle)
+	s.env.RegisterActivity(activities.CheckLiens)
+	s.env.RegisterActivity(activities.VoidLoanAgreement)
+	s.env.RegisterActivity(activities.ReleaseRateLock)
+	s.env.RegisterActivity(activities.SendFundingFailureNotification)
+	s.env.RegisterActivity(&activities.NoticeActivities{})
+	s.env.RegisterActivity(&activities.NotificationActivities{})
+	s.env.RegisterActivityWithOptions(func(ctx context.Context, state LoanOriginationState) error {
+		return nil
+	}, activity.RegisterOptions{Name: ProjectLoanStateActivity})
+
+	var agreements *activities.AgreementActivities
+	s.env.OnActivity(agreements.GenerateLoanAgreement, mock.Anything, mock.Anything).Return(
+		func(ctx context.Context, input activities.GenerateLoanAgreementInput) (*activities.GenerateLoanAgreementResult, error) {
+			s.agreements = append(s.agreements, input)
+			base := fmt.Sprintf("loans/%s/agreements/v%d", input.LoanApplicationID, input.Version)
+			return &activities.GenerateLoanAgreementResult{
+				HTML: activities.StoredFile{FilePath: base + ".html", ContentType: "text/html; charset=utf-8", Size: 4096, SHA256: "agreement-html-sha"},
+				PDF:  activities.StoredFile{FilePath: base + ".pdf", ContentType: "application/pdf", Size: 8192, SHA256: "agreement-pdf-sha"},
+			}, nil
+		})
+	var funding *activities.FundingActivities
+	s.env.OnActivity(funding.ProcessFunding, mock.Anything, mock.Anything).Return(
+		func(ctx context.Context, input activities.ProcessFundingInput) (*activities.ProcessFundingResult, error) {
+			s.disbursements = append(s.disbursements, input)
+			if len(s.disbursementErrs) > 0 {
+				err := s.disbursementErrs[0]
+				s.disbursementErrs = s.disbursementErrs[1:]
+				return nil, err
+			}
+			entryID := fmt.Sprintf("disbursement-%s-%d", input.LoanApplicationID, input.Attempt)
+			return &activities.ProcessFundingResult{LedgerEntryID: entryID, PostedAt: input.FundedAt}, nil
+		})
+	s.env.OnActivity(funding.ReverseFunding, mock.Anything, mock.Anything).Return(
+		func(ctx context.Context, input activities.ReverseFundingInput) (*activities.ReverseFundingResult, error) {
+			entryID := fmt.Sprintf("reversal-disbursement-%s-%d", input.LoanApplicationID, input.Attempt)
+			return &activities.ReverseFundingResult{LedgerEntryID: entryID}, nil
+		})
+	s.env.OnActivity(activities.SendFundingFailureNotification, mock.Anything, mock.Anything).Return(nil)
+	s.env.OnActivity(activities.ReleaseRateLock, mock.Anything, mock.Anything).Return(nil)
+	var credit *activities.CreditActivities
+	s.env.OnActivity(credit.CreditScoreCheck, mock.Anything, mock.Anything).Return(
+		func(ctx context.Context, input activities.CreditScoreCheckInput) (*activities.CreditScoreCheckResult, error) {
+			if s.creditErr != nil {
+				return nil, s.creditErr
+			}
+			return &activities.CreditScoreCheckResult{
+				CreditScore: 720,
+				Status:      "completed",
+				Report: creditbureau.Report{
+					Bureau:     "Test Bureau",
+					Reference:  "report-" + input.LoanApplicationID,
+					PullType:   input.PullType,
+					Score:      720,
+					ScoreModel: "FICO 8",
+					Tradelines: []creditbureau.Tradeline{{Creditor: "Test Bank", AccountType: "credit_card", Status: "open", MonthlyPayment: 150}},
+				},
+			}, nil
+		})
+	var notices *activities.NoticeActivities
+	s.env.OnActivity(notices.GenerateAdverseActionNotice, mock.Anything, mock.Anything).Return(
+		func(ctx context.Context, input activities.GenerateAdverseActionNoticeInput) (*activities.GenerateAdverseActionNoticeResult, error) {
+			if s.noticeErr != nil {
+				return nil, s.noticeErr
+			}
+			s.notices = append(s.notices, input)
+			base := "loans/" + input.LoanApplicationID + "/documents/" + input.NoticeID
+			return &activities.GenerateAdverseActionNoticeResult{
+				DocumentID: input.NoticeID,
+				HTML:       activities.StoredFile{FilePath: base + ".html", ContentType: "text/html; charset=utf-8", Size: 2048, SHA256: "notice-html-sha"},
+				PDF:        activities.StoredFile{FilePath: base + ".pdf", ContentType: "application/pdf", Size: 4096, SHA256: "notice-pdf-sha"},
+			}, nil
+		})
+	var notifications *activities.NotificationActivities
+	s.env.OnActivity(notifications.SendNotification, mock.Anything, mock.Anything).Return(
+		func(ctx context.Context, input activities.SendNotificationInput) (*activities.SendNotificationResult, error) {
+			if input.Channel == notify.ChannelEmail && s.emailErr != nil {
+				return nil, s.emailErr
+			}
+			s.notifications = append(s.notifications, input)
+			return &activities.SendNotificationResult{
+				Subject:   "subject: " + string(input.Event),
+				MessageID: fmt.Sprintf("%s-%d", input.Channel, len(s.notifications)),
+			}, nil
+		})
+	s.env.OnActivity(ProjectLoanStateActivity, mock.Anything, mock.Anything).Return(
+		func(ctx context.Context, state LoanOriginationState) error {
+			s.projections = append(s.projections, state)
+			return nil
+		})
+}
+
+// notifiedEvents lists the events the borrower was emailed about, in order.
+func (s *LoanOriginationWorkflowTestSuite) notifiedEvents() []notify.Event {
+	var events []notify.Event
+	for _, notification := range s.notifications {
+		if notification.Channel == notify.ChannelEmail {
+			events = append(events, notification.Event)
+		}
+	}
+	return events
+}
+
+func testLoanApplication() LoanApplication {
+	return LoanApplication{
+		ID:                "loan-1",
+		BorrowerName:      "Jane Doe",
+		BorrowerEmail:     "jane@example.com",
+		BorrowerEmailHash: "email-hash",
+		BorrowerPhone:     "555-0100",
+		LoanAmount:        250000,
+		LoanPurpose:       "home purchase",
+		Status:            "pending",
+		CreatedBy:         "loan-officer",
+		WorkflowID:        "loan-origination-loan-1",
+	}
+}
+
+// signalAt delivers a signal to the workflow after the given delay.
+func (s *LoanOriginationWorkflowTestSuite) signalAt(delay time.Duration, name string, arg interface{}) {
+	s.env.RegisterDelayedCallback(func() {
+		s.env.SignalWorkflow(name, arg)
+	}, delay)
+}
+
+// queryAt captures the workflow state after the given delay.
+func (s *LoanOriginationWorkflowTestSuite) queryAt(delay time.Duration, state *LoanOriginationState) {
+	s.env.RegisterDelayedCallback(func() {
+		s.Require().NoError(s.queryState(state))
+	}, delay)
+}
+
+func (s *LoanOriginationWorkflowTestSuite) queryState(state *LoanOriginationState) error {
+	value, err := s.env.QueryWorkflow("getLoanApplication")
+	if err != nil {
+		return err
+	}
+	return value.Get(state)
+}
+
+func (s *LoanOriginationWorkflowTestSuite) uploadAt(delay time.Duration, documentID string, documentType policy.DocumentType) {
+	s.signalAt(delay, "document-uploaded", DocumentUploadedSignal{
+		DocumentID:   documentID,
+		DocumentType: documentType,
+	})
+}
+
+func (s *LoanOriginationWorkflowTestSuite) verifyAt(delay time.Duration, documentID, status string) {
+	s.signalAt(delay, "document-verified", DocumentVerificationSignal{
+		DocumentID:          documentID,
+		VerificationStatus:  status,
+		VerificationDetails: map[string]interface{}{"verified_by": "loan-processor"},
+	})
+}
+
+func (s *LoanOriginationWorkflowTestSuite) appraiseAt(delay time.Duration) {
+	s.signalAt(delay, "appraisal-completed", AppraisalCompletedSignal{
+		PropertyValue:  300000,
+		AppraisalNotes: "good condition",
+		AppraiserID:    "appraiser-001",
+	})
+}
+
+func (s *LoanOriginationWorkflowTestSuite) decideAt(delay time.Duration, decision string) {
+	s.signalAt(delay, "underwriting-decision", UnderwritingDecisionSignal{
+		Decision:      decision,
+		Comments:      "decision: " + decision,
+		UnderwriterID: "underwriter-001",
+	})
+}
+
+// requestDocumentsAt asks the borrower for more documents with a
+// needs_more_info decision.
+func (s *LoanOriginationWorkflowTestSuite) requestDocumentsAt(delay time.Duration, documentTypes ...policy.DocumentType) {
+	var requests []DocumentRequest
+	for _, documentType := range documentTypes {
+		requests = append(requests, DocumentRequest{DocumentType: documentType, Reason: "please provide " + string(documentType)})
+	}
+	s.signalAt(delay, "underwriting-decision", UnderwritingDecisionSignal{
+		Decision:           "needs_more_info",
+		UnderwriterID:      "underwriter-001",
+		RequestedDocuments: requests,
+	})
+}
+
+func (s *LoanOriginationWorkflowTestSuite) fundAt(delay time.Duration) {
+	s.fundAmountAt(delay, 250000)
+}
+
+func (s *LoanOriginationWorkflowTestSuite) fundAmountAt(delay time.Duration, amount float64) {
+	s.signalAt(delay, "funding-completed", FundingCompletedSignal{
+		FundManagerID: "fund-manager-001",
+		FundingAmount: amount,
+	})
+}
+
+// signAt signs the loan agreement as the borrower after the given delay.
+func (s *LoanOriginationWorkflowTestSuite) signAt(delay time.Duration) {
+	s.signalAt(delay, "agreement-signature", testSignature())
+}
+
+func testSignature() AgreementSignatureSignal {
+	return AgreementSignatureSignal{
+		Signed:         true,
+		SignerName:     "Jane Doe",
+		DocumentSHA256: "agreement-pdf-sha",
+		SignedBy:       "customer",
+		IPAddress:      "203.0.113.7",
+		UserAgent:      "test-browser",
+	}
+}
+
+func testPolicy() *policy.Snapshot {
+	return productPolicy(policy.ProductMortgage)
+}
+
+func productPolicy(product policy.LoanProduct) *policy.Snapshot {
+	snapshot, err := policy.Default().Snapshot(product)
+	if err != nil {
+		panic(err)
+	}
+	return &snapshot
+}
+
+func (s *LoanOriginationWorkflowTestSuite) executeWorkflow() LoanOriginationState {
+	return s.executeWorkflowWith(LoanOriginationWorkflowInput{
+		LoanApplication: testLoanApplication(),
+		Policy:          testPolicy(),
+	})
+}
+
+func (s *LoanOriginationWorkflowTestSuite) executeWorkflowWith(input LoanOriginationWorkflowInput) LoanOriginationState {
+	s.env.ExecuteWorkflow(LoanOriginationWorkflow, input)
+
+	s.Require().True(s.env.IsWorkflowCompleted())
+	s.Require().NoError(s.env.GetWorkflowError())
+
+	var state LoanOriginationState
+	s.Require().NoError(s.queryState(&state))
+	return state
+}
+
+func (s *LoanOriginationWorkflowTestSuite) Test_HappyPath_Funded() {
+	var awaitingDocs, awaitingVerification, awaitingAppraisal, awaitingDecision, awaitingSignature, awaitingFunding LoanOriginationState
+
+	s.queryAt(time.Minute, &awaitingDocs)
+	s.uploadAt(2*time.Minute, "doc-1", "income_statement")
+	s.uploadAt(3*time.Minute, "doc-2", "bank_statement")
+	s.queryAt(4*time.Minute, &awaitingVerification)
+	s.verifyAt(5*time.Minute, "doc-1", "verified")
+	s.verifyAt(6*time.Minute, "doc-2", "verified")
+	s.queryAt(7*time.Minute, &awaitingAppraisal)
+	s.appraiseAt(8 * time.Minute)
+	s.queryAt(9*time.Minute, &awaitingDecision)
+	s.decideAt(10*time.Minute, "approved")
+	s.queryAt(11*time.Minute, &awaitingSignature)
+	s.signAt(12 * time.Minute)
+	s.queryAt(13*time.Minute, &awaitingFunding)
+	s.fundAt(14 * time.Minute)
+
+	state := s.executeWorkflow()
+
+	s.Equal("Waiting for customer documents: income_statement, bank_statement", awaitingDocs.NextStep)
+	s.Equal("processing", awaitingDocs.Status)
+	s.Require().Len(awaitingDocs.DocumentChecklist, 2)
+	s.Equal("missing", awaitingDocs.DocumentChecklist[0].Status)
+	s.Equal("Waiting for document verification", awaitingVerification.NextStep)
+	s.Len(awaitingVerification.Documents, 2)
+	s.Equal("uploaded", awaitingVerification.DocumentChecklist[1].Status)
+	s.Equal("doc-2", awaitingVerification.DocumentChecklist[1].DocumentID)
+	s.Equal("Waiting for appraisal", awaitingAppraisal.NextStep)
+	s.Equal("Waiting for underwriting decision", awaitingDecision.NextStep)
+	s.Equal("awaiting_signature", awaitingSignature.Status)
+	s.Equal("Waiting for borrower to sign the loan agreement", awaitingSignature.NextStep)
+	s.Require().NotNil(awaitingSignature.Signature)
+	s.Equal("pending", awaitingSignature.Signature.Status)
+	s.Equal(1, awaitingSignature.Signature.AgreementVersion)
+	s.Equal("agreement-pdf-sha", awaitingSignature.Signature.DocumentSHA256)
+	s.Equal("approved", awaitingFunding.Status)
+	s.Equal("Waiting for funding", awaitingFunding.NextStep)
+
+	s.Equal("funded", state.Status)
+	s.Equal("funded", state.LoanApplication.Status)
+	s.Equal("n/a", state.NextStep)
+	s.Len(state.Documents, 2)
+	for _, doc := range state.Documents {
+		s.Equal("verified", doc.VerificationStatus)
+		s.NotNil(doc.VerifiedAt)
+	}
+	s.Require().NotNil(state.Appraisal)
+	s.Equal(300000.0, state.Appraisal.PropertyValue)
+	s.Equal("completed", state.Appraisal.Status)
+	s.Require().NotNil(state.CreditScore)
+	s.Equal(720, state.CreditScore.Score)
+	s.Equal("completed", state.CreditScore.Status)
+	s.Equal("Test Bureau", state.CreditScore.Bureau)
+	s.Equal(creditbureau.PullHard, state.CreditScore.PullType)
+	s.Equal("report-loan-1", state.CreditScore.ReportReference)
+	s.Len(state.CreditScore.Tradelines, 1)
+	s.Require().NotNil(awaitingDecision.Recommendation)
+	s.Equal(decisioning.Refer, awaitingDecision.Recommendation.Recommendation)
+	s.Equal(decisioning.TierGood, awaitingDecision.Recommendation.CreditTier)
+	s.InDelta(0.8333, awaitingDecision.Recommendation.LTV, 0.0001)
+	s.Nil(awaitingDecision.Recommendation.DTI)
+	s.Require().Len(awaitingDecision.Recommendation.Reasons, 1)
+	s.Equal(decisioning.ReasonLTVAboveThreshold, awaitingDecision.Recommendation.Reasons[0].Code)
+	s.Require().NotNil(state.UnderwritingDecision)
+	s.Equal("approved", state.UnderwritingDecision.Decision)
+	s.Equal(decisioning.Refer, state.UnderwritingDecision.Recommendation)
+	s.Require().NotNil(state.LoanAgreement)
+	s.Equal(1, state.LoanAgreement.Version)
+	s.Equal("loans/loan-1/agreements/v1.pdf", state.LoanAgreement.PDF.FilePath)
+	s.Equal("agreement-pdf-sha", state.LoanAgreement.PDF.SHA256)
+	s.Require().NotNil(state.Signature)
+	s.Equal("signed", state.Signature.Status)
+	s.Equal("Jane Doe", state.Signature.SignerName)
+	s.Equal("203.0.113.7", state.Signature.IPAddress)
+	s.Require().NotNil(state.Signature.SignedAt)
+	s.Require().NotNil(state.Funding)
+	s.Equal(250000.0, state.Funding.Amount)
+	s.Equal("fund-manager-001", state.Funding.FundManagerID)
+	s.Equal("disbursement-loan-1-1", state.Funding.LedgerEntryID)
+	s.Equal(1, state.Funding.Attempt)
+	s.Equal("loans/loan-1/agreements/v1.html", state.LoanAgreement.HTML.FilePath)
+	s.Require().Len(s.agreements, 1)
+	s.Equal("Jane Doe", s.agreements[0].BorrowerName)
+	s.Equal("home purchase", s.agreements[0].LoanPurpose)
+	s.Equal(250000.0, s.agreements[0].LoanAmount)
+	s.env.AssertCalled(s.T(), "ProcessFunding", mock.Anything, mock.Anything)
+}
+
+func (s *LoanOriginationWorkflowTestSuite) Test_CreditCheckFailure_LeftToUnderwriter() {
+	s.creditErr = temporal.NewNonRetryableApplicationError("borrower not found at credit bureau", activities.CreditBureauRejectedErrorType, nil)
+
+	var awaitingDecision LoanOriginationState
+	s.uploadAt(time.Minute, "doc-1", "income_statement")
+	s.uploadAt(2*time.Minute, "doc-2", "bank_statement")
+	s.verifyAt(3*time.Minute, "doc-1", "verified")
+	s.verifyAt(4*time.Minute, "doc-2", "verified")
+	s.appraiseAt(5 * time.Minute)
+	s.queryAt(6*time.Minute, &awaitingDecision)
+	s.decideAt(7*time.Minute, "rejected")
+
+	state := s.executeWorkflow()
+
+	s.Equal("Waiting for underwriting decision: credit check failed", awaitingDecision.NextStep)
+	s.Require().NotNil(state.CreditScore)
+	s.Equal("failed", state.CreditScore.Status)
+	s.Contains(state.CreditScore.Error, "borrower not found")
+	s.Require().NotNil(state.Recommendation)
+	s.Equal(decisioning.Refer, state.Recommendation.Recommendation)
+	s.Equal(decisioning.TierUnavailable, state.Recommendation.CreditTier)
+	s.Equal(decisioning.ReasonCreditReportUnavailable, state.Recommendation.Reasons[len(state.Recommendation.Reasons)-1].Code)
+	s.Equal("rejected", state.Status)
+}
+
+func (s *LoanOriginationWorkflowTestSuite) Test_Decisioning_UsesIncomeAndCreditDebts() {
+	application := testLoanApplication()
+	application.LoanAmount = 200000
+	application.MonthlyIncome = 10000
+
+	s.uploadAt(time.Minute, "doc-1", "income_statement")
+	s.uploadAt(2*time.Minute, "doc-2", "bank_statement")
+	s.verifyAt(3*time.Minute, "doc-1", "verified")
+	s.verifyAt(4*time.Minute, "doc-2", "verified")
+	s.appraiseAt(5 * time.Minute)
+	s.decideAt(6*time.Minute, "approved")
+	s.signAt(6*time.Minute + 30*time.Second)
+	s.fundAt(7 * time.Minute)
+
+	state := s.executeWorkflowWith(LoanOriginationWorkflowInput{LoanApplication: application, Policy: testPolicy()})
+
+	s.Require().NotNil(state.Recommendation)
+	s.Equal(decisioning.Approve, state.Recommendation.Recommendation)
+	s.Empty(state.Recommendation.Reasons)
+	s.Require().NotNil(state.Recommendation.DTI)
+	// The 150 a month tradeline plus the estimated payment on 200,000
+	payment := decisioning.DefaultPolicy().MonthlyPayment(200000)
+	s.InDelta((150+payment)/10000, *state.Recommendation.DTI, 0.0001)
+	s.Equal(decisioning.Approve, state.UnderwritingDecision.Recommendation)
+}
+
+func (s *LoanOriginationWorkflowTestSuite) Test_Rejected() {
+	s.uploadAt(time.Minute, "doc-1", "income_statement")
+	s.uploadAt(2*time.Minute, "doc-2", "bank_statement")
+	s.verifyAt(3*time.Minute, "doc-1", "verified")
+	s.verifyAt(4*time.Minute, "doc-2", "verified")
+	s.appraiseAt(5 * time.Minute)
+	s.decideAt(6*time.Minute, "rejected")
+
+	state := s.executeWorkflow()
+
+	s.Equal("rejected", state.Status)
+	s.Equal("rejected", state.LoanApplication.Status)
+	s.Equal("n/a", state.NextStep)
+	s.Require().NotNil(state.UnderwritingDecision)
+	s.Equal("rejected", state.UnderwritingDecision.Decision)
+	s.env.AssertNotCalled(s.T(), "ProcessFunding", mock.Anything, mock.Anything)
+
+	// The notice lists the policy findings and discloses the score used
+	s.Require().Len(s.notices, 1)
+	notice := s.notices[0]
+	s.Equal([]activities.NoticeReason{{Code: "LTV_ABOVE_THRESHOLD", Description: "LTV 83.3% exceeds 80.0%"}}, notice.Reasons)
+	s.Require().NotNil(notice.CreditScore)
+	s.Equal(720, notice.CreditScore.Score)
+	s.Equal("Test Bureau", notice.CreditScore.Bureau)
+	s.Equal(state.UnderwritingDecision.DecisionDate, notice.DecisionDate)
+
+	s.Require().NotNil(state.AdverseActionNotice)
+	s.Equal("adverse-action-notice-decision-loan-1", state.AdverseActionNotice.DocumentID)
+	s.True(state.AdverseActionNotice.CreditScore)
+	s.Require().NotNil(state.AdverseActionNotice.PDF)
+	s.Equal("loans/loan-1/documents/adverse-action-notice-decision-loan-1.pdf", state.AdverseActionNotice.PDF.FilePath)
+	s.Equal([]notify.Event{notify.EventApplicationReceived, notify.EventRejected}, s.notifiedEvents())
+	s.NotNil(state.AdverseActionNotice.GeneratedAt)
+	s.Require().Len(state.Documents, 3)
+	s.Equal(policy.DocumentAdverseActionNotice, state.Documents[2].DocumentType)
+	s.Equal(state.AdverseActionNotice.DocumentID, state.Documents[2].ID)
+	s.Equal("application/pdf", state.Documents[2].ContentType)
+	s.Equal("generated", state.Documents[2].VerificationStatus)
+}
+
+func (s *LoanOriginationWorkflowTestSuite) Test_Rejected_NoticeFailureDoesNotFailWorkflow() {
+	s.creditErr = temporal.NewNonRetryableApplicationError("borrower not found at credit bureau", activities.CreditBureauRejectedErrorType, nil)
+	s.noticeErr = temporal.NewNonRetryableApplicationError("storage unavailable", "StorageError", nil)
+
+	decide := s.approveDocumentsAndAppraisal()
+	s.signalAt(decide, "underwriting-decision", UnderwritingDecisionSignal{
+		Decision:      "rejected",
+		Comments:      "Unable to verify credit history",
+		UnderwriterID: "underwriter-001",
+	})
+
+	state := s.executeWorkflow()
+
+	s.Equal("rejected", state.Status)
+	s.Require().NotNil(state.AdverseActionNotice)
+	s.Contains(state.AdverseActionNotice.Error, "storage unavailable")
+	s.Empty(state.AdverseActionNotice.DocumentID)
+	s.False(state.AdverseActionNotice.CreditScore)
+	s.Len(state.Documents, 2)
+}
+
+func (s *LoanOriginationWorkflowTestSuite) Test_InvalidDecisionSignalsDropped() {
+	var afterInvalid LoanOriginationState
+
+	decide := s.approveDocumentsAndAppraisal()
+	s.decideAt(decide, "approve")
+	s.requestDocumentsAt(decide)
+	s.signalAt(decide, "underwriting-decision", UnderwritingDecisionSignal{
+		Decision:      "approved",
+		UnderwriterID: "underwriter-001",
+		Conditions:    []ConditionRequest{{Description: ""}},
+	})
+	s.queryAt(decide+time.Minute, &afterInvalid)
+	s.decideAt(decide+2*time.Minute, "rejected")
+
+	state := s.executeWorkflow()
+
+	s.Equal("processing", afterInvalid.Status)
+	s.Equal("Waiting for underwriting decision", afterInvalid.NextStep)
+	s.Nil(afterInvalid.UnderwritingDecision)
+	s.Empty(afterInvalid.Conditions)
+
+	s.Equal("rejected", state.Status)
+	s.Equal("rejected", state.UnderwritingDecision.Decision)
+	s.Len(s.notices, 1)
+}
+
+func (s *LoanOriginationWorkflowTestSuite) Test_NeedsMoreInfo_RequestsAnotherDocument() {
+	var afterFirstRequest, afterSecondRequest LoanOriginationState
+
+	s.uploadAt(time.Minute, "doc-1", "income_statement")
+	s.uploadAt(2*time.Minute, "doc-2", "bank_statement")
+	s.verifyAt(3*time.Minute, "doc-1", "verified")
+	s.verifyAt(4*time.Minute, "doc-2", "verified")
+	s.appraiseAt(5 * time.Minute)
+	s.requestDocumentsAt(6*time.Minute, policy.DocumentTaxReturns)
+	s.queryAt(7*time.Minute, &afterFirstRequest)
+	s.uploadAt(8*time.Minute, "doc-3", "tax_returns")
+	s.verifyAt(9*time.Minute, "doc-3", "verified")
+	s.requestDocumentsAt(10*time.Minute, policy.DocumentEmploymentVerification)
+	s.queryAt(11*time.Minute, &afterSecondRequest)
+	s.uploadAt(12*time.Minute, "doc-4", "employment_verification")
+	s.verifyAt(13*time.Minute, "doc-4", "verified")
+	s.decideAt(14*time.Minute, "approved")
+	s.signAt(14*time.Minute + 30*time.Second)
+	s.fundAt(15 * time.Minute)
+
+	state := s.executeWorkflow()
+
+	s.Equal("processing", afterFirstRequest.Status)
+	s.Equal("Waiting for customer documents: tax_returns", afterFirstRequest.NextStep)
+	s.Equal("needs_more_info", afterFirstRequest.UnderwritingDecision.Decision)
+	s.Require().Len(afterFirstRequest.DocumentChecklist, 3)
+	s.Equal("please provide tax_returns", afterFirstRequest.DocumentChecklist[2].Reason)
+	s.Equal("underwriter-001", afterFirstRequest.DocumentChecklist[2].RequestedBy)
+	s.Equal("Waiting for customer documents: employment_verification", afterSecondRequest.NextStep)
+
+	s.Equal("funded", state.Status)
+	s.Len(state.Documents, 4)
+	s.Equal("approved", state.UnderwritingDecision.Decision)
+	s.env.AssertCalled(s.T(), "ProcessFunding", mock.Anything, mock.Anything)
+}
+
+func (s *LoanOriginationWorkflowTestSuite) Test_RejectedDocument_RequiresReplacement() {
+	var afterRejection LoanOriginationState
+
+	s.uploadAt(time.Minute, "doc-1", "income_statement")
+	s.uploadAt(2*time.Minute, "doc-2", "bank_statement")
+	s.verifyAt(3*time.Minute, "doc-1", "verified")
+	s.verifyAt(4*time.Minute, "doc-2", "rejected")
+	s.queryAt(5*time.Minute, &afterRejection)
+	s.uploadAt(6*time.Minute, "doc-3", "bank_statement")
+	s.verifyAt(7*time.Minute, "doc-3", "verified")
+	s.appraiseAt(8 * time.Minute)
+	s.decideAt(9*time.Minute, "approved")
+	s.signAt(9*time.Minute + 30*time.Second)
+	s.fundAt(10 * time.Minute)
+
+	state := s.executeWorkflow()
+
+	s.Equal("Waiting for customer documents: bank_statement", afterRejection.NextStep)
+	s.Equal("rejected", afterRejection.Documents[1].VerificationStatus)
+	s.Equal("rejected", afterRejection.DocumentChecklist[1].Status)
+
+	s.Equal("funded", state.Status)
+	s.Len(state.Documents, 3)
+	s.Equal("rejected", state.Documents[1].VerificationStatus)
+	s.Equal("verified", state.Documents[2].VerificationStatus)
+	s.Equal("verified", state.DocumentChecklist[1].Status)
+	s.Equal("doc-3", state.DocumentChecklist[1].DocumentID)
+}
+
+func (s *LoanOriginationWorkflowTestSuite) Test_Checklist_OnlyMatchingTypesSatisfyItems() {
+	var afterOtherUploads LoanOriginationState
+
+	s.uploadAt(time.Minute, "doc-1", "other")
+	s.uploadAt(2*time.Minute, "doc-2", "other")
+	s.verifyAt(3*time.Minute, "doc-1", "verified")
+	s.verifyAt(4*time.Minute, "doc-2", "verified")
+	unknown := s.updateAt(5*time.Minute, "uploadDocument", DocumentUploadedSignal{DocumentID: "doc-3", DocumentType: "selfie"})
+	s.queryAt(6*time.Minute, &afterOtherUploads)
+	s.uploadAt(7*time.Minute, "doc-4", "bank_statement")
+	s.uploadAt(8*time.Minute, "doc-5", "income_statement")
+	s.verifyAt(9*time.Minute, "doc-4", "verified")
+	s.verifyAt(10*time.Minute, "doc-5", "verified")
+	s.appraiseAt(11 * time.Minute)
+	noDocuments := s.updateAt(12*time.Minute, "makeUnderwritingDecision", UnderwritingDecisionSignal{Decision: "needs_more_info"})
+	noReason := s.updateAt(12*time.Minute, "makeUnderwritingDecision", UnderwritingDecisionSignal{
+		Decision:           "needs_more_info",
+		RequestedDocuments: []DocumentRequest{{DocumentType: policy.DocumentPayStub}},
+	})
+	s.decideAt(13*time.Minute, "rejected")
+
+	state := s.executeWorkflow()
+
+	s.Error(unknown.rejected)
+	s.Error(noDocuments.rejected)
+	s.Error(noReason.rejected)
+	s.Equal("Waiting for customer documents: income_statement, bank_statement", afterOtherUploads.NextStep)
+	s.Equal("doc-5", state.DocumentChecklist[0].DocumentID)
+	s.Equal("doc-4", state.DocumentChecklist[1].DocumentID)
+	s.Len(state.DocumentChecklist, 2)
+	s.Equal("rejected", state.Status)
+}
+
+func (s *LoanOriginationWorkflowTestSuite) Test_Timeout_WaitingForSteps() {
+	s.uploadAt(time.Hour, "doc-1", "income_statement")
+
+	state := s.executeWorkflow()
+
+	s.Equal("incomplete", state.Status)
+	s.Equal("incomplete", state.LoanApplication.Status)
+	s.Equal("n/a", state.NextStep)
+	s.Len(state.Documents, 1)
+	s.Nil(state.UnderwritingDecision)
+	s.env.AssertNotCalled(s.T(), "CreditScoreCheck", mock.Anything, mock.Anything)
+	s.Equal([]notify.Event{notify.EventApplicationReceived, notify.EventIncomplete}, s.notifiedEvents())
+}
+
+func (s *LoanOriginationWorkflowTestSuite) Test_Policy_ProductWithoutAppraisal() {
+	rules := testPolicy()
+	rules.Product.Name = policy.ProductPersonal
+	rules.Product.RequiredDocuments = []policy.DocumentType{policy.DocumentIncomeStatement}
+	rules.Product.RequireAppraisal = false
+	rules.Product.Decisioning.MaxLTV, rules.Product.Decisioning.ReferLTV = 0, 0
+	rules.Product.SLA.Funding = policy.Duration(time.Hour)
+
+	var awaitingDecision LoanOriginationState
+	appraisal := s.updateAt(time.Minute, "completeAppraisal", AppraisalCompletedSignal{PropertyValue: 300000})
+	s.uploadAt(2*time.Minute, "doc-1", "income_statement")
+	s.verifyAt(3*time.Minute, "doc-1", "verified")
+	s.queryAt(4*time.Minute, &awaitingDecision)
+	s.decideAt(5*time.Minute, "approved")
+	s.signAt(6 * time.Minute)
+
+	state := s.executeWorkflowWith(LoanOriginationWorkflowInput{LoanApplication: testLoanApplication(), Policy: rules})
+
+	s.Error(appraisal.rejected)
+	s.Equal("Waiting for underwriting decision", awaitingDecision.NextStep)
+	s.Len(awaitingDecision.DocumentChecklist, 1)
+	s.Equal(policy.ProductPersonal, awaitingDecision.LoanApplication.Product)
+	s.Require().NotNil(awaitingDecision.Recommendation)
+	s.Equal(decisioning.Approve, awaitingDecision.Recommendation.Recommendation)
+	s.Nil(state.Appraisal)
+	// The product's one hour funding SLA expires
+	s.Equal("funding_timeout", state.Status)
+}
+
+// submitDocumentsAt uploads and verifies one document of each type, a minute
+// apart, and returns when the last one is verified.
+func (s *LoanOriginationWorkflowTestSuite) submitDocumentsAt(start time.Duration, documentTypes []policy.DocumentType) time.Duration {
+	at := start
+	for i, documentType := range documentTypes {
+		documentID := fmt.Sprintf("doc-%d", i+1)
+		s.uploadAt(at, documentID, documentType)
+		s.verifyAt(at+time.Minute, documentID, "verified")
+		at += 2 * time.Minute
+	}
+	return at
+}
+
+func (s *LoanOriginationWorkflowTestSuite) Test_AutoLoan_ValuesVehicleInsteadOfAppraisal() {
+	rules := productPolicy(policy.ProductAuto)
+	application := testLoanApplication()
+	application.LoanAmount = 15000
+	application.Vehicle = &Vehicle{VIN: "1HGCM82633A004352", Year: time.Now().Year() - 2, Make: "Honda", Model: "Accord", Mileage: 24000}
+
+	var awaitingDecision LoanOriginationState
+	appraisal := s.updateAt(time.Minute, "completeAppraisal", AppraisalCompletedSignal{PropertyValue: 300000})
+	done := s.submitDocumentsAt(2*time.Minute, rules.Product.RequiredDocuments)
+	s.queryAt(done, &awaitingDecision)
+	s.decideAt(done+time.Minute, "rejected")
+
+	s.executeWorkflowWith(LoanOriginationWorkflowInput{LoanApplication: application, Policy: rules})
+
+	s.Error(appraisal.rejected)
+	s.Equal("Waiting for underwriting decision", awaitingDecision.NextStep)
+	s.Len(awaitingDecision.DocumentChecklist, 3)
+	s.Nil(awaitingDecision.Appraisal)
+	s.Nil(awaitingDecision.LienCheck)
+	s.Require().NotNil(awaitingDecision.VehicleValuation)
+	s.Equal("completed", awaitingDecision.VehicleValuation.Status)
+	s.Positive(awaitingDecision.VehicleValuation.Value)
+	s.Require().NotNil(awaitingDecision.Recommendation)
+	s.InDelta(15000/awaitingDecision.VehicleValuation.Value, awaitingDecision.Recommendation.LTV, 0.0001)
+}
+
+func (s *LoanOriginationWorkflowTestSuite) Test_AutoLoan_InvalidVehicleIsReferred() {
+	rules := productPolicy(policy.ProductAuto)
+	application := testLoanApplication()
+	application.Vehicle = &Vehicle{VIN: "not-a-vin", Year: 2020, Make: "Honda", Model: "Accord"}
+
+	var awaitingDecision LoanOriginationState
+	done := s.submitDocumentsAt(time.Minute, rules.Product.RequiredDocuments)
+	s.queryAt(done, &awaitingDecision)
+
+	s.executeWorkflowWith(LoanOriginationWorkflowInput{LoanApplication: application, Policy: rules})
+
+	s.Require().NotNil(awaitingDecision.VehicleValuation)
+	s.Equal("failed", awaitingDecision.VehicleValuation.Status)
+	s.Contains(awaitingDecision.VehicleValuation.Error, "invalid VIN")
+	s.Require().NotNil(awaitingDecision.Recommendation)
+	s.Equal(decisioning.Refer, awaitingDecision.Recommendation.Recommendation)
+	s.Equal(decisioning.ReasonCollateralValueMissing, awaitingDecision.Recommendation.Reasons[0].Code)
+}
+
+func (s *LoanOriginationWorkflowTestSuite) Test_AutoLoan_WithdrawnWhileValuing() {
+	s.env.OnActivity(activities.VoidLoanAgreement, mock.Anything, mock.Anything).Return(nil)
+	s.env.OnActivity(activities.ValueVehicle, mock.Anything, mock.Anything).After(10*time.Minute).Return(
+		&activities.VehicleValuationResult{Value: 20000, Source: "test", Reference: "valuation-1"}, nil)
+	rules := productPolicy(policy.ProductAuto)
+	application := testLoanApplication()
+	application.LoanAmount = 15000
+	application.Vehicle = &Vehicle{VIN: "1HGCM82633A004352", Year: time.Now().Year() - 2, Make: "Honda", Model: "Accord", Mileage: 24000}
+
+	done := s.submitDocumentsAt(time.Minute, rules.Product.RequiredDocuments)
+	withdrawal := s.updateAt(done, "closeApplication", CloseApplicationSignal{
+		Status:      "withdrawn",
+		ReasonCode:  ClosureFoundOtherLender,
+		RequestedBy: "customer",
+	})
+
+	state := s.executeWorkflowWith(LoanOriginationWorkflowInput{LoanApplication: application, Policy: rules})
+
+	s.NoError(withdrawal.rejected)
+	s.Equal("withdrawn", state.Status)
+	s.Equal("n/a", state.NextStep)
+	for _, projected := range s.projections {
+		if projected.closed() {
+			s.Equal("n/a", projected.NextStep, "first projection after the withdrawal")
+			break
+		}
+	}
+	s.Require().NotNil(state.VehicleValuation)
+	s.Equal("completed", state.VehicleValuation.Status)
+	s.Nil(state.Recommendation)
+	s.env.AssertNotCalled(s.T(), "CreditScoreCheck", mock.Anything, mock.Anything)
+}
+
+func (s *LoanOriginationWorkflowTestSuite) Test_HELOC_ChecksLiensForCombinedLTV() {
+	rules := productPolicy(policy.ProductHELOC)
+	application := testLoanApplication()
+	application.LoanAmount = 30000
+	application.PropertyAddress = "1 Main St, Springfield"
+
+	var awaitingAppraisal, awaitingDecision LoanOriginationState
+	done := s.submitDocumentsAt(time.Minute, rules.Product.RequiredDocuments)
+	s.queryAt(done, &awaitingAppraisal)
+	s.appraiseAt(done + time.Minute)
+	s.queryAt(done+2*time.Minute, &awaitingDecision)
+
+	s.executeWorkflowWith(LoanOriginationWorkflowInput{LoanApplication: application, Policy: rules})
+
+	s.Equal("Waiting for appraisal", awaitingAppraisal.NextStep)
+	s.Equal(policy.ProductHELOC, awaitingDecision.LoanApplication.Product)
+	s.Require().NotNil(awaitingDecision.LienCheck)
+	s.Equal("completed", awaitingDecision.LienCheck.Status)
+	s.NotEmpty(awaitingDecision.LienCheck.Reference)
+	s.Require().NotNil(awaitingDecision.Recommendation)
+	s.InDelta((30000+awaitingDecision.LienCheck.ExistingBalance)/300000, awaitingDecision.Recommendation.LTV, 0.0001)
+}
+
+func (s *LoanOriginationWorkflowTestSuite) Test_PersonalLoan_SkipsCollateral() {
+	rules := productPolicy(policy.ProductPersonal)
+	application := testLoanApplication()
+	application.LoanAmount = 10000
+	application.MonthlyIncome = 8000
+
+	var awaitingDecision LoanOriginationState
+	done := s.submitDocumentsAt(time.Minute, rules.Product.RequiredDocuments)
+	s.queryAt(done, &awaitingDecision)
+
+	s.executeWorkflowWith(LoanOriginationWorkflowInput{LoanApplication: application, Policy: rules})
+
+	s.Equal("Waiting for underwriting decision", awaitingDecision.NextStep)
+	s.Nil(awaitingDecision.Appraisal)
+	s.Nil(awaitingDecision.VehicleValuation)
+	s.Nil(awaitingDecision.LienCheck)
+	s.Require().NotNil(awaitingDecision.Recommendation)
+	s.Equal(decisioning.Approve, awaitingDecision.Recommendation.Recommendation)
+	s.Zero(awaitingDecision.Recommendation.LTV)
+}
+
+func (s *LoanOriginationWorkflowTestSuite) Test_LegacyInput_UsesOriginalRules() {
+	var awaitingDocs LoanOriginationState
+	s.queryAt(time.Minute, &awaitingDocs)
+
+	state := s.executeWorkflowWith(LoanOriginationWorkflowInput{LoanApplication: testLoanApplication()})
+
+	s.Len(awaitingDocs.DocumentChecklist, 2)
+	s.Equal("legacy", awaitingDocs.Policy.Version)
+	s.Equal(policy.ProductMortgage, awaitingDocs.LoanApplication.Product)
+	s.Equal("incomplete", state.Status)
+}
+
+func (s *LoanOriginationWorkflowTestSuite) Test_Timeout_WaitingForFunding() {
+	var beforeTimeout LoanOriginationState
+
+	s.uploadAt(time.Minute, "doc-1", "income_statement")
+	s.uploadAt(2*time.Minute, "doc-2", "bank_statement")
+	s.verifyAt(3*time.Minute, "doc-1", "verified")
+	s.verifyAt(4*time.Minute, "doc-2", "verified")
+	s.appraiseAt(5 * time.Minute)
+	s.decideAt(6*time.Minute, "approved")
+	s.signAt(7 * time.Minute)
+	s.queryAt(6*24*time.Hour, &beforeTimeout)
+
+	state := s.executeWorkflow()
+
+	s.Equal("approved", beforeTimeout.Status)
+	s.Equal("Waiting for funding", beforeTimeout.NextStep)
+
+	s.Equal("funding_timeout", state.Status)
+	s.Equal("funding_timeout", state.LoanApplication.Status)
+	s.Equal("n/a", state.NextStep)
+	s.Nil(state.Funding)
+	s.env.AssertNotCalled(s.T(), "ProcessFunding", mock.Anything, mock.Anything)
+}
+
+func (s *LoanOriginationWorkflowTestSuite) Test_Withdrawn_WhileProcessing() {
+	s.env.OnActivity(activities.VoidLoanAgreement, mock.Anything, mock.Anything).Return(nil)
+
+	s.uploadAt(time.Minute, "doc-1", "income_statement")
+	wrongReason := s.updateAt(2*time.Minute, "closeApplication", CloseApplicationSignal{Status: "withdrawn", ReasonCode: ClosureSuspectedFraud})
+	otherWithoutComments := s.updateAt(2*time.Minute, "closeApplication", CloseApplicationSignal{Status: "withdrawn", ReasonCode: ClosureOther})
+	withdrawal := s.updateAt(3*time.Minute, "closeApplication", CloseApplicationSignal{
+		Status:      "withdrawn",
+		ReasonCode:  ClosureFoundOtherLender,
+		Comments:    "Went with a credit union",
+		RequestedBy: "customer",
+	})
+	lateUpload := s.updateAt(3*time.Minute, "uploadDocument", DocumentUploadedSignal{DocumentID: "doc-2", DocumentType: "bank_statement"})
+
+	state := s.executeWorkflow()
+
+	s.Error(wrongReason.rejected)
+	s.Error(otherWithoutComments.rejected)
+	s.NoError(withdrawal.rejected)
+	s.Equal("withdrawn", withdrawal.state.Status)
+	s.Error(lateUpload.rejected)
+
+	s.Equal("withdrawn", state.Status)
+	s.Equal("withdrawn", state.LoanApplication.Status)
+	s.Equal("n/a", state.NextStep)
+	s.Require().NotNil(state.Closure)
+	s.Equal(ClosureFoundOtherLender, state.Closure.ReasonCode)
+	s.Equal("processing", state.Closure.PreviousStatus)
+	s.Equal("customer", state.Closure.RequestedBy)
+	s.NotNil(state.Closure.CleanedUpAt)
+	s.Empty(state.Closure.CleanupErrors)
+	s.env.AssertCalled(s.T(), "VoidLoanAgreement", mock.Anything, mock.Anything)
+	s.env.AssertCalled(s.T(), "ReleaseRateLock", mock.Anything, mock.Anything)
+	s.Equal([]notify.Event{notify.EventApplicationReceived, notify.EventWithdrawn}, s.notifiedEvents())
+	s.env.AssertNotCalled(s.T(), "CreditScoreCheck", mock.Anything, mock.Anything)
+}
+
+func (s *LoanOriginationWorkflowTestSuite) Test_Cancelled_WhileWaitingForFunding() {
+	s.env.OnActivity(activities.VoidLoanAgreement, mock.Anything, mock.Anything).Return(
+		temporal.NewNonRetryableApplicationError("agreement service unavailable", "AgreementError", nil))
+
+	s.uploadAt(time.Minute, "doc-1", "income_statement")
+	s.uploadAt(2*time.Minute, "doc-2", "bank_statement")
+	s.verifyAt(3*time.Minute, "doc-1", "verified")
+	s.verifyAt(4*time.Minute, "doc-2", "verified")
+	s.appraiseAt(5 * time.Minute)
+	s.decideAt(6*time.Minute, "approved")
+	s.signAt(7 * time.Minute)
+	s.signalAt(8*time.Minute, "application-closed", CloseApplicationSignal{
+		Status:      "cancelled",
+		ReasonCode:  ClosureSuspectedFraud,
+		RequestedBy: "loan-officer",
+	})
+
+	state := s.executeWorkflow()
+
+	s.Equal("cancelled", state.Status)
+	s.Require().NotNil(state.Closure)
+	s.Equal("approved", state.Closure.PreviousStatus)
+	s.Require().Len(state.Closure.CleanupErrors, 1)
+	s.Contains(state.Closure.CleanupErrors[0], "void loan agreement")
+	s.NotNil(state.Closure.CleanedUpAt)
+	s.env.AssertNotCalled(s.T(), "ProcessFunding", mock.Anything, mock.Anything)
+	s.Equal(notify.EventCancelled, s.notifiedEvents()[len(s.notifiedEvents())-1])
+}
+
+// approveDocumentsAndAppraisal takes the application to the underwriting
+// decision, which can be made from the returned delay.
+func (s *LoanOriginationWorkflowTestSuite) approveDocumentsAndAppraisal() time.Duration {
+	s.uploadAt(time.Minute, "doc-1", "income_statement")
+	s.uploadAt(2*time.Minute, "doc-2", "bank_statement")
+	s.verifyAt(3*time.Minute, "doc-1", "verified")
+	s.verifyAt(4*time.Minute, "doc-2", "verified")
+	s.appraiseAt(5 * time.Minute)
+	return 6 * time.Minute
+}
+
+func (s *LoanOriginationWorkflowTestSuite) counterOfferAt(delay time.Duration) *updateResult {
+	return s.updateAt(delay, "makeUnderwritingDecision", UnderwritingDecisionSignal{
+		Decision:      "counter_offer",
+		Comments:      "LTV too high for the requested amount",
+		UnderwriterID: "underwriter-001",
+		CounterOffer:  &LoanTerms{LoanAmount: 200000, InterestRate: 0.075, TermMonths: 240},
+	})
+}
+
+func (s *LoanOriginationWorkflowTestSuite) Test_CounterOffer_AcceptedThenFunded() {
+	decide := s.approveDocumentsAndAppraisal()
+	missingTerms := s.updateAt(decide, "makeUnderwritingDecision", UnderwritingDecisionSignal{Decision: "counter_offer"})
+	offer := s.counterOfferAt(decide + time.Minute)
+	earlyFunding := s.updateAt(decide+2*time.Minute, "completeFunding", FundingCompletedSignal{FundingAmount: 200000})
+	accept := s.updateAt(decide+3*time.Minute, "respondToCounterOffer", CounterOfferResponseSignal{Accepted: true, RespondedBy: "customer"})
+	secondAnswer := s.updateAt(decide+4*time.Minute, "respondToCounterOffer", CounterOfferResponseSignal{Accepted: false})
+	s.signAt(decide + 5*time.Minute)
+	originalAmount := s.updateAt(decide+6*time.Minute, "completeFunding", FundingCompletedSignal{FundingAmount: 250000})
+	s.fundAmountAt(decide+6*time.Minute, 200000)
+
+	state := s.executeWorkflow()
+
+	s.Error(missingTerms.rejected)
+	s.NoError(offer.rejected)
+	s.Equal("counter_offered", offer.state.Status)
+	s.Equal("Waiting for borrower to respond to counter-offer", offer.state.NextStep)
+	s.Require().NotNil(offer.state.CounterOffer)
+	s.Equal(1611.19, offer.state.CounterOffer.Terms.MonthlyPayment)
+	s.Equal(250000.0, offer.state.CounterOffer.OriginalTerms.LoanAmount)
+	s.Error(earlyFunding.rejected)
+	s.NoError(accept.rejected)
+	s.Error(secondAnswer.rejected)
+	s.ErrorContains(originalAmount.rejected, "does not match the approved amount 200000.00")
+
+	s.Equal("funded", state.Status)
+	s.Equal("accepted", state.CounterOffer.Status)
+	s.Equal("customer", state.CounterOffer.RespondedBy)
+	s.Equal(200000.0, state.LoanApplication.LoanAmount)
+	s.Equal(state.CounterOffer.Terms, state.Terms)
+	s.Require().Len(s.agreements, 2)
+	s.Equal(250000.0, s.agreements[0].LoanAmount)
+	s.Equal(2, s.agreements[1].Version)
+	s.Equal(200000.0, s.agreements[1].LoanAmount)
+	s.Equal(0.075, s.agreements[1].InterestRate)
+	s.Equal(240, s.agreements[1].TermMonths)
+	s.Equal(1611.19, s.agreements[1].MonthlyPayment)
+	s.Require().NotNil(state.LoanAgreement)
+	s.Equal(2, state.LoanAgreement.Version)
+	s.Equal(state.Terms, state.LoanAgreement.Terms)
+	s.Equal("loans/loan-1/agreements/v2.pdf", state.LoanAgreement.PDF.FilePath)
+	s.Equal(200000.0, state.Funding.Amount)
+	s.env.AssertCalled(s.T(), "ProcessFunding", mock.Anything, activities.ProcessFundingInput{
+		LoanApplicationID: "loan-1",
+		Attempt:           1,
+		Amount:            200000,
+		FundedAt:          state.Funding.FundedAt,
+	})
+	s.Equal([]notify.Event{
+		notify.EventApplicationReceived,
+		notify.EventCounterOffered,
+		notify.EventSignatureRequested,
+		notify.EventFunded,
+	}, s.notifiedEvents())
+	s.Equal(200000.0, s.notifications[2].Data.LoanAmount)
+	s.Equal(state.CounterOffer.ExpiresAt, s.notifications[2].Data.ExpiresAt)
+}
+
+func (s *LoanOriginationWorkflowTestSuite) Test_CounterOffer_Declined() {
+	decide := s.approveDocumentsAndAppraisal()
+	s.counterOfferAt(decide)
+	s.signalAt(decide+time.Minute, "counter-offer-response", CounterOfferResponseSignal{
+		Accepted:    false,
+		Comments:    "Need the full amount",
+		RespondedBy: "customer",
+	})
+
+	state := s.executeWorkflow()
+
+	s.Equal("counter_offer_declined", state.Status)
+	s.Equal("n/a", state.NextStep)
+	s.Require().NotNil(state.CounterOffer)
+	s.Equal("declined", state.CounterOffer.Status)
+	s.Equal("Need the full amount", state.CounterOffer.Comments)
+	s.NotNil(state.CounterOffer.RespondedAt)
+	s.Equal(250000.0, state.LoanApplication.LoanAmount)
+	s.Len(s.agreements, 1)
+	s.Equal(1, state.LoanAgreement.Version)
+	s.env.AssertNotCalled(s.T(), "ProcessFunding", mock.Anything, mock.Anything)
+}
+
+func (s *LoanOriginationWorkflowTestSuite) Test_CounterOffer_StaleResponseDropped() {
+	var awaitingResponse LoanOriginationState
+
+	decide := s.approveDocumentsAndAppraisal()
+	s.signalAt(decide-time.Minute, "counter-offer-response", CounterOfferResponseSignal{Accepted: true, RespondedBy: "customer"})
+	s.counterOfferAt(decide)
+	s.queryAt(decide+time.Minute, &awaitingResponse)
+
+	state := s.executeWorkflow()
+
+	s.Equal("counter_offered", awaitingResponse.Status)
+	s.Require().NotNil(awaitingResponse.CounterOffer)
+	s.Equal("pending", awaitingResponse.CounterOffer.Status)
+	s.Equal("counter_offer_expired", state.Status)
+	s.Equal("expired", state.CounterOffer.Status)
+	s.Len(s.agreements, 1)
+	s.env.AssertNotCalled(s.T(), "ProcessFunding", mock.Anything, mock.Anything)
+}
+
+func (s *LoanOriginationWorkflowTestSuite) Test_CounterOffer_Expired() {
+	var beforeExpiry LoanOriginationState
+
+	decide := s.approveDocumentsAndAppraisal()
+	s.counterOfferAt(decide)
+	s.queryAt(6*24*time.Hour, &beforeExpiry)
+
+	state := s.executeWorkflow()
+
+	s.Equal("counter_offered", beforeExpiry.Status)
+	s.Equal("counter_offer_expired", state.Status)
+	s.Equal("expired", state.CounterOffer.Status)
+	s.Nil(state.CounterOffer.RespondedAt)
+	s.Equal(7*24*time.Hour, state.CounterOffer.ExpiresAt.Sub(state.CounterOffer.OfferedAt))
+	s.env.AssertNotCalled(s.T(), "ProcessFunding", mock.Anything, mock.Anything)
+}
+
+func (s *LoanOriginationWorkflowTestSuite) approveWithConditionsAt(delay time.Duration) *updateResult {
+	return s.updateAt(delay, "makeUnderwritingDecision", UnderwritingDecisionSignal{
+		Decision:      "approved",
+		UnderwriterID: "underwriter-001",
+		Conditions: []ConditionRequest{
+			{Description: "Provide final pay stub"},
+			{Description: "Provide proof of homeowners insurance"},
+		},
+	})
+}
+
+func (s *LoanOriginationWorkflowTestSuite) Test_ConditionalApproval_FundedOnceConditionsCleared() {
+	decide := s.approveDocumentsAndAppraisal()
+	rejectedWithConditions := s.updateAt(decide, "makeUnderwritingDecision", UnderwritingDecisionSignal{
+		Decision:   "rejected",
+		Conditions: []ConditionRequest{{Description: "Provide final pay stub"}},
+	})
+	approval := s.approveWithConditionsAt(decide + time.Minute)
+	earlyFunding := s.updateAt(decide+2*time.Minute, "completeFunding", FundingCompletedSignal{FundingAmount: 250000})
+	satisfied := s.updateAt(decide+3*time.Minute, "clearCondition", ClearConditionSignal{
+		ConditionID: "condition-1",
+		Status:      "satisfied",
+		ClearedBy:   "loan-processor",
+	})
+	again := s.updateAt(decide+4*time.Minute, "clearCondition", ClearConditionSignal{ConditionID: "condition-1", Status: "waived", Comments: "n/a"})
+	waivedWithoutComments := s.updateAt(decide+4*time.Minute, "clearCondition", ClearConditionSignal{ConditionID: "condition-2", Status: "waived"})
+	s.signalAt(decide+5*time.Minute, "condition-cleared", ClearConditionSignal{
+		ConditionID: "condition-2",
+		Status:      "waived",
+		Comments:    "Escrow covers insurance",
+		ClearedBy:   "underwriter-001",
+	})
+	s.signAt(decide + 6*time.Minute)
+	s.fundAt(decide + 7*time.Minute)
+
+	state := s.executeWorkflow()
+
+	s.Error(rejectedWithConditions.rejected)
+	s.NoError(approval.rejected)
+	s.Equal("conditionally_approved", approval.state.Status)
+	s.Equal("Waiting for approval conditions: 2 open", approval.state.NextStep)
+	s.Error(earlyFunding.rejected)
+	s.NoError(satisfied.rejected)
+	s.Equal("Waiting for approval conditions: 1 open", satisfied.state.NextStep)
+	s.Error(again.rejected)
+	s.Error(waivedWithoutComments.rejected)
+
+	s.Equal("funded", state.Status)
+	s.Require().Len(state.Conditions, 2)
+	s.Equal("satisfied", state.Conditions[0].Status)
+	s.Equal("loan-processor", state.Conditions[0].ClearedBy)
+	s.Equal("waived", state.Conditions[1].Status)
+	s.Equal("Escrow covers insurance", state.Conditions[1].Comments)
+	s.NotNil(state.Conditions[1].ClearedAt)
+	s.env.AssertCalled(s.T(), "ProcessFunding", mock.Anything, mock.Anything)
+	s.Equal(notify.EventConditionallyApproved, s.notifications[2].Event)
+	s.Equal([]string{"Provide final pay stub", "Provide proof of homeowners insurance"}, s.notifications[2].Data.Conditions)
+}
+
+func (s *LoanOriginationWorkflowTestSuite) Test_ConditionalApproval_TimesOutWithOpenConditions() {
+	var beforeTimeout LoanOriginationState
+
+	decide := s.approveDocumentsAndAppraisal()
+	s.approveWithConditionsAt(decide)
+	s.queryAt(29*24*time.Hour, &beforeTimeout)
+
+	state := s.executeWorkflow()
+
+	s.Equal("conditionally_approved", beforeTimeout.Status)
+	s.Equal("conditions_timeout", state.Status)
+	s.Equal("n/a", state.NextStep)
+	s.Equal("open", state.Conditions[0].Status)
+	s.env.AssertNotCalled(s.T(), "ProcessFunding", mock.Anything, mock.Anything)
+}
+
+func (s *LoanOriginationWorkflowTestSuite) Test_Signature_RequiredBeforeFunding() {
+	decide := s.approveDocumentsAndAppraisal()
+	s.decideAt(decide, "approved")
+	earlyFunding := s.updateAt(decide+time.Minute, "completeFunding", FundingCompletedSignal{FundingAmount: 250000})
+	stale := testSignature()
+	stale.DocumentSHA256 = "stale-agreement-sha"
+	staleHash := s.updateAt(decide+2*time.Minute, "signAgreement", stale)
+	noAddress := testSignature()
+	noAddress.IPAddress = ""
+	missingAddress := s.updateAt(decide+2*time.Minute, "signAgreement", noAddress)
+	unnamed := testSignature()
+	unnamed.SignerName = ""
+	s.signalAt(decide+2*time.Minute, "agreement-signature", unnamed)
+	signed := s.updateAt(decide+3*time.Minute, "signAgreement", testSignature())
+	again := s.updateAt(decide+4*time.Minute, "signAgreement", testSignature())
+	s.fundAt(decide + 5*time.Minute)
+
+	state := s.executeWorkflow()
+
+	s.Error(earlyFunding.rejected)
+	s.Error(staleHash.rejected)
+	s.Error(missingAddress.rejected)
+	s.NoError(signed.rejected)
+	s.Equal("approved", signed.state.Status)
+	s.Equal("Waiting for funding", signed.state.NextStep)
+	s.Error(again.rejected)
+
+	s.Equal("funded", state.Status)
+	s.Equal("signed", state.Signature.Status)
+	s.Equal("customer", state.Signature.SignedBy)
+	s.Equal("test-browser", state.Signature.UserAgent)
+	s.Equal(state.LoanAgreement.PDF.SHA256, state.Signature.DocumentSHA256)
+	s.Equal(state.Signature.RequestedAt.Add(3*time.Minute), *state.Signature.SignedAt)
+	s.env.AssertNumberOfCalls(s.T(), "ProcessFunding", 1)
+}
+
+func (s *LoanOriginationWorkflowTestSuite) Test_Signature_Declined() {
+	decide := s.approveDocumentsAndAppraisal()
+	s.decideAt(decide, "approved")
+	declined := s.updateAt(decide+time.Minute, "signAgreement", AgreementSignatureSignal{
+		Signed:    false,
+		Reason:    "Rate is higher than quoted",
+		SignedBy:  "customer",
+		IPAddress: "203.0.113.7",
+	})
+
+	state := s.executeWorkflow()
+
+	s.NoError(declined.rejected)
+	s.Equal("signature_declined", state.Status)
+	s.Equal("n/a", state.NextStep)
+	s.Equal("declined", state.Signature.Status)
+	s.Equal("Rate is higher than quoted", state.Signature.Reason)
+	s.NotNil(state.Signature.DeclinedAt)
+	s.Nil(state.Signature.SignedAt)
+	s.env.AssertNotCalled(s.T(), "ProcessFunding", mock.Anything, mock.Anything)
+}
+
+func (s *LoanOriginationWorkflowTestSuite) Test_Signature_Expires() {
+	var beforeExpiry LoanOriginationState
+
+	decide := s.approveDocumentsAndAppraisal()
+	s.decideAt(decide, "approved")
+	s.queryAt(13*24*time.Hour, &beforeExpiry)
+
+	state := s.executeWorkflow()
+
+	s.Equal("awaiting_signature", beforeExpiry.Status)
+	s.Equal(beforeExpiry.Signature.RequestedAt.Add(policy.DefaultSignatureExpiry), beforeExpiry.Signature.ExpiresAt)
+	s.Equal("signature_expired", state.Status)
+	s.Equal("expired", state.Signature.Status)
+	s.env.AssertNotCalled(s.T(), "ProcessFunding", mock.Anything, mock.Anything)
+}
+
+func (s *LoanOriginationWorkflowTestSuite) Test_Signature_WithdrawnWhileAwaiting() {
+	s.env.OnActivity(activities.VoidLoanAgreement, mock.Anything, mock.Anything).Return(nil)
+
+	decide := s.approveDocumentsAndAppraisal()
+	s.decideAt(decide, "approved")
+	withdrawal := s.updateAt(decide+time.Minute, "closeApplication", CloseApplicationSignal{
+		Status:      "withdrawn",
+		ReasonCode:  ClosureFoundOtherLender,
+		RequestedBy: "customer",
+	})
+
+	state := s.executeWorkflow()
+
+	s.NoError(withdrawal.rejected)
+	s.Equal("withdrawn", state.Status)
+	s.Equal("awaiting_signature", state.Closure.PreviousStatus)
+	s.Equal("pending", state.Signature.Status)
+	s.env.AssertCalled(s.T(), "VoidLoanAgreement", mock.Anything, mock.Anything)
+}
+
+// ledgerDown fails a disbursement without retrying it.
+func ledgerDown() error {
+	return temporal.NewNonRetryableApplicationError("ledger unavailable", "LedgerUnavailable", nil)
+}
+
+func (s *LoanOriginationWorkflowTestSuite) Test_Funding_FailureIsCompensatedAndRetried() {
+	s.disbursementErrs = []error{ledgerDown()}
+	s.env.OnActivity(activities.VoidLoanAgreement, mock.Anything, mock.Anything).Return(nil)
+
+	var failed LoanOriginationState
+	decide := s.approveDocumentsAndAppraisal()
+	s.decideAt(decide, "approved")
+	s.signAt(decide + time.Minute)
+	s.fundAt(decide + 2*time.Minute)
+	s.queryAt(decide+3*time.Minute, &failed)
+	fundingWhileFailed := s.updateAt(decide+4*time.Minute, "completeFunding", FundingCompletedSignal{FundManagerID: "fund-manager-001", FundingAmount: 250000})
+	retry := s.updateAt(decide+5*time.Minute, "retryFunding", RetryFundingSignal{RequestedBy: "admin", Comments: "ledger is back"})
+	retryAgain := s.updateAt(decide+6*time.Minute, "retryFunding", RetryFundingSignal{RequestedBy: "admin"})
+	s.signAt(decide + 7*time.Minute)
+	s.fundAt(decide + 8*time.Minute)
+
+	state := s.executeWorkflow()
+
+	s.Equal("funding_failed", failed.Status)
+	s.Equal("Waiting for an administrator to retry funding", failed.NextStep)
+	s.Nil(failed.Funding)
+	s.Equal("voided", failed.Signature.Status)
+	s.Require().Len(failed.FundingFailures, 1)
+	failure := failed.FundingFailures[0]
+	s.Equal(1, failure.Funding.Attempt)
+	s.Equal("fund-manager-001", failure.Funding.FundManagerID)
+	s.Contains(failure.Error, "ledger unavailable")
+	s.Equal("reversal-disbursement-loan-1-1", failure.ReversalEntryID)
+	s.Empty(failure.CompensationErrors)
+	s.NotNil(failure.CompensatedAt)
+	s.env.AssertCalled(s.T(), "VoidLoanAgreement", mock.Anything, activities.VoidLoanAgreementInput{LoanApplicationID: "loan-1", Reason: "FUNDING_FAILED"})
+	s.env.AssertCalled(s.T(), "SendFundingFailureNotification", mock.Anything, mock.Anything)
+
+	s.Require().Error(fundingWhileFailed.rejected)
+	s.Contains(fundingWhileFailed.rejected.Error(), "loan is funding_failed")
+	s.Require().NoError(retry.rejected)
+	s.Equal("approved", retry.state.Status)
+	s.Require().Error(retryAgain.rejected)
+
+	s.Equal("funded", state.Status)
+	s.Equal(2, state.Funding.Attempt)
+	s.Equal("disbursement-loan-1-2", state.Funding.LedgerEntryID)
+	s.Equal("admin", state.FundingFailures[0].RetriedBy)
+	s.Equal("ledger is back", state.FundingFailures[0].RetryComments)
+	// The retry is signed and funded under a new agreement
+	s.Equal(2, state.LoanAgreement.Version)
+	s.Equal("signed", state.Signature.Status)
+	s.Equal(2, state.Signature.AgreementVersion)
+	s.Require().Len(s.disbursements, 2)
+	s.Equal(1, s.disbursements[0].Attempt)
+	s.Equal(2, s.disbursements[1].Attempt)
+}
+
+func (s *LoanOriginationWorkflowTestSuite) Test_Funding_FailureNotRetried() {
+	s.disbursementErrs = []error{ledgerDown()}
+	s.env.OnActivity(activities.VoidLoanAgreement, mock.Anything, mock.Anything).Return(
+		temporal.NewNonRetryableApplicationError("document system unavailable", "DocumentSystemUnavailable", nil))
+
+	var awaitingRetry LoanOriginationState
+	decide := s.approveDocumentsAndAppraisal()
+	s.decideAt(decide, "approved")
+	s.signAt(decide + time.Minute)
+	s.fundAt(decide + 2*time.Minute)
+	s.queryAt(6*24*time.Hour, &awaitingRetry)
+
+	state := s.executeWorkflow()
+
+	s.Equal("funding_failed", awaitingRetry.Status)
+	s.Equal(fundingRetryStep, awaitingRetry.NextStep)
+	s.Equal("funding_abandoned", state.Status)
+	s.Equal("funding_abandoned", state.LoanApplication.Status)
+	s.Equal("n/a", state.NextStep)
+	s.Nil(state.Funding)
+	s.Require().Len(state.FundingFailures, 1)
+	s.Require().Len(state.FundingFailures[0].CompensationErrors, 1)
+	s.Contains(state.FundingFailures[0].CompensationErrors[0], "void loan agreement")
+	s.Nil(state.FundingFailures[0].RetriedAt)
+	s.env.AssertCalled(s.T(), "SendFundingFailureNotification", mock.Anything, mock.Anything)
+}
+
+func (s *LoanOriginationWorkflowTestSuite) Test_Funding_WithdrawnAfterFailure() {
+	s.disbursementErrs = []error{ledgerDown()}
+	s.env.OnActivity(activities.VoidLoanAgreement, mock.Anything, mock.Anything).Return(nil)
+
+	decide := s.approveDocumentsAndAppraisal()
+	s.decideAt(decide, "approved")
+	s.signAt(decide + time.Minute)
+	s.fundAt(decide + 2*time.Minute)
+	withdrawal := s.updateAt(decide+3*time.Minute, "closeApplication", CloseApplicationSignal{
+		Status:      "withdrawn",
+		ReasonCode:  ClosureFoundOtherLender,
+		RequestedBy: "customer",
+	})
+
+	state := s.executeWorkflow()
+
+	s.NoError(withdrawal.rejected)
+	s.Equal("withdrawn", state.Status)
+	s.Equal("funding_failed", state.Closure.PreviousStatus)
+	s.Len(s.disbursements, 1)
+	s.Equal([]notify.Event{
+		notify.EventApplicationReceived,
+		notify.EventSignatureRequested,
+		notify.EventFundingFailed,
+		notify.EventWithdrawn,
+	}, s.notifiedEvents())
+}
+
+func (s *LoanOriginationWorkflowTestSuite) Test_Notifications_SentOnTransitions() {
+	s.uploadAt(time.Minute, "doc-1", "income_statement")
+	s.uploadAt(2*time.Minute, "doc-2", "bank_statement")
+	s.verifyAt(3*time.Minute, "doc-1", "verified")
+	s.signalAt(4*time.Minute, "document-verified", DocumentVerificationSignal{
+		DocumentID:          "doc-2",
+		VerificationStatus:  "rejected",
+		VerificationDetails: map[string]interface{}{"reason": "statement is more than 90 days old"},
+	})
+	s.uploadAt(5*time.Minute, "doc-3", "bank_statement")
+	s.verifyAt(6*time.Minute, "doc-3", "verified")
+	s.appraiseAt(7 * time.Minute)
+	s.requestDocumentsAt(8*time.Minute, policy.DocumentTaxReturns)
+	s.uploadAt(9*time.Minute, "doc-4", "tax_returns")
+	s.verifyAt(10*time.Minute, "doc-4", "verified")
+	s.decideAt(11*time.Minute, "approved")
+	s.signAt(12 * time.Minute)
+	s.fundAt(13 * time.Minute)
+
+	state := s.executeWorkflow()
+
+	s.Equal("funded", state.Status)
+	s.Equal([]notify.Event{
+		notify.EventApplicationReceived,
+		notify.EventDocumentRejected,
+		notify.EventDocumentsRequested,
+		notify.EventSignatureRequested,
+		notify.EventFunded,
+	}, s.notifiedEvents())
+
+	// Each message goes out by email and SMS
+	s.Require().Len(s.notifications, 10)
+	rejected := s.notifications[2]
+	s.Equal(notify.ChannelEmail, rejected.Channel)
+	s.Equal("jane@example.com", rejected.Recipient)
+	s.Equal("loan-1", rejected.Data.LoanApplicationID)
+	s.Equal("Jane Doe", rejected.Data.BorrowerName)
+	s.Equal("bank statement", rejected.Data.DocumentType)
+	s.Equal("statement is more than 90 days old", rejected.Data.Reason)
+	s.Equal(notify.ChannelSMS, s.notifications[3].Channel)
+	s.Equal("555-0100", s.notifications[3].Recipient)
+	s.Equal([]string{"tax returns"}, s.notifications[4].Data.Documents)
+	s.Equal(state.Signature.ExpiresAt, s.notifications[6].Data.ExpiresAt)
+	s.Equal(250000.0, s.notifications[8].Data.LoanAmount)
+
+	s.Require().Len(state.Notifications, 10)
+	for i, notification := range state.Notifications {
+		s.Equal("sent", notification.Status)
+		s.Equal(s.notifications[i].Event, notification.Event)
+		s.Equal(s.notifications[i].Channel, notification.Channel)
+		s.Equal(fmt.Sprintf("%s-%d", notification.Channel, i+1), notification.MessageID)
+	}
+	s.Equal("subject: application_received", state.Notifications[0].Subject)
+}
+
+func (s *LoanOriginationWorkflowTestSuite) Test_Notifications_FailedSendIsRecorded() {
+	s.emailErr = temporal.NewNonRetryableApplicationError("mail server unavailable", "SMTPError", nil)
+	s.signalAt(time.Minute, "application-closed", CloseApplicationSignal{
+		Status:      "cancelled",
+		ReasonCode:  ClosureDuplicateApplication,
+		RequestedBy: "loan-officer",
+	})
+
+	state := s.executeWorkflow()
+
+	s.Equal("cancelled", state.Status)
+	s.Require().Len(state.Notifications, 4)
+	for _, notification := range state.Notifications {
+		if notification.Channel == notify.ChannelEmail {
+			s.Equal("failed", notification.Status)
+			s.Contains(notification.Error, "mail server unavailable")
+			s.Empty(notification.MessageID)
+		} else {
+			s.Equal("sent", notification.Status)
+			s.Empty(notification.Error)
+		}
+	}
+	s.Equal(notify.EventCancelled, state.Notifications[3].Event)
+}
+
+// updateResult records the outcome of a workflow update in tests.
+type updateResult struct {
+	rejected error
+	state    LoanOriginationState
+	err      error
+}
+
+func (u *updateResult) Accept() {}
+
+func (u *updateResult) Reject(err error) {
+	u.rejected = err
+}
+
+func (u *updateResult) Complete(success interface{}, err error) {
+	u.err = err
+	if state, ok := success.(LoanOriginationState); ok {
+		u.state = state
+	}
+}
+
+// updateAt runs a workflow update after the given delay.
+func (s *LoanOriginationWorkflowTestSuite) updateAt(delay time.Duration, name string, arg interface{}) *updateResult {
+	result := &updateResult{}
+	s.env.RegisterDelayedCallback(func() {
+		s.env.UpdateWorkflow(name, "", result, arg)
+	}, delay)
+	return result
+}
+
+func (s *LoanOriginationWorkflowTestSuite) Test_Updates_ReturnResultingState() {
+	upload1 := s.updateAt(time.Minute, "uploadDocument", DocumentUploadedSignal{
+		DocumentID:   "doc-1",
+		DocumentType: "income_statement",
+		FileName:     "passport.pdf",
+		FilePath:     "loans/loan-1/documents/doc-1",
+		ContentType:  "application/pdf",
+		Size:         1024,
+		SHA256:       "abc123",
+	})
+	upload2 := s.updateAt(2*time.Minute, "uploadDocument", DocumentUploadedSignal{DocumentID: "doc-2", DocumentType: "bank_statement"})
+	appraisal := s.updateAt(3*time.Minute, "completeAppraisal", AppraisalCompletedSignal{PropertyValue: 300000, AppraiserID: "appraiser-001"})
+	verify1 := s.updateAt(4*time.Minute, "verifyDocument", DocumentVerificationSignal{DocumentID: "doc-1", VerificationStatus: "verified"})
+	verify2 := s.updateAt(5*time.Minute, "verifyDocument", DocumentVerificationSignal{DocumentID: "doc-2", VerificationStatus: "verified"})
+	decision := s.updateAt(6*time.Minute, "makeUnderwritingDecision", UnderwritingDecisionSignal{Decision: "approved", UnderwriterID: "underwriter-001"})
+	signature := s.updateAt(7*time.Minute, "signAgreement", testSignature())
+	funding := s.updateAt(8*time.Minute, "completeFunding", FundingCompletedSignal{FundManagerID: "fund-manager-001", FundingAmount: 250000})
+
+	state := s.executeWorkflow()
+
+	for _, result := range []*updateResult{upload1, upload2, appraisal, verify1, verify2, decision, signature, funding} {
+		s.NoError(result.rejected)
+		s.NoError(result.err)
+	}
+	s.Equal("Waiting for customer documents: bank_statement", upload1.state.NextStep)
+	s.Equal("passport.pdf", upload1.state.Documents[0].FileName)
+	s.Equal("loans/loan-1/documents/doc-1", upload1.state.Documents[0].FilePath)
+	s.Equal("application/pdf", upload1.state.Documents[0].ContentType)
+	s.Equal(int64(1024), upload1.state.Documents[0].Size)
+	s.Equal("abc123", upload1.state.Documents[0].SHA256)
+	s.Equal("Waiting for document verification", upload2.state.NextStep)
+	s.Require().NotNil(appraisal.state.Appraisal)
+	s.Equal("Waiting for document verification", appraisal.state.NextStep)
+	s.Equal("verified", verify1.state.Documents[0].VerificationStatus)
+	s.Equal("Performing credit score check", verify2.state.NextStep)
+	s.Equal("approved", decision.state.Status)
+	s.Equal("Waiting for borrower to sign the loan agreement", decision.state.NextStep)
+	s.Equal("approved", signature.state.Status)
+	s.Equal("signed", signature.state.Signature.Status)
+	s.Equal("funded", funding.state.Status)
+
+	s.Equal("funded", state.Status)
+}
+
+func (s *LoanOriginationWorkflowTestSuite) Test_Updates_RejectedOutsideTheirStage() {
+	earlyDecision := s.updateAt(time.Minute, "makeUnderwritingDecision", UnderwritingDecisionSignal{Decision: "approved"})
+	earlyFunding := s.updateAt(time.Minute, "completeFunding", FundingCompletedSignal{FundingAmount: 250000})
+	unknownDoc := s.updateAt(time.Minute, "verifyDocument", DocumentVerificationSignal{DocumentID: "missing", VerificationStatus: "verified"})
+	firstAppraisal := s.updateAt(2*time.Minute, "completeAppraisal", AppraisalCompletedSignal{PropertyValue: 300000})
+	secondAppraisal := s.updateAt(3*time.Minute, "completeAppraisal", AppraisalCompletedSignal{PropertyValue: 1})
+
+	state := s.executeWorkflow()
+
+	s.NoError(firstAppraisal.rejected)
+	for _, result := range []*updateResult{earlyDecision, earlyFunding, unknownDoc, secondAppraisal} {
+		var appErr *temporal.ApplicationError
+		s.Require().ErrorAs(result.rejected, &appErr)
+		s.Equal(UpdateRejectedErrorType, appErr.Type())
+	}
+	s.Nil(state.UnderwritingDecision)
+	s.Equal("incomplete", state.Status)
+}
+
+func (s *LoanOriginationWorkflowTestSuite) Test_SearchAttributes_UpsertedOnChange() {
+	var upserts []map[string]interface{}
+	s.env.OnUpsertSearchAttributes(mock.Anything).Run(func(args mock.Arguments) {
+		upserts = append(upserts, args.Get(0).(map[string]interface{}))
+	}).Return(nil)
+
+	s.uploadAt(time.Minute, "doc-1", "income_statement")
+	s.uploadAt(2*time.Minute, "doc-2", "bank_statement")
+	s.verifyAt(3*time.Minute, "doc-1", "verified")
+	s.verifyAt(4*time.Minute, "doc-2", "verified")
+	s.appraiseAt(5 * time.Minute)
+	s.decideAt(6*time.Minute, "approved")
+	s.signAt(6*time.Minute + 30*time.Second)
+	s.fundAt(7 * time.Minute)
+
+	s.executeWorkflow()
+
+	s.Require().NotEmpty(upserts)
+	first := upserts[0]
+	s.Equal("processing", first[SearchAttributeLoanStatus])
+	s.Equal("Waiting for customer documents: income_statement, bank_statement", first[SearchAttributeNextStep])
+	s.Equal("email-hash", first[SearchAttributeBorrowerEmail])
+	s.Equal(250000.0, first[SearchAttributeLoanAmount])
+
+	var statuses []interface{}
+	creditScoreUpserts := 0
+	for _, upsert := range upserts[1:] {
+		s.NotContains(upsert, SearchAttributeBorrowerEmail)
+		if status, ok := upsert[SearchAttributeLoanStatus]; ok {
+			statuses = append(statuses, status)
+		}
+		if score, ok := upsert[SearchAttributeCreditScore]; ok {
+			s.Equal(720, score)
+			creditScoreUpserts++
+		}
+	}
+	s.Equal([]interface{}{"awaiting_signature", "approved", "funded"}, statuses)
+	s.Equal(1, creditScoreUpserts)
+	s.Equal("n/a", upserts[len(upserts)-1][SearchAttributeNextStep])
+}
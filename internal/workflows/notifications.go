@@ -0,0 +1,116 @@
+package workflows
+
+import (
+	"loan-origination-system/internal/activities"
+	"loan-origination-system/internal/notify"
+	"loan-origination-system/internal/policy"
+	"strings"
+	"time"
+
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+// Notification records a message sent to the borrower, or one that could
+// not be sent.
+type Notification struct {
+	Event     notify.Event   `json:"event"`
+	Channel   notify.Channel `json:"channel"`
+	Recipient string         `json:"recipient"`
+	Subject   string         `json:"subject,omitempty"`
+	// Status is sent or failed
+	Status    string    `json:"status"`
+	MessageID string    `json:"message_id,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	SentAt    time.Time `json:"sent_at"`
+}
+
+type pendingNotification struct {
+	event notify.Event
+	data  notify.Data
+}
+
+// notifyBorrower queues a message about event for the borrower. Most
+// transitions are applied by update handlers, which do not run activities,
+// so queued messages go out the next time the state is published.
+func (s *LoanOriginationState) notifyBorrower(event notify.Event, data notify.Data) {
+	data.LoanApplicationID = s.LoanApplication.ID
+	data.BorrowerName = s.LoanApplication.BorrowerName
+	if data.LoanAmount == 0 {
+		data.LoanAmount = s.Terms.LoanAmount
+	}
+	s.pendingNotifications = append(s.pendingNotifications, pendingNotification{event: event, data: data})
+}
+
+// sendNotifications sends the queued messages by email and SMS, to whichever
+// of the borrower's address and phone number are known, and records each
+// send. A message that still fails after its retries is recorded as failed
+// and not sent again.
+func sendNotifications(ctx workflow.Context, state *LoanOriginationState) {
+	if len(state.pendingNotifications) == 0 {
+		return
+	}
+	logger := workflow.GetLogger(ctx)
+
+	notificationCtx := workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: 30 * time.Second,
+		RetryPolicy: &temporal.RetryPolicy{
+			MaximumAttempts: 3,
+		},
+	})
+
+	app := state.LoanApplication
+	recipients := []struct {
+		channel notify.Channel
+		to      string
+	}{
+		{notify.ChannelEmail, app.BorrowerEmail},
+		{notify.ChannelSMS, app.BorrowerPhone},
+	}
+
+	var notifications *activities.NotificationActivities
+	type send struct {
+		notification Notification
+		future       workflow.Future
+	}
+	var sends []send
+	for _, pending := range state.pendingNotifications {
+		for _, recipient := range recipients {
+			if recipient.to == "" {
+				continue
+			}
+			sends = append(sends, send{
+				Notification{Event: pending.event, Channel: recipient.channel, Recipient: recipient.to},
+				workflow.ExecuteActivity(notificationCtx, notifications.SendNotification, activities.SendNotificationInput{
+					LoanApplicationID: app.ID,
+					Event:             pending.event,
+					Channel:           recipient.channel,
+					Recipient:         recipient.to,
+					Data:              pending.data,
+				}),
+			})
+		}
+	}
+	state.pendingNotifications = nil
+
+	for _, s := range sends {
+		notification := s.notification
+		var result activities.SendNotificationResult
+		if err := s.future.Get(ctx, &result); err != nil {
+			logger.Error("Failed to notify borrower", "event", notification.Event, "channel", notification.Channel, "error", err)
+			notification.Status = "failed"
+			notification.Error = err.Error()
+		} else {
+			notification.Status = "sent"
+			notification.Subject = result.Subject
+			notification.MessageID = result.MessageID
+		}
+		notification.SentAt = workflow.Now(ctx)
+		state.Notifications = append(state.Notifications, notification)
+	}
+}
+
+// documentName is how a document type reads in a message to the borrower.
+func documentName(documentType policy.DocumentType) string {
+	return strings.ReplaceAll(string(documentType), "_", " ")
+}
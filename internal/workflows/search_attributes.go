@@ -0,0 +1,86 @@
+package workflows
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	enumspb "go.temporal.io/api/enums/v1"
+	"go.temporal.io/sdk/workflow"
+)
+
+// Custom search attributes upserted by LoanOriginationWorkflow so loans can be
+// found in the Temporal UI or with `temporal workflow list --query`. Search
+// attributes are indexed in plaintext, so BorrowerEmail holds a keyed hash
+// of the email rather than the address.
+const (
+	SearchAttributeLoanStatus    = "LoanStatus"
+	SearchAttributeNextStep      = "NextStep"
+	SearchAttributeLoanAmount    = "LoanAmount"
+	SearchAttributeBorrowerEmail = "BorrowerEmail"
+	SearchAttributeCreatedBy     = "CreatedBy"
+	SearchAttributeCreditScore   = "CreditScore"
+)
+
+// SearchAttributeTypes is the type each custom search attribute must be
+// registered with in the namespace before the workflow runs.
+var SearchAttributeTypes = map[string]enumspb.IndexedValueType{
+	SearchAttributeLoanStatus:    enumspb.INDEXED_VALUE_TYPE_KEYWORD,
+	SearchAttributeNextStep:      enumspb.INDEXED_VALUE_TYPE_KEYWORD,
+	SearchAttributeLoanAmount:    enumspb.INDEXED_VALUE_TYPE_DOUBLE,
+	SearchAttributeBorrowerEmail: enumspb.INDEXED_VALUE_TYPE_KEYWORD,
+	SearchAttributeCreatedBy:     enumspb.INDEXED_VALUE_TYPE_KEYWORD,
+	SearchAttributeCreditScore:   enumspb.INDEXED_VALUE_TYPE_INT,
+}
+
+// StartSearchAttributes returns the search attributes known when a loan
+// application is submitted, so the workflow is searchable from the start.
+func StartSearchAttributes(loan LoanApplication) map[string]interface{} {
+	attributes := map[string]interface{}{
+		SearchAttributeLoanStatus: loan.Status,
+		SearchAttributeLoanAmount: loan.LoanAmount,
+		SearchAttributeCreatedBy:  loan.CreatedBy,
+	}
+	if loan.BorrowerEmailHash != "" {
+		attributes[SearchAttributeBorrowerEmail] = loan.BorrowerEmailHash
+	}
+	return attributes
+}
+
+// HashBorrowerEmail returns the value indexed as BorrowerEmail: the
+// HMAC-SHA256 of the trimmed, lower-cased email under key, hex-encoded.
+// Without the key the hash cannot be matched against a list of addresses.
+func HashBorrowerEmail(key, email string) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(strings.ToLower(strings.TrimSpace(email))))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// upsertSearchAttributes upserts the search attributes whose values changed
+// since the last upsert.
+func upsertSearchAttributes(ctx workflow.Context, state *LoanOriginationState) error {
+	current := StartSearchAttributes(state.LoanApplication)
+	current[SearchAttributeLoanStatus] = state.Status
+	current[SearchAttributeNextStep] = state.NextStep
+	if state.creditScoreCompleted() {
+		current[SearchAttributeCreditScore] = state.CreditScore.Score
+	}
+
+	changed := map[string]interface{}{}
+	for name, value := range current {
+		if previous, ok := state.searchAttributes[name]; !ok || previous != value {
+			changed[name] = value
+		}
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+
+	err := workflow.UpsertSearchAttributes(ctx, changed)
+	if err != nil {
+		return err
+	}
+	state.searchAttributes = current
+	return nil
+}
@@ -0,0 +1,166 @@
+package workflows
+
+import (
+	"loan-origination-system/internal/notify"
+	"time"
+
+	"go.temporal.io/sdk/workflow"
+)
+
+// Signature is the borrower's signature on the loan agreement, requested
+// once the loan is approved and required before it is funded.
+type Signature struct {
+	// AgreementVersion and DocumentSHA256 identify the agreement PDF the
+	// borrower is asked to sign
+	AgreementVersion int    `json:"agreement_version"`
+	DocumentSHA256   string `json:"document_sha256"`
+	// Status is pending, signed, declined or expired
+	Status      string    `json:"status"`
+	RequestedAt time.Time `json:"requested_at"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	// SignerName is the name the borrower typed to sign, and SignedBy the
+	// principal who signed
+	SignerName string     `json:"signer_name,omitempty"`
+	SignedBy   string     `json:"signed_by,omitempty"`
+	IPAddress  string     `json:"ip_address,omitempty"`
+	UserAgent  string     `json:"user_agent,omitempty"`
+	Reason     string     `json:"reason,omitempty"`
+	SignedAt   *time.Time `json:"signed_at"`
+	DeclinedAt *time.Time `json:"declined_at"`
+}
+
+// AgreementSignatureSignal is the borrower signing the loan agreement, or
+// declining to. DocumentSHA256 is the hash of the agreement the borrower
+// was shown, so a signature on any other version is refused.
+type AgreementSignatureSignal struct {
+	Signed         bool   `json:"signed"`
+	SignerName     string `json:"signer_name"`
+	DocumentSHA256 string `json:"document_sha256"`
+	Reason         string `json:"reason"`
+	SignedBy       string `json:"signed_by"`
+	IPAddress      string `json:"ip_address"`
+	UserAgent      string `json:"user_agent"`
+}
+
+// signatureStep is the next step of an approved loan until the agreement is
+// signed.
+const signatureStep = "Waiting for borrower to sign the loan agreement"
+
+func requestSignature(ctx workflow.Context, state *LoanOriginationState) {
+	now := workflow.Now(ctx)
+	state.Signature = &Signature{
+		AgreementVersion: state.LoanAgreement.Version,
+		DocumentSHA256:   state.LoanAgreement.PDF.SHA256,
+		Status:           "pending",
+		RequestedAt:      now,
+		ExpiresAt:        now.Add(state.Policy.Product.SLA.SignatureExpiry()),
+	}
+	state.setStatus("awaiting_signature")
+	state.NextStep = signatureStep
+	state.notifyBorrower(notify.EventSignatureRequested, notify.Data{ExpiresAt: state.Signature.ExpiresAt})
+	workflow.GetLogger(ctx).Info("Signature requested", "agreementVersion", state.Signature.AgreementVersion)
+}
+
+func applyAgreementSignature(ctx workflow.Context, state *LoanOriginationState, signal AgreementSignatureSignal) {
+	now := workflow.Now(ctx)
+	signature := state.Signature
+	signature.SignedBy = signal.SignedBy
+	signature.IPAddress = signal.IPAddress
+	signature.UserAgent = signal.UserAgent
+
+	if signal.Signed {
+		signature.Status = "signed"
+		signature.SignerName = signal.SignerName
+		signature.SignedAt = &now
+		state.setStatus("approved")
+		state.NextStep = "Waiting for funding"
+	} else {
+		signature.Status = "declined"
+		signature.Reason = signal.Reason
+		signature.DeclinedAt = &now
+		state.setStatus("signature_declined")
+		state.NextStep = "n/a"
+	}
+	workflow.GetLogger(ctx).Info("Loan agreement signature answered", "status", signature.Status)
+}
+
+func validateAgreementSignature(state *LoanOriginationState, signal AgreementSignatureSignal) error {
+	if state.Status != "awaiting_signature" || state.Signature == nil || state.Signature.Status != "pending" {
+		return rejectUpdate("loan is %s and has no agreement waiting for a signature", state.Status)
+	}
+	if signal.IPAddress == "" {
+		return rejectUpdate("the signer's IP address is required")
+	}
+	if !signal.Signed {
+		return nil
+	}
+	if signal.SignerName == "" {
+		return rejectUpdate("the signer's name is required to sign")
+	}
+	if signal.DocumentSHA256 != state.Signature.DocumentSHA256 {
+		return rejectUpdate("document hash does not match loan agreement version %d", state.Signature.AgreementVersion)
+	}
+	return nil
+}
+
+// signed reports whether the borrower has signed the current loan agreement
+// with the timestamp, address and document hash funding relies on.
+func (s *LoanOriginationState) signed() bool {
+	return s.Signature != nil && s.Signature.Status == "signed" && s.Signature.SignedAt != nil &&
+		s.Signature.IPAddress != "" && s.LoanAgreement != nil &&
+		s.Signature.DocumentSHA256 == s.LoanAgreement.PDF.SHA256
+}
+
+// waitForSignature asks the borrower to sign the loan agreement and waits
+// until it is signed, declined or the request expires.
+func waitForSignature(ctx workflow.Context, state *LoanOriginationState, stateChanged workflow.ReceiveChannel) error {
+	logger := workflow.GetLogger(ctx)
+
+	signatureChannel := workflow.GetSignalChannel(ctx, "agreement-signature")
+	closeChannel := workflow.GetSignalChannel(ctx, "application-closed")
+
+	requestSignature(ctx, state)
+
+	timerCtx, timerCancel := workflow.WithCancel(ctx)
+	timer := workflow.NewTimer(timerCtx, state.Signature.ExpiresAt.Sub(workflow.Now(ctx)))
+
+	for state.Status == "awaiting_signature" {
+		selector := workflow.NewSelector(ctx)
+
+		selector.AddReceive(signatureChannel, func(c workflow.ReceiveChannel, more bool) {
+			var signal AgreementSignatureSignal
+			c.Receive(ctx, &signal)
+			if err := validateAgreementSignature(state, signal); err != nil {
+				logger.Warn("Ignoring agreement-signature signal", "error", err)
+				return
+			}
+			applyAgreementSignature(ctx, state, signal)
+		})
+
+		selector.AddReceive(closeChannel, func(c workflow.ReceiveChannel, more bool) {
+			receiveClosure(ctx, c, state)
+		})
+
+		selector.AddReceive(stateChanged, func(c workflow.ReceiveChannel, more bool) {
+			c.Receive(ctx, nil)
+		})
+
+		err := publishState(ctx, state)
+		if err != nil {
+			return err
+		}
+
+		selector.AddFuture(timer, func(f workflow.Future) {
+			logger.Error("Signature request expired")
+			state.Signature.Status = "expired"
+			state.setStatus("signature_expired")
+			state.NextStep = "n/a"
+		})
+
+		selector.Select(ctx)
+	}
+
+	timerCancel()
+
+	return nil
+}
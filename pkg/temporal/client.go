@@ -1,22 +1,105 @@
 package temporal
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log"
+	"os"
+
+	"loan-origination-system/internal/codec"
+	"loan-origination-system/internal/config"
+
 	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/converter"
 	"go.temporal.io/sdk/worker"
 )
 
-const (
-	TaskQueue = "loan-origination-task-queue"
-	Namespace = "default"
-)
+func NewClient(cfg config.Temporal) (client.Client, error) {
+	if !cfg.Encryption.Enabled() {
+		log.Println("WARNING: payload encryption is off, so borrower data is stored in Temporal in plaintext; set TEMPORAL_ENCRYPTION_KEY_ID and TEMPORAL_ENCRYPTION_KEYS")
+	}
+
+	dataConverter, err := NewDataConverter(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	options := client.Options{
+		HostPort:      cfg.HostPort,
+		Namespace:     cfg.Namespace,
+		DataConverter: dataConverter,
+	}
+
+	if cfg.TLSEnabled() {
+		tlsConfig, err := newTLSConfig(cfg.TLS)
+		if err != nil {
+			return nil, err
+		}
+		options.ConnectionOptions.TLS = tlsConfig
+	}
+
+	if cfg.APIKey != "" {
+		options.HeadersProvider = apiKeyHeaders{apiKey: cfg.APIKey, namespace: cfg.Namespace}
+	}
+
+	return client.Dial(options)
+}
+
+// NewDataConverter returns the converter for workflow payloads, encrypting
+// them when encryption keys are configured.
+func NewDataConverter(cfg config.Temporal) (converter.DataConverter, error) {
+	if !cfg.Encryption.Enabled() {
+		return converter.GetDefaultDataConverter(), nil
+	}
+
+	encryption, err := codec.NewFromConfig(cfg.Encryption)
+	if err != nil {
+		return nil, err
+	}
+	return converter.NewCodecDataConverter(converter.GetDefaultDataConverter(), encryption), nil
+}
+
+func NewWorker(c client.Client, taskQueue string) worker.Worker {
+	return worker.New(c, taskQueue, worker.Options{})
+}
+
+func newTLSConfig(cfg config.TLS) (*tls.Config, error) {
+	tlsConfig := &tls.Config{ServerName: cfg.ServerName}
+
+	if cfg.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	if cfg.CAFile != "" {
+		pem, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read CA certificate: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", cfg.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	return tlsConfig, nil
+}
 
-func NewClient() (client.Client, error) {
-	return client.Dial(client.Options{
-		HostPort:  "localhost:7233",
-		Namespace: Namespace,
-	})
+// apiKeyHeaders authenticates every request with a Temporal Cloud API key.
+type apiKeyHeaders struct {
+	apiKey    string
+	namespace string
 }
 
-func NewWorker(c client.Client) worker.Worker {
-	return worker.New(c, TaskQueue, worker.Options{})
+func (h apiKeyHeaders) GetHeaders(context.Context) (map[string]string, error) {
+	return map[string]string{
+		"Authorization":      "Bearer " + h.apiKey,
+		"temporal-namespace": h.namespace,
+	}, nil
 }
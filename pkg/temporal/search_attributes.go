@@ -0,0 +1,41 @@
+package temporal
+
+import (
+	"context"
+
+	"go.temporal.io/api/enums/v1"
+	"go.temporal.io/api/operatorservice/v1"
+	"go.temporal.io/sdk/client"
+)
+
+// RegisterSearchAttributes adds the custom search attributes that are not yet
+// registered in the namespace, leaving existing ones untouched.
+func RegisterSearchAttributes(ctx context.Context, c client.Client, namespace string, attributes map[string]enums.IndexedValueType) ([]string, error) {
+	existing, err := c.OperatorService().ListSearchAttributes(ctx, &operatorservice.ListSearchAttributesRequest{
+		Namespace: namespace,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	missing := map[string]enums.IndexedValueType{}
+	var added []string
+	for name, valueType := range attributes {
+		if _, ok := existing.CustomAttributes[name]; !ok {
+			missing[name] = valueType
+			added = append(added, name)
+		}
+	}
+	if len(missing) == 0 {
+		return nil, nil
+	}
+
+	_, err = c.OperatorService().AddSearchAttributes(ctx, &operatorservice.AddSearchAttributesRequest{
+		Namespace:        namespace,
+		SearchAttributes: missing,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return added, nil
+}
@@ -1,17 +1,38 @@
 class APIClient {
     constructor() {
-        this.baseURL = 'http://localhost:8082/api/v1';
+        // Same origin as the server that serves the frontend
+        this.baseURL = '/api/v1';
+        this.token = null;
         console.log('APIClient initialized - Version 2.0'); // Debug version check
     }
 
+    // Signs in as the demo user for a persona and keeps its bearer token
+    async login(role, password = 'demo') {
+        this.token = null;
+        const data = await this.request('/auth/login', {
+            method: 'POST',
+            body: JSON.stringify({ username: role, password })
+        });
+        this.token = data.token;
+        return data.principal;
+    }
+
+    authHeaders() {
+        return this.token ? { 'Authorization': `Bearer ${this.token}` } : {};
+    }
+
     async request(endpoint, options = {}) {
         const url = `${this.baseURL}${endpoint}`;
+        // Let the browser set the multipart boundary for FormData bodies
+        const defaultHeaders = options.body instanceof FormData ?
+            {} : { 'Content-Type': 'application/json' };
+        Object.assign(defaultHeaders, this.authHeaders());
         const config = {
+            ...options,
             headers: {
-                'Content-Type': 'application/json',
+                ...defaultHeaders,
                 ...options.headers
-            },
-            ...options
+            }
         };
 
         try {
@@ -37,8 +58,10 @@ class APIClient {
         });
     }
 
-    async getLoanApplications() {
-        return this.request('/loans');
+    // Returns { loans, next_page_token } filtered by the given query parameters
+    async getLoanApplications(params = {}) {
+        const query = new URLSearchParams(params).toString();
+        return this.request(query ? `/loans?${query}` : '/loans');
     }
 
     async getLoanApplication(id) {
@@ -50,13 +73,107 @@ class APIClient {
     }
 
     // Document APIs
-    async uploadDocument(loanId, documentData) {
+    async uploadDocument(loanId, documentType, file) {
+        const formData = new FormData();
+        formData.append('document_type', documentType);
+        formData.append('file', file);
+
         return this.request(`/loans/${loanId}/documents`, {
             method: 'POST',
-            body: JSON.stringify(documentData)
+            body: formData
         });
     }
 
+    // Fetches a file with the bearer token and saves it in the browser
+    async download(endpoint, fileName) {
+        const response = await fetch(`${this.baseURL}${endpoint}`, {
+            headers: this.authHeaders()
+        });
+        if (!response.ok) {
+            const data = await response.json().catch(() => ({}));
+            throw new Error(data.error || 'Download failed');
+        }
+
+        const url = URL.createObjectURL(await response.blob());
+        const link = document.createElement('a');
+        link.href = url;
+        link.download = fileName;
+        link.click();
+        URL.revokeObjectURL(url);
+    }
+
+    async downloadDocument(loanId, documentId, fileName) {
+        return this.download(`/loans/${loanId}/documents/${documentId}`, fileName);
+    }
+
+    async downloadLoanAgreement(loanId, format, fileName) {
+        return this.download(`/loans/${loanId}/agreement?format=${format}`, fileName);
+    }
+
+    // Returns the loan agreement HTML for previewing on the signing page
+    async getLoanAgreementHTML(loanId) {
+        const response = await fetch(`${this.baseURL}/loans/${loanId}/agreement?format=html`, {
+            headers: this.authHeaders()
+        });
+        if (!response.ok) {
+            const data = await response.json().catch(() => ({}));
+            throw new Error(data.error || 'Failed to load loan agreement');
+        }
+        return response.text();
+    }
+
+    // Live update APIs
+
+    // Follows a Server-Sent Events stream and calls onEvent with each parsed
+    // event until the signal aborts. fetch is used instead of EventSource so
+    // the bearer token can be sent as a header.
+    async streamEvents(endpoint, onEvent, signal) {
+        const response = await fetch(`${this.baseURL}${endpoint}`, {
+            headers: this.authHeaders(),
+            signal
+        });
+        if (!response.ok) {
+            throw new Error(`Event stream failed: ${response.status}`);
+        }
+
+        const reader = response.body.pipeThrough(new TextDecoderStream()).getReader();
+        let buffer = '';
+        while (true) {
+            const { value, done } = await reader.read();
+            if (done) {
+                return;
+            }
+            buffer += value;
+
+            let end;
+            while ((end = buffer.indexOf('\n\n')) >= 0) {
+                const block = buffer.slice(0, end);
+                buffer = buffer.slice(end + 2);
+
+                const event = { id: null, type: 'message', data: '' };
+                for (const line of block.split('\n')) {
+                    const [field, ...rest] = line.split(':');
+                    const text = rest.join(':').replace(/^ /, '');
+                    if (field === 'id') event.id = text;
+                    if (field === 'event') event.type = text;
+                    if (field === 'data') event.data += text;
+                }
+                if (event.data) {
+                    onEvent({ ...event, data: JSON.parse(event.data) });
+                }
+            }
+        }
+    }
+
+    async streamLoanChanges(onChange, signal) {
+        return this.streamEvents('/events', event => onChange(event.data), signal);
+    }
+
+    // Audit APIs
+    async downloadAuditTrail(loanId, format) {
+        return this.download(`/loans/${loanId}/audit?format=${format}`, `loan-${loanId}-audit.${format}`);
+    }
+
     async verifyDocument(loanId, verificationData) {
         return this.request(`/loans/${loanId}/verify-documents`, {
             method: 'POST',
@@ -80,6 +197,38 @@ class APIClient {
         });
     }
 
+    // Condition APIs. status is satisfied or waived
+    async clearCondition(loanId, conditionId, conditionData) {
+        return this.request(`/loans/${loanId}/conditions/${conditionId}`, {
+            method: 'POST',
+            body: JSON.stringify(conditionData)
+        });
+    }
+
+    // Counter-offer APIs
+    async respondToCounterOffer(loanId, responseData) {
+        return this.request(`/loans/${loanId}/counter-offer`, {
+            method: 'POST',
+            body: JSON.stringify(responseData)
+        });
+    }
+
+    // signature is { signed, signer_name, document_sha256, reason }
+    async signAgreement(loanId, signature) {
+        return this.request(`/loans/${loanId}/signature`, {
+            method: 'POST',
+            body: JSON.stringify(signature)
+        });
+    }
+
+    // Closure APIs. action is withdraw (borrower) or cancel (lender)
+    async closeApplication(loanId, action, closureData) {
+        return this.request(`/loans/${loanId}/${action}`, {
+            method: 'POST',
+            body: JSON.stringify(closureData)
+        });
+    }
+
     // Funding APIs
     async processFunding(loanId, fundingData) {
         return this.request(`/loans/${loanId}/funding`, {
@@ -87,6 +236,21 @@ class APIClient {
             body: JSON.stringify(fundingData)
         });
     }
+
+    // Journal entries, only those of loanId if it is given, and account
+    // balances. Amounts are in cents
+    async getLedger(loanId) {
+        const query = loanId ? `?loan_id=${encodeURIComponent(loanId)}` : '';
+        return this.request(`/ledger${query}`);
+    }
+
+    // Admin APIs
+    async retryFunding(loanId, retryData) {
+        return this.request(`/admin/loans/${loanId}/retry-funding`, {
+            method: 'POST',
+            body: JSON.stringify(retryData)
+        });
+    }
 }
 
 // Global API client instance
@@ -43,11 +43,13 @@ function formatDate(dateString) {
     });
 }
 
-// Handle page visibility changes to pause/resume auto-refresh
+// Handle page visibility changes to pause/resume live updates
 document.addEventListener('visibilitychange', function() {
     if (document.hidden) {
         personaManager.stopAutoRefresh();
     } else {
+        // Catch up on changes missed while hidden
+        personaManager.loadRoleData();
         personaManager.startAutoRefresh();
     }
 });
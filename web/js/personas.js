@@ -1,8 +1,46 @@
+// Reason codes for withdrawing (borrower) and cancelling (lender) an
+// application
+const CLOSURE_REASONS = {
+    withdraw: {
+        NO_LONGER_NEEDED: 'No longer needed',
+        FOUND_OTHER_LENDER: 'Found another lender',
+        TERMS_NOT_ACCEPTABLE: 'Terms not acceptable',
+        PURCHASE_FELL_THROUGH: 'Purchase fell through',
+        OTHER: 'Other'
+    },
+    cancel: {
+        DUPLICATE_APPLICATION: 'Duplicate application',
+        BORROWER_UNRESPONSIVE: 'Borrower unresponsive',
+        SUSPECTED_FRAUD: 'Suspected fraud',
+        ENTERED_IN_ERROR: 'Entered in error',
+        OTHER: 'Other'
+    }
+};
+
+// Document types the API accepts, with their display names
+const DOCUMENT_TYPES = {
+    id_proof: 'ID Proof',
+    pay_stub: 'Pay Stub',
+    income_statement: 'Income Statement',
+    bank_statement: 'Bank Statement',
+    employment_verification: 'Employment Verification',
+    tax_returns: 'Tax Returns',
+    purchase_agreement: 'Purchase Agreement',
+    drivers_license: "Driver's License",
+    proof_of_insurance: 'Proof of Insurance',
+    mortgage_statement: 'Mortgage Statement',
+    homeowners_insurance: 'Homeowners Insurance',
+    other: 'Other'
+};
+
 class PersonaManager {
     constructor() {
         this.currentRole = 'loan-officer';
+        // principal is the demo user signed in for the current persona
+        this.principal = null;
         this.loans = [];
-        this.refreshInterval = null;
+        this.liveUpdates = null;
+        this.refreshTimeout = null;
         console.log('PersonaManager initialized - Version 2.0'); // Debug version check
     }
 
@@ -10,7 +48,6 @@ class PersonaManager {
         this.setupRoleSelector();
         this.setupEventListeners();
         this.switchRole(this.currentRole);
-        this.startAutoRefresh();
     }
 
     setupRoleSelector() {
@@ -21,11 +58,16 @@ class PersonaManager {
     }
 
     setupEventListeners() {
-        // Loan Officer form
+        // Application form
         const loanForm = document.getElementById('loan-application-form');
         if (loanForm) {
             loanForm.addEventListener('submit', (e) => this.handleLoanApplicationSubmit(e));
         }
+        const productSelect = document.getElementById('loanProduct');
+        if (productSelect) {
+            productSelect.addEventListener('change', () => this.updateProductFields());
+            this.updateProductFields();
+        }
 
         // Modal close
         const modal = document.getElementById('modal');
@@ -44,8 +86,20 @@ class PersonaManager {
         });
     }
 
-    switchRole(role) {
+    async switchRole(role) {
         this.currentRole = role;
+
+        // Sign in as the persona so the API authorizes its actions
+        try {
+            this.principal = await api.login(role);
+        } catch (error) {
+            this.showMessage('Error signing in as ' + role + ': ' + error.message, 'error');
+            return;
+        }
+
+        // Follow live updates with the new persona's token
+        this.stopAutoRefresh();
+        this.startAutoRefresh();
         
         // Hide all role views
         document.querySelectorAll('.role-view').forEach(view => {
@@ -58,13 +112,48 @@ class PersonaManager {
             currentView.style.display = 'block';
         }
         
+        this.placeApplicationForm(role);
+
         // Load data for current role
         this.loadRoleData();
     }
 
+    // Loan officers and customers share the application form
+    placeApplicationForm(role) {
+        const section = document.getElementById('application-section');
+        const slot = document.querySelector(`#${role}-view .application-slot`);
+        section.style.display = slot ? 'block' : 'none';
+        if (slot) {
+            slot.appendChild(section);
+        }
+    }
+
+    // Server-side filters for each persona's queue
+    roleFilters() {
+        switch (this.currentRole) {
+            case 'loan-officer':
+                return { created_by: this.principal.sub };
+            case 'customer':
+                return { status: 'processing,pending,counter_offered,awaiting_signature,rejected' };
+            case 'loan-processor':
+                return { status: 'processing,conditionally_approved' };
+            case 'appraiser':
+                return { status: 'processing' };
+            case 'underwriter':
+                return { status: 'processing,conditionally_approved' };
+            case 'fund-manager':
+                return { status: 'approved' };
+            case 'admin':
+                return { status: 'funding_failed' };
+            default:
+                return {};
+        }
+    }
+
     async loadRoleData() {
         try {
-            this.loans = await api.getLoanApplications();
+            const page = await api.getLoanApplications({ ...this.roleFilters(), page_size: 100 });
+            this.loans = page.loans;
             // Ensure loans is always an array
             if (!Array.isArray(this.loans)) {
                 this.loans = [];
@@ -98,16 +187,19 @@ class PersonaManager {
             case 'fund-manager':
                 this.renderFundManagerView();
                 break;
+            case 'admin':
+                this.renderAdminView();
+                break;
         }
     }
 
     renderLoanOfficerView() {
         const container = document.getElementById('officer-applications');
-        const myLoans = this.loans.filter(loan => loan.created_by === 'loan-officer');
+        const myLoans = this.loans.filter(loan => loan.created_by === this.principal.sub);
         
         container.innerHTML = myLoans.length === 0 ? 
             '<p>No applications created yet.</p>' : 
-            myLoans.map(loan => this.createLoanCard(loan, ['view-details'])).join('');
+            myLoans.map(loan => this.createLoanCard(loan, ['view-details', 'cancel'])).join('');
     }
 
     renderCustomerView() {
@@ -115,10 +207,17 @@ class PersonaManager {
         const processingLoans = this.loans.filter(loan => 
             loan.status === 'processing' || loan.status === 'pending'
         );
+        const counterOffers = this.loans.filter(loan => loan.status === 'counter_offered');
+        const awaitingSignature = this.loans.filter(loan => loan.status === 'awaiting_signature');
+        // Declined loans stay listed so the borrower can read the notice
+        const declinedLoans = this.loans.filter(loan => loan.status === 'rejected');
         
-        container.innerHTML = processingLoans.length === 0 ? 
+        container.innerHTML = processingLoans.length === 0 && counterOffers.length === 0 && awaitingSignature.length === 0 && declinedLoans.length === 0 ? 
             '<p>No loans requiring document upload.</p>' : 
-            processingLoans.map(loan => this.createLoanCard(loan, ['upload-documents'])).join('');
+            awaitingSignature.map(loan => this.createLoanCard(loan, ['sign-agreement', 'view-details', 'withdraw'])).join('') +
+            counterOffers.map(loan => this.createLoanCard(loan, ['respond-counter-offer', 'withdraw'])).join('') +
+            processingLoans.map(loan => this.createLoanCard(loan, ['upload-documents', 'withdraw'])).join('') +
+            declinedLoans.map(loan => this.createLoanCard(loan, ['view-details'])).join('');
     }
 
     renderLoanProcessorView() {
@@ -129,15 +228,22 @@ class PersonaManager {
             loan.documents.some(doc => doc.verification_status === 'pending')
         );
         
-        container.innerHTML = pendingLoans.length === 0 ? 
+        const conditionalLoans = this.loans.filter(loan => loan.status === 'conditionally_approved');
+        
+        container.innerHTML = pendingLoans.length === 0 && conditionalLoans.length === 0 ? 
             '<p>No applications pending document verification.</p>' : 
-            pendingLoans.map(loan => this.createLoanCard(loan, ['verify-documents'])).join('');
+            pendingLoans.map(loan => this.createLoanCard(loan, ['verify-documents'])).join('') +
+            conditionalLoans.map(loan => this.createLoanCard(loan, ['clear-conditions'])).join('');
     }
 
     renderAppraiserView() {
         const container = document.getElementById('appraiser-applications');
+        // Only property loans are appraised by hand; vehicles are valued
+        // automatically and unsecured loans have no collateral
         const appraisalLoans = this.loans.filter(loan => 
             loan.status === 'processing' && 
+            ['mortgage', 'heloc'].includes(loan.product || 'mortgage') &&
+            (!loan.policy || !loan.policy.product || loan.policy.product.require_appraisal) &&
             (!loan.appraisal || loan.appraisal.status !== 'completed')
         );
         
@@ -150,14 +256,17 @@ class PersonaManager {
         const container = document.getElementById('underwriter-applications');
         const underwritingLoans = this.loans.filter(loan => 
             loan.status === 'processing' && 
-            loan.appraisal && 
-            loan.appraisal.status === 'completed' &&
+            loan.recommendation &&
+            (loan.next_step || '').startsWith('Waiting for underwriting decision') &&
             (!loan.underwriting_decision || loan.underwriting_decision.decision == 'needs_more_info')
         );
         
-        container.innerHTML = underwritingLoans.length === 0 ? 
+        const conditionalLoans = this.loans.filter(loan => loan.status === 'conditionally_approved');
+        
+        container.innerHTML = underwritingLoans.length === 0 && conditionalLoans.length === 0 ? 
             '<p>No applications pending underwriting.</p>' : 
-            underwritingLoans.map(loan => this.createLoanCard(loan, ['make-decision'])).join('');
+            underwritingLoans.map(loan => this.createLoanCard(loan, ['make-decision'])).join('') +
+            conditionalLoans.map(loan => this.createLoanCard(loan, ['clear-conditions'])).join('');
     }
 
     renderFundManagerView() {
@@ -175,6 +284,43 @@ class PersonaManager {
         container.innerHTML = approvedLoans.length === 0 ? 
             '<p>No approved applications for funding.</p>' : 
             approvedLoans.map(loan => this.createLoanCard(loan, ['process-funding'])).join('');
+
+        this.renderLedger();
+    }
+
+    renderAdminView() {
+        const container = document.getElementById('admin-applications');
+        const failedLoans = this.loans.filter(loan => loan.status === 'funding_failed');
+
+        container.innerHTML = failedLoans.length === 0 ?
+            '<p>No loans with failed funding.</p>' :
+            failedLoans.map(loan => this.createLoanCard(loan, ['retry-funding', 'view-details'])).join('');
+    }
+
+    async renderLedger() {
+        const container = document.getElementById('fund-manager-ledger');
+        try {
+            const ledger = await api.getLedger();
+            const dollars = cents => `$${(cents / 100).toLocaleString(undefined, { minimumFractionDigits: 2 })}`;
+            container.innerHTML = `
+                <table class="ledger-table">
+                    <tr><th>Account</th><th>Debits</th><th>Credits</th><th>Balance</th></tr>
+                    ${ledger.balances.map(balance => `
+                    <tr><td>${balance.name}</td><td>${dollars(balance.debits)}</td><td>${dollars(balance.credits)}</td><td>${dollars(balance.balance)}</td></tr>
+                    `).join('')}
+                </table>
+                ${ledger.entries.length === 0 ? '<p>No journal entries yet.</p>' : `
+                <table class="ledger-table">
+                    <tr><th>Posted</th><th>Entry</th><th>Account</th><th>Debit</th><th>Credit</th></tr>
+                    ${ledger.entries.map(entry => entry.lines.map(line => `
+                    <tr><td>${new Date(entry.posted_at).toLocaleString()}</td><td>${entry.description}</td><td>${line.account}</td>
+                        <td>${line.debit ? dollars(line.debit) : ''}</td><td>${line.credit ? dollars(line.credit) : ''}</td></tr>
+                    `).join('')).join('')}
+                </table>`}
+            `;
+        } catch (error) {
+            container.innerHTML = `<p>Error loading ledger: ${error.message}</p>`;
+        }
     }
 
     createLoanCard(loan, actions = []) {
@@ -184,24 +330,55 @@ class PersonaManager {
                 <span class="info-value">${loan.documents.length} uploaded</span>
             </div>` : '';
 
+        const missingDocuments = this.outstandingItems(loan);
+        const checklistInfo = missingDocuments.length > 0 ?
+            `<div class="info-item">
+                <span class="info-label">Still Needed</span>
+                <span class="info-value">${missingDocuments.map(item => DOCUMENT_TYPES[item.document_type] || item.document_type).join(', ')}</span>
+            </div>` : '';
+
         const creditScoreInfo = loan.credit_score ?
             `<div class="info-item">
                 <span class="info-label">Credit Score</span>
                 <span class="info-value">${loan.credit_score.score || 'N/A'}</span>
             </div>` : '';
 
+        const productInfo = loan.product ?
+            `<div class="info-item">
+                <span class="info-label">Product</span>
+                <span class="info-value">${loan.product}</span>
+            </div>` : '';
+
+        const vehicleInfo = loan.vehicle_valuation && loan.vehicle_valuation.status === 'completed' ?
+            `<div class="info-item">
+                <span class="info-label">Vehicle Value</span>
+                <span class="info-value">$${loan.vehicle_valuation.value?.toLocaleString() || 'N/A'}</span>
+            </div>` : '';
+
         const appraisalInfo = loan.appraisal ? 
             `<div class="info-item">
                 <span class="info-label">Property Value</span>
                 <span class="info-value">$${loan.appraisal.property_value?.toLocaleString() || 'N/A'}</span>
             </div>` : '';
 
+        const recommendationInfo = loan.recommendation && this.currentRole !== 'customer' ?
+            `<div class="info-item">
+                <span class="info-label">Recommendation</span>
+                <span class="info-value"><span class="status ${loan.recommendation.recommendation}">${loan.recommendation.recommendation}</span></span>
+            </div>` : '';
+
         const underwritingInfo = loan.underwriting_decision ? 
             `<div class="info-item">
                 <span class="info-label">Decision</span>
                 <span class="info-value">${loan.underwriting_decision.decision}</span>
             </div>` : '';
 
+        const counterOfferInfo = loan.counter_offer && loan.counter_offer.status === 'pending' ?
+            `<div class="info-item">
+                <span class="info-label">Counter-Offer</span>
+                <span class="info-value">${this.formatTerms(loan.counter_offer.terms)}</span>
+            </div>` : '';
+
         const actionButtons = actions.map(action => {
             switch (action) {
                 case 'view-details':
@@ -216,6 +393,21 @@ class PersonaManager {
                     return `<button onclick="personaManager.showUnderwritingForm('${loan.id}')">Make Decision</button>`;
                 case 'process-funding':
                     return `<button onclick="personaManager.processFunding('${loan.id}')">Process Funding</button>`;
+                case 'clear-conditions':
+                    return `<button onclick="personaManager.showConditions('${loan.id}')">Clear Conditions</button>`;
+                case 'respond-counter-offer':
+                    return `<button onclick="personaManager.showCounterOfferResponse('${loan.id}')">Review Counter-Offer</button>`;
+                case 'retry-funding':
+                    // Compensation must finish before funding can be retried
+                    return loan.next_step === 'Waiting for an administrator to retry funding' ?
+                        `<button onclick="personaManager.showFundingRetry('${loan.id}')">Retry Funding</button>` : '';
+                case 'sign-agreement':
+                    return `<button onclick="window.open('/sign/${encodeURIComponent(loan.id)}', '_blank')">Sign Agreement</button>`;
+                case 'withdraw':
+                case 'cancel':
+                    // Only open applications can be closed
+                    return ['processing', 'approved', 'counter_offered', 'awaiting_signature', 'funding_failed'].includes(loan.status) ?
+                        `<button class="danger" onclick="personaManager.showClosureForm('${loan.id}', '${action}')">${action === 'withdraw' ? 'Withdraw' : 'Cancel Application'}</button>` : '';
                 default:
                     return '';
             }
@@ -239,8 +431,13 @@ class PersonaManager {
                     </div>
                     ${creditScoreInfo}
                     ${documentsInfo}
+                    ${checklistInfo}
+                    ${productInfo}
                     ${appraisalInfo}
+                    ${vehicleInfo}
+                    ${recommendationInfo}
                     ${underwritingInfo}
+                    ${counterOfferInfo}
                     <div class="info-item">
                         <span class="info-label">Next Step</span>
                         <span class="info-value">${loan.next_step}</span>
@@ -263,42 +460,91 @@ class PersonaManager {
             borrower_phone: document.getElementById('borrowerPhone').value,
             loan_amount: parseFloat(document.getElementById('loanAmount').value),
             loan_purpose: document.getElementById('loanPurpose').value,
-            created_by: 'loan-officer'
+            monthly_income: parseFloat(document.getElementById('monthlyIncome').value) || 0,
+            product: document.getElementById('loanProduct').value,
+            property_address: document.getElementById('propertyAddress').value
         };
+        if (formData.product === 'auto') {
+            formData.vehicle = {
+                vin: document.getElementById('vehicleVin').value,
+                year: parseInt(document.getElementById('vehicleYear').value) || 0,
+                make: document.getElementById('vehicleMake').value,
+                model: document.getElementById('vehicleModel').value,
+                mileage: parseInt(document.getElementById('vehicleMileage').value) || 0
+            };
+        }
 
         try {
             await api.createLoanApplication(formData);
             this.showMessage('Loan application created successfully!', 'success');
             e.target.reset();
+            this.updateProductFields();
             this.loadRoleData();
         } catch (error) {
             this.showMessage('Error creating loan application: ' + error.message, 'error');
         }
     }
 
+    // Shows the collateral fields the selected product needs
+    updateProductFields() {
+        const product = document.getElementById('loanProduct').value;
+        const isVehicle = product === 'auto';
+        const isProperty = product === 'mortgage' || product === 'heloc';
+        document.getElementById('vehicleGroup').style.display = isVehicle ? 'block' : 'none';
+        document.getElementById('propertyAddressGroup').style.display = isProperty ? 'block' : 'none';
+        ['vehicleVin', 'vehicleYear', 'vehicleMake', 'vehicleModel'].forEach(id => {
+            document.getElementById(id).required = isVehicle;
+        });
+        document.getElementById('propertyAddress').required = product === 'heloc';
+    }
+
+    // Lists the outstanding checklist items first, then every other type
+    documentTypeOptions(first = [], types = Object.keys(DOCUMENT_TYPES)) {
+        const rest = types.filter(type => !first.includes(type));
+        return [...new Set(first)].concat(rest)
+            .map(type => `<option value="${type}">${DOCUMENT_TYPES[type]}${first.includes(type) ? ' (required)' : ''}</option>`)
+            .join('');
+    }
+
+    outstandingItems(loan) {
+        return (loan.document_checklist || []).filter(item => item.status === 'missing' || item.status === 'rejected');
+    }
+
+    renderChecklist(loan) {
+        const checklist = loan.document_checklist || [];
+        if (checklist.length === 0) {
+            return '';
+        }
+        return `
+            <div class="detail-section">
+                <h4>Document Checklist</h4>
+                ${checklist.map(item => `
+                    <p><strong>${DOCUMENT_TYPES[item.document_type] || item.document_type}:</strong>
+                    <span class="status ${item.status}">${item.status}</span>
+                    ${item.reason ? `<br><small>${item.reason}</small>` : ''}</p>
+                `).join('')}
+            </div>
+        `;
+    }
+
     showDocumentUpload(loanId) {
+        const loan = this.loans.find(l => l.id === loanId);
+        const missing = loan ? this.outstandingItems(loan).map(item => item.document_type) : [];
         const modalBody = document.getElementById('modal-body');
         modalBody.innerHTML = `
             <h3>Upload Documents</h3>
+            ${loan ? this.renderChecklist(loan) : ''}
             <form id="document-upload-form">
                 <div class="form-group">
                     <label for="documentType">Document Type:</label>
                     <select id="documentType" required>
                         <option value="">Select Type</option>
-                        <option value="income_statement">Income Statement</option>
-                        <option value="bank_statement">Bank Statement</option>
-                        <option value="id_proof">ID Proof</option>
-                        <option value="employment_verification">Employment Verification</option>
-                        <option value="tax_returns">Tax Returns</option>
+                        ${this.documentTypeOptions(missing)}
                     </select>
                 </div>
                 <div class="form-group">
-                    <label for="fileName">File Name:</label>
-                    <input type="text" id="fileName" required placeholder="e.g., income_statement_2024.pdf">
-                </div>
-                <div class="form-group">
-                    <label for="filePath">File Path:</label>
-                    <input type="text" id="filePath" required placeholder="e.g., /uploads/documents/...">
+                    <label for="documentFile">File:</label>
+                    <input type="file" id="documentFile" required>
                 </div>
                 <button type="submit">Upload Document</button>
             </form>
@@ -307,14 +553,11 @@ class PersonaManager {
         document.getElementById('document-upload-form').addEventListener('submit', async (e) => {
             e.preventDefault();
             
-            const documentData = {
-                document_type: document.getElementById('documentType').value,
-                file_name: document.getElementById('fileName').value,
-                file_path: document.getElementById('filePath').value
-            };
+            const documentType = document.getElementById('documentType').value;
+            const file = document.getElementById('documentFile').files[0];
 
             try {
-                await api.uploadDocument(loanId, documentData);
+                await api.uploadDocument(loanId, documentType, file);
                 this.showMessage('Document uploaded successfully!', 'success');
                 document.getElementById('modal').style.display = 'none';
                 this.loadRoleData();
@@ -338,7 +581,7 @@ class PersonaManager {
                     <div class="document-item">
                         <div class="document-info">
                             <strong>${doc.document_type}</strong><br>
-                            <small>${doc.file_name}</small>
+                            <small><a href="#" onclick="personaManager.downloadDocument('${loanId}', '${doc.id}', '${doc.file_name}'); return false;">${doc.file_name}</a></small>
                         </div>
                         <div class="actions">
                             <button class="success" onclick="personaManager.verifyDocument('${loanId}', '${doc.id}', 'verified')">Verify</button>
@@ -352,12 +595,83 @@ class PersonaManager {
         document.getElementById('modal').style.display = 'block';
     }
 
+    showConditions(loanId) {
+        const loan = this.loans.find(l => l.id === loanId);
+        const openConditions = (loan.conditions || []).filter(condition => condition.status === 'open');
+        // Only underwriters can waive a condition
+        const canWaive = this.currentRole === 'underwriter';
+
+        const modalBody = document.getElementById('modal-body');
+        modalBody.innerHTML = `
+            <h3>Approval Conditions</h3>
+            <div class="form-group">
+                <label for="conditionComments">Comments (required to waive):</label>
+                <textarea id="conditionComments" rows="2"></textarea>
+            </div>
+            <div class="documents-list">
+                ${openConditions.map(condition => `
+                    <div class="document-item">
+                        <div class="document-info">
+                            <strong>${condition.description}</strong><br>
+                            <small>Added by ${condition.added_by}</small>
+                        </div>
+                        <div class="actions">
+                            <button class="success" onclick="personaManager.clearCondition('${loanId}', '${condition.id}', 'satisfied')">Satisfied</button>
+                            ${canWaive ? `<button class="danger" onclick="personaManager.clearCondition('${loanId}', '${condition.id}', 'waived')">Waive</button>` : ''}
+                        </div>
+                    </div>
+                `).join('')}
+            </div>
+        `;
+
+        document.getElementById('modal').style.display = 'block';
+    }
+
+    async clearCondition(loanId, conditionId, status) {
+        try {
+            await api.clearCondition(loanId, conditionId, {
+                status: status,
+                comments: document.getElementById('conditionComments').value
+            });
+
+            this.showMessage(`Condition ${status}`, 'success');
+            document.getElementById('modal').style.display = 'none';
+            this.loadRoleData();
+        } catch (error) {
+            this.showMessage('Error clearing condition: ' + error.message, 'error');
+        }
+    }
+
+    async downloadDocument(loanId, documentId, fileName) {
+        try {
+            await api.downloadDocument(loanId, documentId, fileName);
+        } catch (error) {
+            this.showMessage('Error downloading document: ' + error.message, 'error');
+        }
+    }
+
+    async downloadLoanAgreement(loanId, format, version) {
+        try {
+            await api.downloadLoanAgreement(loanId, format, `loan-agreement-${loanId}-v${version}.${format}`);
+        } catch (error) {
+            this.showMessage('Error downloading loan agreement: ' + error.message, 'error');
+        }
+    }
+
+    async downloadAuditTrail(loanId, format) {
+        try {
+            await api.downloadAuditTrail(loanId, format);
+        } catch (error) {
+            this.showMessage('Error exporting audit trail: ' + error.message, 'error');
+        }
+    }
+
     async verifyDocument(loanId, documentId, status) {
         try {
             await api.verifyDocument(loanId, {
                 document_id: documentId,
                 verification_status: status,
-                verification_details: { verified_by: 'loan-processor', timestamp: new Date().toISOString() }
+                verification_details: { timestamp: new Date().toISOString() }
             });
             
             this.showMessage(`Document ${status} successfully!`, 'success');
@@ -368,6 +682,131 @@ class PersonaManager {
         }
     }
 
+    showClosureForm(loanId, action) {
+        const reasons = CLOSURE_REASONS[action];
+        const modalBody = document.getElementById('modal-body');
+        modalBody.innerHTML = `
+            <h3>${action === 'withdraw' ? 'Withdraw Application' : 'Cancel Application'}</h3>
+            <form id="closure-form">
+                <div class="form-group">
+                    <label for="closureReason">Reason:</label>
+                    <select id="closureReason" required>
+                        <option value="">Select Reason</option>
+                        ${Object.entries(reasons).map(([code, label]) => `<option value="${code}">${label}</option>`).join('')}
+                    </select>
+                </div>
+                <div class="form-group">
+                    <label for="closureComments">Comments (required for Other):</label>
+                    <textarea id="closureComments" rows="3"></textarea>
+                </div>
+                <button type="submit" class="danger">Confirm</button>
+            </form>
+        `;
+
+        document.getElementById('closure-form').addEventListener('submit', async (e) => {
+            e.preventDefault();
+
+            try {
+                await api.closeApplication(loanId, action, {
+                    reason_code: document.getElementById('closureReason').value,
+                    comments: document.getElementById('closureComments').value
+                });
+                this.showMessage(`Application ${action === 'withdraw' ? 'withdrawn' : 'cancelled'}`, 'success');
+                document.getElementById('modal').style.display = 'none';
+                this.loadRoleData();
+            } catch (error) {
+                this.showMessage('Error closing application: ' + error.message, 'error');
+            }
+        });
+
+        document.getElementById('modal').style.display = 'block';
+    }
+
+    showFundingRetry(loanId) {
+        const loan = this.loans.find(l => l.id === loanId);
+        const failure = loan.funding_failures[loan.funding_failures.length - 1];
+        const modalBody = document.getElementById('modal-body');
+        modalBody.innerHTML = `
+            <h3>Retry Funding</h3>
+            <div class="loan-summary">
+                <p><strong>Borrower:</strong> ${loan.borrower_name}</p>
+                <p><strong>Failed Attempt:</strong> ${failure.funding.attempt} for $${failure.funding.amount?.toLocaleString()}</p>
+                <p><strong>Error:</strong> ${failure.error}</p>
+                <p>The borrower signs a new loan agreement before the loan is funded again.</p>
+            </div>
+            <form id="funding-retry-form">
+                <div class="form-group">
+                    <label for="retryComments">Comments:</label>
+                    <textarea id="retryComments" rows="3" placeholder="What was fixed..." required></textarea>
+                </div>
+                <button type="submit">Retry Funding</button>
+            </form>
+        `;
+
+        document.getElementById('funding-retry-form').addEventListener('submit', async (e) => {
+            e.preventDefault();
+
+            try {
+                await api.retryFunding(loanId, {
+                    comments: document.getElementById('retryComments').value
+                });
+                this.showMessage('Funding retried', 'success');
+                document.getElementById('modal').style.display = 'none';
+                this.loadRoleData();
+            } catch (error) {
+                this.showMessage('Error retrying funding: ' + error.message, 'error');
+            }
+        });
+
+        document.getElementById('modal').style.display = 'block';
+    }
+
+    formatTerms(terms) {
+        return `$${terms.loan_amount?.toLocaleString()} at ${(terms.interest_rate * 100).toFixed(2)}% for ${terms.term_months} months ($${terms.monthly_payment?.toLocaleString()}/month)`;
+    }
+
+    showCounterOfferResponse(loanId) {
+        const loan = this.loans.find(l => l.id === loanId);
+        const offer = loan.counter_offer;
+        const modalBody = document.getElementById('modal-body');
+        modalBody.innerHTML = `
+            <h3>Counter-Offer</h3>
+            <div class="loan-summary">
+                <p><strong>You applied for:</strong> ${this.formatTerms(offer.original_terms)}</p>
+                <p><strong>We can offer:</strong> ${this.formatTerms(offer.terms)}</p>
+                <p><strong>Offer expires:</strong> ${new Date(offer.expires_at).toLocaleString()}</p>
+                ${loan.underwriting_decision && loan.underwriting_decision.comments ? `<p><strong>Notes:</strong> ${loan.underwriting_decision.comments}</p>` : ''}
+            </div>
+            <form id="counter-offer-form">
+                <div class="form-group">
+                    <label for="counterOfferComments">Comments:</label>
+                    <textarea id="counterOfferComments" rows="3"></textarea>
+                </div>
+                <button type="submit" value="accept">Accept Offer</button>
+                <button type="submit" value="decline" class="danger">Decline Offer</button>
+            </form>
+        `;
+
+        document.getElementById('counter-offer-form').addEventListener('submit', async (e) => {
+            e.preventDefault();
+            const accepted = e.submitter.value === 'accept';
+
+            try {
+                await api.respondToCounterOffer(loanId, {
+                    accepted: accepted,
+                    comments: document.getElementById('counterOfferComments').value
+                });
+                this.showMessage(`Counter-offer ${accepted ? 'accepted' : 'declined'}`, 'success');
+                document.getElementById('modal').style.display = 'none';
+                this.loadRoleData();
+            } catch (error) {
+                this.showMessage('Error responding to counter-offer: ' + error.message, 'error');
+            }
+        });
+
+        document.getElementById('modal').style.display = 'block';
+    }
+
     showAppraisalForm(loanId) {
         const modalBody = document.getElementById('modal-body');
         modalBody.innerHTML = `
@@ -390,8 +829,7 @@ class PersonaManager {
             
             const appraisalData = {
                 property_value: parseFloat(document.getElementById('propertyValue').value),
-                appraisal_notes: document.getElementById('appraisalNotes').value,
-                appraiser_id: 'appraiser-001'
+                appraisal_notes: document.getElementById('appraisalNotes').value
             };
 
             try {
@@ -407,10 +845,29 @@ class PersonaManager {
         document.getElementById('modal').style.display = 'block';
     }
 
+    // renderRecommendation shows the automated decisioning result and the
+    // reasons behind it
+    renderRecommendation(recommendation) {
+        const percent = ratio => ratio == null ? 'N/A (no income)' : `${(ratio * 100).toFixed(1)}%`;
+        const reasons = (recommendation.reasons || []).map(reason =>
+            `<li><strong>${reason.code}</strong> (${reason.outcome}): ${reason.message}</li>`).join('');
+        return `
+            <div class="detail-section">
+                <h4>Automated Recommendation</h4>
+                <p><strong>Recommendation:</strong> <span class="status ${recommendation.recommendation}">${recommendation.recommendation}</span></p>
+                <p><strong>LTV:</strong> ${percent(recommendation.ltv)}, <strong>DTI:</strong> ${percent(recommendation.dti)}, <strong>Credit Tier:</strong> ${recommendation.credit_tier}</p>
+                <p><strong>Estimated Payment:</strong> $${recommendation.estimated_monthly_payment?.toLocaleString()}/month</p>
+                ${reasons ? `<ul>${reasons}</ul>` : ''}
+            </div>
+        `;
+    }
+
     showUnderwritingForm(loanId) {
+        const loan = this.loans.find(l => l.id === loanId);
         const modalBody = document.getElementById('modal-body');
         modalBody.innerHTML = `
             <h3>Underwriting Decision</h3>
+            ${loan && loan.recommendation ? this.renderRecommendation(loan.recommendation) : ''}
             <form id="underwriting-form">
                 <div class="form-group">
                     <label for="decision">Decision:</label>
@@ -419,8 +876,41 @@ class PersonaManager {
                         <option value="approved">Approved</option>
                         <option value="rejected">Rejected</option>
                         <option value="needs_more_info">Needs More Information</option>
+                        <option value="counter_offer">Counter-Offer</option>
                     </select>
                 </div>
+                <div id="conditionsGroup" style="display: none;">
+                    <div class="form-group">
+                        <label for="conditions">Conditions, one per line (leave empty for an unconditional approval):</label>
+                        <textarea id="conditions" rows="3" placeholder="Provide final pay stub"></textarea>
+                    </div>
+                </div>
+                <div id="counterOfferGroup" style="display: none;">
+                    <div class="form-group">
+                        <label for="counterOfferAmount">Loan Amount ($):</label>
+                        <input type="number" id="counterOfferAmount" step="0.01" value="${loan ? loan.loan_amount : ''}">
+                    </div>
+                    <div class="form-group">
+                        <label for="counterOfferRate">Interest Rate (%):</label>
+                        <input type="number" id="counterOfferRate" step="0.001" value="${loan && loan.terms ? (loan.terms.interest_rate * 100).toFixed(3) : ''}">
+                    </div>
+                    <div class="form-group">
+                        <label for="counterOfferTerm">Term (months):</label>
+                        <input type="number" id="counterOfferTerm" step="1" value="${loan && loan.terms ? loan.terms.term_months : ''}">
+                    </div>
+                </div>
+                <div id="requestedDocumentGroup" style="display: none;">
+                    <div class="form-group">
+                        <label for="requestedDocumentType">Document Needed:</label>
+                        <select id="requestedDocumentType">
+                            ${this.documentTypeOptions([], Object.keys(DOCUMENT_TYPES).filter(type => type !== 'other'))}
+                        </select>
+                    </div>
+                    <div class="form-group">
+                        <label for="requestedDocumentReason">Reason (shown to the customer):</label>
+                        <input type="text" id="requestedDocumentReason">
+                    </div>
+                </div>
                 <div class="form-group">
                     <label for="comments">Comments:</label>
                     <textarea id="comments" rows="4" placeholder="Enter underwriting comments..."></textarea>
@@ -429,14 +919,45 @@ class PersonaManager {
             </form>
         `;
 
+        document.getElementById('decision').addEventListener('change', (e) => {
+            const needsMoreInfo = e.target.value === 'needs_more_info';
+            document.getElementById('requestedDocumentGroup').style.display = needsMoreInfo ? 'block' : 'none';
+            document.getElementById('requestedDocumentReason').required = needsMoreInfo;
+            document.getElementById('conditionsGroup').style.display = e.target.value === 'approved' ? 'block' : 'none';
+            const counterOffer = e.target.value === 'counter_offer';
+            document.getElementById('counterOfferGroup').style.display = counterOffer ? 'block' : 'none';
+            ['counterOfferAmount', 'counterOfferRate', 'counterOfferTerm'].forEach(id => {
+                document.getElementById(id).required = counterOffer;
+            });
+        });
+
         document.getElementById('underwriting-form').addEventListener('submit', async (e) => {
             e.preventDefault();
             
             const decisionData = {
                 decision: document.getElementById('decision').value,
-                comments: document.getElementById('comments').value,
-                underwriter_id: 'underwriter-001'
+                comments: document.getElementById('comments').value
             };
+            if (decisionData.decision === 'needs_more_info') {
+                decisionData.requested_documents = [{
+                    document_type: document.getElementById('requestedDocumentType').value,
+                    reason: document.getElementById('requestedDocumentReason').value
+                }];
+            }
+            if (decisionData.decision === 'approved') {
+                decisionData.conditions = document.getElementById('conditions').value
+                    .split('\n')
+                    .map(line => line.trim())
+                    .filter(line => line !== '')
+                    .map(description => ({ description }));
+            }
+            if (decisionData.decision === 'counter_offer') {
+                decisionData.counter_offer = {
+                    loan_amount: parseFloat(document.getElementById('counterOfferAmount').value),
+                    interest_rate: parseFloat(document.getElementById('counterOfferRate').value) / 100,
+                    term_months: parseInt(document.getElementById('counterOfferTerm').value, 10)
+                };
+            }
 
             try {
                 await api.makeUnderwritingDecision(loanId, decisionData);
@@ -453,18 +974,21 @@ class PersonaManager {
 
     async processFunding(loanId) {
         const loan = this.loans.find(l => l.id === loanId);
+        // A counter-offer can approve less than was applied for
+        const approvedAmount = loan.terms?.loan_amount || loan.loan_amount;
         
         const modalBody = document.getElementById('modal-body');
         modalBody.innerHTML = `
             <h3>Process Funding</h3>
             <div class="loan-summary">
                 <p><strong>Borrower:</strong> ${loan.borrower_name}</p>
-                <p><strong>Loan Amount:</strong> $${loan.loan_amount?.toLocaleString()}</p>
+                <p><strong>Approved Amount:</strong> $${approvedAmount?.toLocaleString()}</p>
             </div>
             <form id="funding-form">
                 <div class="form-group">
                     <label for="fundingAmount">Funding Amount ($):</label>
-                    <input type="number" id="fundingAmount" step="0.01" value="${loan.loan_amount}" required>
+                    <input type="number" id="fundingAmount" step="0.01" value="${approvedAmount}" required>
+                    <small>Must match the approved amount.</small>
                 </div>
                 <div class="form-group">
                     <label for="fundingNotes">Funding Notes:</label>
@@ -478,7 +1002,6 @@ class PersonaManager {
             e.preventDefault();
             
             const fundingData = {
-                fund_manager_id: 'fund-manager-001',
                 funding_amount: parseFloat(document.getElementById('fundingAmount').value),
                 funding_notes: document.getElementById('fundingNotes').value
             };
@@ -514,15 +1037,20 @@ class PersonaManager {
                     <h4>Loan Information</h4>
                     <p><strong>Amount:</strong> $${loan.loan_amount?.toLocaleString()}</p>
                     <p><strong>Purpose:</strong> ${loan.loan_purpose}</p>
+                    ${loan.property_address ? `<p><strong>Property:</strong> ${loan.property_address}</p>` : ''}
+                    ${loan.vehicle ? `<p><strong>Vehicle:</strong> ${loan.vehicle.year} ${loan.vehicle.make} ${loan.vehicle.model}, ${loan.vehicle.mileage?.toLocaleString()} miles (VIN ${loan.vehicle.vin})</p>` : ''}
+                    ${loan.policy && loan.policy.product ? `<p><strong>Product:</strong> ${loan.policy.product.name} (policy ${loan.policy.version})</p>` : ''}
                     <p><strong>Status:</strong> <span class="status ${loan.status}">${loan.status}</span></p>
                     <p><strong>Next step:</strong> ${loan.next_step}</p>
                 </div>
                 
+                ${this.renderChecklist(loan)}
+
                 ${loan.documents && loan.documents.length > 0 ? `
                 <div class="detail-section">
                     <h4>Documents</h4>
                     ${loan.documents.map(doc => `
-                        <p><strong>${doc.document_type}:</strong> ${doc.file_name} 
+                        <p><strong>${doc.document_type}:</strong> <a href="#" onclick="personaManager.downloadDocument('${loan.id}', '${doc.id}', '${doc.file_name}'); return false;">${doc.file_name}</a> 
                         <span class="status ${doc.verification_status}">${doc.verification_status}</span></p>
                     `).join('')}
                 </div>
@@ -536,20 +1064,166 @@ class PersonaManager {
                 </div>
                 ` : ''}
                 
+                ${loan.vehicle_valuation ? `
+                <div class="detail-section">
+                    <h4>Vehicle Valuation</h4>
+                    ${loan.vehicle_valuation.status === 'failed' ? `
+                    <p><strong>Status:</strong> <span class="status failed">failed</span></p>
+                    <p><strong>Error:</strong> ${loan.vehicle_valuation.error || 'N/A'}</p>
+                    ` : `
+                    <p><strong>Value:</strong> $${loan.vehicle_valuation.value?.toLocaleString()}</p>
+                    <p><strong>Source:</strong> ${loan.vehicle_valuation.source || 'N/A'} (${loan.vehicle_valuation.reference || 'N/A'})</p>
+                    `}
+                </div>
+                ` : ''}
+
+                ${loan.lien_check ? `
+                <div class="detail-section">
+                    <h4>Lien Check</h4>
+                    ${loan.lien_check.status === 'failed' ? `
+                    <p><strong>Status:</strong> <span class="status failed">failed</span></p>
+                    <p><strong>Error:</strong> ${loan.lien_check.error || 'N/A'}</p>
+                    ` : `
+                    <p><strong>Existing Balance:</strong> $${loan.lien_check.existing_balance?.toLocaleString()}</p>
+                    ${(loan.lien_check.liens || []).map(lien => `
+                        <p>Position ${lien.position}: ${lien.holder}, $${lien.balance?.toLocaleString()}</p>
+                    `).join('')}
+                    `}
+                </div>
+                ` : ''}
+
                 ${loan.credit_score ? `
                 <div class="detail-section">
                     <h4>Credit Score</h4>
-                    <p><strong>Credit Score:</strong> ${loan.credit_score.score}</p>
+                    ${loan.credit_score.status === 'failed' ? `
+                    <p><strong>Status:</strong> <span class="status failed">failed</span></p>
+                    <p><strong>Error:</strong> ${loan.credit_score.error || 'N/A'}</p>
+                    ` : `
+                    <p><strong>Credit Score:</strong> ${loan.credit_score.score} (${loan.credit_score.score_model || 'N/A'})</p>
+                    <p><strong>Bureau:</strong> ${loan.credit_score.bureau || 'N/A'}</p>
+                    <p><strong>Report:</strong> ${loan.credit_score.report_reference || 'N/A'} (${loan.credit_score.pull_type || 'N/A'} pull)</p>
+                    <p><strong>Tradelines:</strong> ${(loan.credit_score.tradelines || []).length}, <strong>Inquiries:</strong> ${loan.credit_score.inquiries || 0}</p>
+                    `}
                 </div>
                 ` : ''}
                 
+                ${loan.recommendation && this.currentRole !== 'customer' ? this.renderRecommendation(loan.recommendation) : ''}
+
                 ${loan.underwriting_decision ? `
                 <div class="detail-section">
                     <h4>Underwriting Decision</h4>
                     <p><strong>Decision:</strong> <span class="status ${loan.underwriting_decision.decision}">${loan.underwriting_decision.decision}</span></p>
+                    ${loan.underwriting_decision.recommendation && this.currentRole !== 'customer' ? `<p><strong>Recommended:</strong> ${loan.underwriting_decision.recommendation}</p>` : ''}
                     <p><strong>Comments:</strong> ${loan.underwriting_decision.comments || 'N/A'}</p>
                 </div>
                 ` : ''}
+
+                ${loan.adverse_action_notice ? `
+                <div class="detail-section">
+                    <h4>Adverse Action Notice</h4>
+                    ${loan.adverse_action_notice.document_id ?
+                        `<p><a href="#" onclick="personaManager.downloadDocument('${loan.id}', '${loan.adverse_action_notice.document_id}', '${loan.adverse_action_notice.document_id}.pdf'); return false;">Download notice</a>
+                        (generated ${new Date(loan.adverse_action_notice.generated_at).toLocaleString()})</p>` :
+                        `<p><span class="status failed">not generated</span> ${loan.adverse_action_notice.error || ''}</p>`}
+                    <ol>${loan.adverse_action_notice.reasons.map(reason => `<li>${reason.description}</li>`).join('')}</ol>
+                </div>
+                ` : ''}
+
+                ${loan.conditions && loan.conditions.length > 0 ? `
+                <div class="detail-section">
+                    <h4>Approval Conditions</h4>
+                    ${loan.conditions.map(condition => `
+                        <p><span class="status ${condition.status}">${condition.status}</span> ${condition.description}
+                        ${condition.cleared_at ? `<br><small>${condition.cleared_by} on ${new Date(condition.cleared_at).toLocaleString()}${condition.comments ? `: ${condition.comments}` : ''}</small>` : ''}</p>
+                    `).join('')}
+                </div>
+                ` : ''}
+
+                ${loan.counter_offer ? `
+                <div class="detail-section">
+                    <h4>Counter-Offer</h4>
+                    <p><strong>Status:</strong> <span class="status ${loan.counter_offer.status}">${loan.counter_offer.status}</span></p>
+                    <p><strong>Applied For:</strong> ${this.formatTerms(loan.counter_offer.original_terms)}</p>
+                    <p><strong>Offered:</strong> ${this.formatTerms(loan.counter_offer.terms)}</p>
+                    <p><strong>Expires:</strong> ${new Date(loan.counter_offer.expires_at).toLocaleString()}</p>
+                    ${loan.counter_offer.responded_at ? `<p><strong>Answered By:</strong> ${loan.counter_offer.responded_by} on ${new Date(loan.counter_offer.responded_at).toLocaleString()}</p>` : ''}
+                    ${loan.counter_offer.comments ? `<p><strong>Comments:</strong> ${loan.counter_offer.comments}</p>` : ''}
+                </div>
+                ` : ''}
+
+                ${loan.loan_agreement ? `
+                <div class="detail-section">
+                    <h4>Loan Agreement</h4>
+                    <p><strong>Version:</strong> ${loan.loan_agreement.version} (generated ${new Date(loan.loan_agreement.generated_at).toLocaleString()})</p>
+                    <p><strong>Terms:</strong> ${this.formatTerms(loan.loan_agreement.terms)}</p>
+                    <p><a href="#" onclick="personaManager.downloadLoanAgreement('${loan.id}', 'pdf', ${loan.loan_agreement.version}); return false;">Download PDF</a>
+                    | <a href="#" onclick="personaManager.downloadLoanAgreement('${loan.id}', 'html', ${loan.loan_agreement.version}); return false;">Download HTML</a></p>
+                    <p><small>SHA-256 ${loan.loan_agreement.pdf.sha256}</small></p>
+                </div>
+                ` : ''}
+
+                ${loan.signature ? `
+                <div class="detail-section">
+                    <h4>Signature</h4>
+                    <p><strong>Status:</strong> <span class="status ${loan.signature.status}">${loan.signature.status}</span> (agreement version ${loan.signature.agreement_version})</p>
+                    ${loan.signature.status === 'pending' ? `<p><strong>Expires:</strong> ${new Date(loan.signature.expires_at).toLocaleString()}</p>` : ''}
+                    ${loan.signature.signed_at ? `<p><strong>Signed By:</strong> ${loan.signature.signer_name} on ${new Date(loan.signature.signed_at).toLocaleString()} from ${loan.signature.ip_address}</p>` : ''}
+                    ${loan.signature.declined_at ? `<p><strong>Declined:</strong> ${new Date(loan.signature.declined_at).toLocaleString()}${loan.signature.reason ? `: ${loan.signature.reason}` : ''}</p>` : ''}
+                    <p><small>Document SHA-256 ${loan.signature.document_sha256}</small></p>
+                </div>
+                ` : ''}
+
+                ${loan.funding ? `
+                <div class="detail-section">
+                    <h4>Funding</h4>
+                    <p><strong>Amount:</strong> $${loan.funding.amount?.toLocaleString()}</p>
+                    <p><strong>Funded By:</strong> ${loan.funding.fund_manager_id} on ${new Date(loan.funding.funded_at).toLocaleString()}</p>
+                    ${loan.funding.notes ? `<p><strong>Notes:</strong> ${loan.funding.notes}</p>` : ''}
+                    <p><strong>Ledger Entry:</strong> ${loan.funding.ledger_entry_id || 'not posted'}</p>
+                </div>
+                ` : ''}
+
+                ${(loan.funding_failures || []).map(failure => `
+                <div class="detail-section">
+                    <h4>Failed Funding Attempt ${failure.funding.attempt}</h4>
+                    <p><strong>Amount:</strong> $${failure.funding.amount?.toLocaleString()} by ${failure.funding.fund_manager_id}</p>
+                    <p><strong>Failed:</strong> ${new Date(failure.failed_at).toLocaleString()}: ${failure.error}</p>
+                    <p><strong>Ledger Reversal:</strong> ${failure.reversal_entry_id || 'nothing posted'}</p>
+                    ${(failure.compensation_errors || []).map(error => `<p><span class="status failed">compensation failed</span> ${error}</p>`).join('')}
+                    ${failure.retried_at ? `<p><strong>Retried By:</strong> ${failure.retried_by} on ${new Date(failure.retried_at).toLocaleString()}: ${failure.retry_comments}</p>` : ''}
+                </div>
+                `).join('')}
+
+                ${loan.closure ? `
+                <div class="detail-section">
+                    <h4>${loan.closure.status === 'withdrawn' ? 'Withdrawal' : 'Cancellation'}</h4>
+                    <p><strong>Reason:</strong> ${loan.closure.reason_code}</p>
+                    <p><strong>Comments:</strong> ${loan.closure.comments || 'N/A'}</p>
+                    <p><strong>By:</strong> ${loan.closure.requested_by} on ${new Date(loan.closure.closed_at).toLocaleString()}</p>
+                    ${(loan.closure.cleanup_errors || []).map(error => `<p><span class="status failed">cleanup failed</span> ${error}</p>`).join('')}
+                </div>
+                ` : ''}
+
+                ${loan.notifications && loan.notifications.length > 0 ? `
+                <div class="detail-section">
+                    <h4>Borrower Notifications</h4>
+                    ${loan.notifications.map(notification => `
+                        <p>
+                            <span class="status ${notification.status}">${notification.status}</span>
+                            ${notification.event.replace(/_/g, ' ')} by ${notification.channel} to ${notification.recipient}
+                            on ${new Date(notification.sent_at).toLocaleString()}${notification.error ? `: ${notification.error}` : ''}
+                        </p>
+                    `).join('')}
+                </div>
+                ` : ''}
+
+                ${this.currentRole !== 'customer' ? `
+                <div class="detail-section">
+                    <h4>Audit Trail</h4>
+                    <button onclick="personaManager.downloadAuditTrail('${loan.id}', 'json')">Export JSON</button>
+                    <button onclick="personaManager.downloadAuditTrail('${loan.id}', 'csv')">Export CSV</button>
+                </div>
+                ` : ''}
             </div>
         `;
 
@@ -579,17 +1253,41 @@ class PersonaManager {
     }
 
     startAutoRefresh() {
-        // Refresh data every 30 seconds
-        this.refreshInterval = setInterval(() => {
-            this.loadRoleData();
-        }, 30000);
+        if (this.liveUpdates || !api.token) {
+            return;
+        }
+
+        // Reload the queue whenever the server pushes a loan change, and
+        // reconnect if the stream drops
+        const controller = new AbortController();
+        this.liveUpdates = controller;
+        api.streamLoanChanges(() => this.scheduleRefresh(), controller.signal)
+            .catch(error => {
+                if (!controller.signal.aborted) {
+                    console.error('Live updates failed:', error);
+                }
+            })
+            .finally(() => {
+                if (this.liveUpdates === controller) {
+                    this.liveUpdates = null;
+                    setTimeout(() => this.startAutoRefresh(), 5000);
+                }
+            });
     }
 
     stopAutoRefresh() {
-        if (this.refreshInterval) {
-            clearInterval(this.refreshInterval);
+        if (this.liveUpdates) {
+            const controller = this.liveUpdates;
+            this.liveUpdates = null;
+            controller.abort();
         }
     }
+
+    // Coalesces bursts of changes into a single reload
+    scheduleRefresh() {
+        clearTimeout(this.refreshTimeout);
+        this.refreshTimeout = setTimeout(() => this.loadRoleData(), 250);
+    }
 }
 
 // Global persona manager instance
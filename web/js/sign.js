@@ -0,0 +1,81 @@
+// Local stand-in for an e-signature provider: shows the borrower the loan
+// agreement and records their signature or refusal. The server records the
+// time, the client address and the hash of the agreement that was shown.
+class SigningPage {
+    constructor() {
+        this.loanId = decodeURIComponent(window.location.pathname.split('/').pop());
+        this.loan = null;
+    }
+
+    async init() {
+        document.getElementById('signing-form').addEventListener('submit', e => {
+            e.preventDefault();
+            this.submit({
+                signed: true,
+                signer_name: document.getElementById('signerName').value
+            });
+        });
+        document.getElementById('declining-form').addEventListener('submit', e => {
+            e.preventDefault();
+            this.submit({
+                signed: false,
+                reason: document.getElementById('declineReason').value
+            });
+        });
+
+        try {
+            // The demo signs in as the borrower persona
+            await api.login('customer');
+            await this.load();
+        } catch (error) {
+            this.showMessage('Error loading agreement: ' + error.message, 'error');
+        }
+    }
+
+    async load() {
+        this.loan = await api.getLoanApplication(this.loanId);
+        const agreement = this.loan.loan_agreement;
+        const signature = this.loan.signature;
+
+        document.getElementById('signing-title').textContent = agreement ?
+            `Loan agreement ${this.loanId}, version ${agreement.version}` :
+            `No loan agreement for ${this.loanId}`;
+        if (agreement) {
+            document.getElementById('agreement-preview').srcdoc = await api.getLoanAgreementHTML(this.loanId);
+        }
+
+        const waiting = this.loan.status === 'awaiting_signature' && signature && signature.status === 'pending';
+        document.getElementById('signing-status').innerHTML = waiting ?
+            `Please sign by ${new Date(signature.expires_at).toLocaleString()}.` :
+            `Nothing to sign: the loan is <span class="status ${this.loan.status}">${this.loan.status}</span>` +
+            (signature ? ` and the agreement is <span class="status ${signature.status}">${signature.status}</span>` : '');
+        document.getElementById('signing-form-section').style.display = waiting ? 'block' : 'none';
+    }
+
+    async submit(signature) {
+        try {
+            await api.signAgreement(this.loanId, {
+                ...signature,
+                // The hash of the agreement the borrower was shown
+                document_sha256: this.loan.signature.document_sha256
+            });
+            this.showMessage(signature.signed ? 'Loan agreement signed.' : 'Loan agreement declined.', 'success');
+            await this.load();
+        } catch (error) {
+            this.showMessage('Error submitting signature: ' + error.message, 'error');
+        }
+    }
+
+    showMessage(message, type = 'info') {
+        document.querySelectorAll('.message').forEach(msg => msg.remove());
+
+        const messageDiv = document.createElement('div');
+        messageDiv.className = `message ${type}`;
+        messageDiv.textContent = message;
+
+        const view = document.getElementById('signing-view');
+        view.insertBefore(messageDiv, view.firstChild);
+    }
+}
+
+document.addEventListener('DOMContentLoaded', () => new SigningPage().init());